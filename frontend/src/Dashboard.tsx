@@ -66,7 +66,7 @@ export default function Dashboard({ onLogout }: DashboardProps) {
 
     await api.post("/expenses", {
       description: exp.description,
-      amount: parseFloat(exp.amount),
+      amount: exp.amount.trim(),
       category_id: exp.category_id,
     });
 
@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/LuisBAndrade/etracker/internal/attachments"
 	"github.com/LuisBAndrade/etracker/internal/auth"
+	"github.com/LuisBAndrade/etracker/internal/budgets"
 	"github.com/LuisBAndrade/etracker/internal/categories"
 	"github.com/LuisBAndrade/etracker/internal/config"
 	"github.com/LuisBAndrade/etracker/internal/database"
 	"github.com/LuisBAndrade/etracker/internal/expenses"
+	"github.com/LuisBAndrade/etracker/internal/income"
+	"github.com/LuisBAndrade/etracker/internal/ledgers"
+	"github.com/LuisBAndrade/etracker/internal/mailer"
+	"github.com/LuisBAndrade/etracker/internal/oidc"
+	"github.com/LuisBAndrade/etracker/internal/ratelimit"
+	"github.com/LuisBAndrade/etracker/internal/rates"
+	"github.com/LuisBAndrade/etracker/internal/recurring"
+	"github.com/LuisBAndrade/etracker/internal/splits"
+	"github.com/LuisBAndrade/etracker/internal/storage"
+	"github.com/LuisBAndrade/etracker/internal/tags"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/handlers"
 	_ "github.com/lib/pq"
@@ -32,40 +49,248 @@ func main() {
 
     queries := database.New(conn)
 
-    authService := auth.NewService(queries)
-    categoriesService := categories.NewService(queries)
-    expensesService := expenses.NewService(queries)
+    templates, err := categories.LoadTemplates(cfg.CategoryTemplatesFile)
+    if err != nil {
+        log.Fatal("Failed to load category templates:", err)
+    }
+    categoriesService := categories.NewService(conn, queries, templates)
+
+    var mail mailer.Mailer
+    switch cfg.Mailer {
+    case "log":
+        mail = mailer.NewLogMailer(os.Stdout, cfg.MailFrom)
+    case "file":
+        mail, err = mailer.NewFileMailer(cfg.MailFile, cfg.MailFrom)
+    case "smtp":
+        mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+            Host:     cfg.SMTPHost,
+            Port:     int(cfg.SMTPPort),
+            Username: cfg.SMTPUsername,
+            Password: cfg.SMTPPassword,
+            From:     cfg.MailFrom,
+        })
+    default:
+        err = fmt.Errorf("unknown MAILER %q", cfg.Mailer)
+    }
+    if err != nil {
+        log.Fatal("Failed to set up mailer:", err)
+    }
+
+    secret := []byte(cfg.AppSecret)
+    if len(secret) == 0 {
+        log.Println("APP_SECRET is not set, using a random key; emailed links stop working on restart")
+        secret = make([]byte, 32)
+        if _, err := rand.Read(secret); err != nil {
+            log.Fatal("Failed to generate secret:", err)
+        }
+    }
+
+    var ipLimiter, accountLimiter, resendLimiter ratelimit.Limiter
+    switch cfg.RateLimitBackend {
+    case "memory":
+        ipLimiter = ratelimit.NewMemoryLimiter(auth.IPLoginPolicy)
+        accountLimiter = ratelimit.NewMemoryLimiter(auth.AccountLoginPolicy)
+        resendLimiter = ratelimit.NewMemoryLimiter(auth.ResendVerificationPolicy)
+    case "postgres":
+        ipLimiter = ratelimit.NewPostgresLimiter(queries, "ip", auth.IPLoginPolicy)
+        accountLimiter = ratelimit.NewPostgresLimiter(queries, "account", auth.AccountLoginPolicy)
+        resendLimiter = ratelimit.NewPostgresLimiter(queries, "verify", auth.ResendVerificationPolicy)
+    default:
+        log.Fatalf("Unknown RATE_LIMIT_BACKEND %q", cfg.RateLimitBackend)
+    }
+
+    var oidcProvider *oidc.Provider
+    if cfg.OIDCIssuer != "" {
+        oidcProvider, err = oidc.NewProvider(oidc.Config{
+            Issuer:       cfg.OIDCIssuer,
+            ClientID:     cfg.OIDCClientID,
+            ClientSecret: cfg.OIDCClientSecret,
+            RedirectURL:  cfg.OIDCRedirectURL,
+            Scopes:       strings.Fields(cfg.OIDCScopes),
+        })
+        if err != nil {
+            log.Fatal("Failed to set up single sign-on:", err)
+        }
+    }
+
+    authService := auth.NewService(conn, queries, categoriesService, mail, auth.Options{
+        AppURL:               cfg.AppURL,
+        Secret:               secret,
+        RequireVerifiedEmail: cfg.RequireVerifiedEmail,
+        IPLimiter:            ipLimiter,
+        AccountLimiter:       accountLimiter,
+        ResendLimiter:        resendLimiter,
+        TrustProxy:           cfg.TrustProxy,
+        SessionDuration:      cfg.SessionDuration,
+        SlidingSessions:      cfg.SessionSliding,
+        SessionMaxLifetime:   cfg.SessionMaxLifetime,
+        OIDC:                 oidcProvider,
+    })
+    ratesService := rates.NewService(conn, queries)
+
+    var store storage.Store
+    switch cfg.StorageBackend {
+    case "local":
+        store, err = storage.NewLocalStore(cfg.StorageDir)
+    case "s3":
+        store, err = storage.NewS3Store(storage.S3Config{
+            Endpoint:        cfg.S3Endpoint,
+            Bucket:          cfg.S3Bucket,
+            Region:          cfg.S3Region,
+            AccessKeyID:     cfg.S3AccessKeyID,
+            SecretAccessKey: cfg.S3SecretAccessKey,
+            PathStyle:       cfg.S3PathStyle,
+        })
+    default:
+        err = fmt.Errorf("unknown STORAGE_BACKEND %q", cfg.StorageBackend)
+    }
+    if err != nil {
+        log.Fatal("Failed to set up attachment storage:", err)
+    }
+    attachmentsService := attachments.NewService(conn, queries, store, cfg.AttachmentMaxSize)
+
+    expensesService := expenses.NewService(conn, queries, ratesService, attachmentsService)
+    recurringService := recurring.NewService(conn, queries)
+    budgetsService := budgets.NewService(queries, expensesService)
+    tagsService := tags.NewService(queries)
+    ledgersService := ledgers.NewService(conn, queries, attachmentsService, mail, cfg.AppURL)
+    splitsService := splits.NewService(conn, queries)
+    incomeService := income.NewService(queries, ratesService)
+
+    if cfg.ExchangeRatesFile != "" {
+        n, err := ratesService.ImportFile(context.Background(), cfg.ExchangeRatesFile)
+        if err != nil {
+            log.Fatal("Failed to load exchange rates:", err)
+        }
+        log.Printf("Loaded %d exchange rates from %s", n, cfg.ExchangeRatesFile)
+    }
+
+    // Materialize recurring expenses in the background for the life of the process
+    go recurringService.RunWorker(context.Background(), cfg.RecurringInterval)
+    // Expired sessions, tokens, invitations and login attempts
+    go authService.RunCleanup(context.Background(), time.Hour)
 
     router := mux.NewRouter()
 
     // Auth routes
     router.HandleFunc("/api/auth/register", authService.HandleRegister).Methods("POST")
     router.HandleFunc("/api/auth/login", authService.HandleLogin).Methods("POST")
+    router.HandleFunc("/api/auth/login/2fa", authService.HandleCompleteLogin).Methods("POST")
     router.HandleFunc("/api/auth/logout", authService.HandleLogout).Methods("POST")
+    router.HandleFunc("/api/auth/password-reset", authService.HandleRequestPasswordReset).Methods("POST")
+    router.HandleFunc("/api/auth/password-reset/confirm", authService.HandleConfirmPasswordReset).Methods("POST")
+    router.HandleFunc("/api/auth/email/confirm", authService.HandleConfirmEmailChange).Methods("POST")
+    router.HandleFunc("/api/auth/verify-email", authService.HandleVerifyEmail).Methods("POST")
+    router.HandleFunc("/api/auth/oidc/login", authService.HandleOIDCLogin).Methods("GET")
+    router.HandleFunc("/api/auth/oidc/callback", authService.HandleOIDCCallback).Methods("GET")
+    router.HandleFunc("/api/category-templates", categoriesService.HandleGetTemplates).Methods("GET")
 
-    // Protected routes
+    // Protected routes. Those wrapped in auth.RequireScope also accept
+    // personal API tokens with that scope; the rest need the session cookie.
     protected := router.PathPrefix("/api").Subrouter()
     protected.Use(authService.AuthMiddleware)
 
     protected.HandleFunc("/auth/me", authService.HandleMe).Methods("GET")
     protected.HandleFunc("/auth/logout-all", authService.HandleLogoutAll).Methods("POST")
+    protected.HandleFunc("/auth/sessions", authService.HandleGetSessions).Methods("GET")
+    protected.HandleFunc("/auth/sessions/{id}", authService.HandleRevokeSession).Methods("DELETE")
+    protected.HandleFunc("/auth/tokens", authService.HandleCreateAPIToken).Methods("POST")
+    protected.HandleFunc("/auth/tokens", authService.HandleGetAPITokens).Methods("GET")
+    protected.HandleFunc("/auth/tokens/{id}", authService.HandleRevokeAPIToken).Methods("DELETE")
+    protected.HandleFunc("/auth/me", authService.HandleUpdatePreferences).Methods("PUT")
+    protected.HandleFunc("/auth/password", authService.HandleChangePassword).Methods("PUT")
+    protected.HandleFunc("/auth/email", authService.HandleChangeEmail).Methods("PUT")
+    protected.HandleFunc("/auth/verify-email/resend", authService.HandleResendVerification).Methods("POST")
+    protected.HandleFunc("/auth/2fa", authService.HandleGetTwoFactor).Methods("GET")
+    protected.HandleFunc("/auth/2fa/setup", authService.HandleStartTwoFactor).Methods("POST")
+    protected.HandleFunc("/auth/2fa/confirm", authService.HandleConfirmTwoFactor).Methods("POST")
+    protected.HandleFunc("/auth/2fa/disable", authService.HandleDisableTwoFactor).Methods("POST")
+    protected.HandleFunc("/auth/2fa/recovery-codes", authService.HandleRegenerateRecoveryCodes).Methods("POST")
+
+    // Ledger management is only for sessions. Members are removed, or
+    // leave, with DELETE on their own user ID.
+    protected.HandleFunc("/ledgers", ledgersService.HandleGetLedgers).Methods("GET")
+    protected.HandleFunc("/ledgers", ledgersService.HandleCreateLedger).Methods("POST")
+    protected.HandleFunc("/ledgers/{id}", ledgersService.HandleUpdateLedger).Methods("PUT")
+    protected.HandleFunc("/ledgers/{id}", ledgersService.HandleDeleteLedger).Methods("DELETE")
+    protected.HandleFunc("/ledgers/{id}/activate", ledgersService.HandleActivateLedger).Methods("POST")
+    protected.HandleFunc("/ledgers/{id}/members", ledgersService.HandleGetMembers).Methods("GET")
+    protected.HandleFunc("/ledgers/{id}/members/{userId}", ledgersService.HandleUpdateMember).Methods("PUT")
+    protected.HandleFunc("/ledgers/{id}/members/{userId}", ledgersService.HandleRemoveMember).Methods("DELETE")
+    protected.HandleFunc("/ledgers/{id}/invitations", ledgersService.HandleCreateInvitation).Methods("POST")
+    protected.HandleFunc("/ledgers/{id}/invitations", ledgersService.HandleGetInvitations).Methods("GET")
+    protected.HandleFunc("/ledgers/{id}/invitations/{invitationId}", ledgersService.HandleCancelInvitation).Methods("DELETE")
+    protected.HandleFunc("/invitations", ledgersService.HandleGetUserInvitations).Methods("GET")
+    protected.HandleFunc("/invitations/{id}/accept", ledgersService.HandleAcceptInvitation).Methods("POST")
+    protected.HandleFunc("/invitations/{id}/decline", ledgersService.HandleDeclineInvitation).Methods("POST")
+
+    // Requests work on the active ledger or the one named in X-Ledger-ID.
+    // Viewers are kept off routes with a write scope or auth.RequireEditor.
+    protected.Handle("/categories", auth.RequireScope(auth.ScopeCategoriesWrite, categoriesService.HandleCreateCategory)).Methods("POST")
+    protected.Handle("/categories", auth.RequireScope(auth.ScopeCategoriesRead, categoriesService.HandleGetCategories)).Methods("GET")
+    protected.Handle("/categories/{id}", auth.RequireScope(auth.ScopeCategoriesWrite, categoriesService.HandleUpdateCategory)).Methods("PUT")
+    protected.Handle("/categories/{id}", auth.RequireScope(auth.ScopeCategoriesWrite, categoriesService.HandleDeleteCategory)).Methods("DELETE")
+    protected.Handle("/categories/{id}/merge", auth.RequireScope(auth.ScopeCategoriesWrite, categoriesService.HandleMergeCategory)).Methods("POST")
+    protected.Handle("/category-templates/{name}/apply", auth.RequireScope(auth.ScopeCategoriesWrite, categoriesService.HandleApplyTemplate)).Methods("POST")
+
+    protected.Handle("/expenses", auth.RequireScope(auth.ScopeExpensesWrite, expensesService.HandleCreateExpense)).Methods("POST")
+    protected.Handle("/expenses", auth.RequireScope(auth.ScopeExpensesRead, expensesService.HandleGetExpenses)).Methods("GET")
+    // Bulk import and export need a verified address when REQUIRE_VERIFIED_EMAIL is on
+    protected.Handle("/expenses/import", authService.RequireVerifiedEmail(auth.RequireScope(auth.ScopeExpensesWrite, expensesService.HandleImportExpenses))).Methods("POST")
+    protected.Handle("/expenses/export", authService.RequireVerifiedEmail(auth.RequireScope(auth.ScopeExpensesRead, expensesService.HandleExportExpenses))).Methods("GET")
+    protected.Handle("/expenses/search", auth.RequireScope(auth.ScopeExpensesRead, expensesService.HandleSearchExpenses)).Methods("GET")
+    protected.Handle("/expenses/{id}", auth.RequireScope(auth.ScopeExpensesWrite, expensesService.HandleUpdateExpense)).Methods("PUT")
+    protected.Handle("/expenses/{id}", auth.RequireScope(auth.ScopeExpensesWrite, expensesService.HandleDeleteExpense)).Methods("DELETE")
+    protected.Handle("/expenses/by-category", auth.RequireScope(auth.ScopeExpensesRead, expensesService.HandleGetExpensesByCategory)).Methods("GET")
+    protected.Handle("/expenses/by-tag", auth.RequireScope(auth.ScopeExpensesRead, expensesService.HandleGetExpensesByTag)).Methods("GET")
+    protected.Handle("/expenses/{id}/attachments", auth.RequireScope(auth.ScopeExpensesWrite, attachmentsService.HandleUploadAttachment)).Methods("POST")
+    protected.Handle("/expenses/{id}/attachments", auth.RequireScope(auth.ScopeExpensesRead, attachmentsService.HandleGetAttachments)).Methods("GET")
+    protected.Handle("/expenses/{id}/attachments/{attachmentId}", auth.RequireScope(auth.ScopeExpensesRead, attachmentsService.HandleDownloadAttachment)).Methods("GET")
+    protected.Handle("/expenses/{id}/attachments/{attachmentId}", auth.RequireScope(auth.ScopeExpensesWrite, attachmentsService.HandleDeleteAttachment)).Methods("DELETE")
+    protected.Handle("/expenses/{id}/split", auth.RequireScope(auth.ScopeExpensesWrite, splitsService.HandleSetSplit)).Methods("PUT")
+    protected.Handle("/expenses/{id}/split", auth.RequireScope(auth.ScopeExpensesRead, splitsService.HandleGetSplit)).Methods("GET")
+    protected.Handle("/expenses/{id}/split", auth.RequireScope(auth.ScopeExpensesWrite, splitsService.HandleDeleteSplit)).Methods("DELETE")
+    
+    // Settlements pay back split expenses and are kept out of spending totals
+    protected.Handle("/balances", auth.RequireScope(auth.ScopeExpensesRead, splitsService.HandleGetBalances)).Methods("GET")
+    protected.Handle("/balances/settle-up", auth.RequireScope(auth.ScopeExpensesRead, splitsService.HandleSettleUp)).Methods("GET")
+    protected.Handle("/settlements", auth.RequireScope(auth.ScopeExpensesWrite, splitsService.HandleCreateSettlement)).Methods("POST")
+    protected.Handle("/settlements", auth.RequireScope(auth.ScopeExpensesRead, splitsService.HandleGetSettlements)).Methods("GET")
+    protected.Handle("/settlements/{id}", auth.RequireScope(auth.ScopeExpensesWrite, splitsService.HandleDeleteSettlement)).Methods("DELETE")
+    
+    protected.Handle("/tags", auth.RequireScope(auth.ScopeExpensesRead, tagsService.HandleGetTags)).Methods("GET")
+    protected.Handle("/tags/{id}", auth.RequireScope(auth.ScopeExpensesWrite, tagsService.HandleDeleteTag)).Methods("DELETE")
+
+    protected.HandleFunc("/budgets", budgetsService.HandleGetBudgets).Methods("GET")
+    protected.Handle("/budgets", auth.RequireEditor(budgetsService.HandleSetBudget)).Methods("PUT")
+    protected.Handle("/budgets/{id}", auth.RequireEditor(budgetsService.HandleDeleteBudget)).Methods("DELETE")
+
+    protected.Handle("/recurring-expenses", auth.RequireEditor(recurringService.HandleCreateRecurringExpense)).Methods("POST")
+    protected.HandleFunc("/recurring-expenses", recurringService.HandleGetRecurringExpenses).Methods("GET")
+    protected.HandleFunc("/recurring-expenses/{id}", recurringService.HandleGetRecurringExpense).Methods("GET")
+    protected.Handle("/recurring-expenses/{id}", auth.RequireEditor(recurringService.HandleUpdateRecurringExpense)).Methods("PUT")
+    protected.Handle("/recurring-expenses/{id}", auth.RequireEditor(recurringService.HandleDeleteRecurringExpense)).Methods("DELETE")
+    protected.Handle("/recurring-expenses/{id}/pause", auth.RequireEditor(recurringService.HandlePauseRecurringExpense)).Methods("POST")
+    protected.Handle("/recurring-expenses/{id}/resume", auth.RequireEditor(recurringService.HandleResumeRecurringExpense)).Methods("POST")
+    protected.Handle("/recurring-expenses/{id}/skip", auth.RequireEditor(recurringService.HandleSkipOccurrence)).Methods("POST")
 
-    protected.HandleFunc("/categories", categoriesService.HandleCreateCategory).Methods("POST")
-    protected.HandleFunc("/categories", categoriesService.HandleGetCategories).Methods("GET")
-    protected.HandleFunc("/categories/{id}", categoriesService.HandleUpdateCategory).Methods("PUT")
-    protected.HandleFunc("/categories/{id}", categoriesService.HandleDeleteCategory).Methods("DELETE")
+    protected.HandleFunc("/income", incomeService.HandleGetIncomes).Methods("GET")
+    protected.Handle("/income", auth.RequireEditor(incomeService.HandleCreateIncome)).Methods("POST")
+    protected.Handle("/income/{id}", auth.RequireEditor(incomeService.HandleUpdateIncome)).Methods("PUT")
+    protected.Handle("/income/{id}", auth.RequireEditor(incomeService.HandleDeleteIncome)).Methods("DELETE")
+    protected.HandleFunc("/income-categories", incomeService.HandleGetCategories).Methods("GET")
+    protected.Handle("/income-categories", auth.RequireEditor(incomeService.HandleCreateCategory)).Methods("POST")
+    protected.Handle("/income-categories/{id}", auth.RequireEditor(incomeService.HandleUpdateCategory)).Methods("PUT")
+    protected.Handle("/income-categories/{id}", auth.RequireEditor(incomeService.HandleDeleteCategory)).Methods("DELETE")
+    protected.HandleFunc("/cash-flow", incomeService.HandleGetCashFlow).Methods("GET")
 
-    protected.HandleFunc("/expenses", expensesService.HandleCreateExpense).Methods("POST")
-    protected.HandleFunc("/expenses", expensesService.HandleGetExpenses).Methods("GET")
-    protected.HandleFunc("/expenses/{id}", expensesService.HandleUpdateExpense).Methods("PUT")
-    protected.HandleFunc("/expenses/{id}", expensesService.HandleDeleteExpense).Methods("DELETE")
-    protected.HandleFunc("/expenses/by-category", expensesService.HandleGetExpensesByCategory).Methods("GET")
+    protected.HandleFunc("/exchange-rates", ratesService.HandleGetRates).Methods("GET")
 
     // ✅ Apply CORS *after* all routes are mounted
     corsHandler := handlers.CORS(
         handlers.AllowedOrigins([]string{"http://localhost:5173", "http://3.91.219.223:5173"}), // your frontend dev server
         handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-        handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Accept", "X-Requested-With"}),
+        handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Accept", "X-Requested-With", auth.LedgerHeader}),
         handlers.AllowCredentials(),
     )(router)
 
@@ -0,0 +1,218 @@
+package attachments
+
+import (
+    "errors"
+    "io"
+    "log"
+    "mime"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+// Uploads and downloads may outlast the server's default timeouts
+const transferTimeout = 5 * time.Minute
+
+type AttachmentResponse struct {
+    ID          string `json:"id"`
+    ExpenseID   string `json:"expense_id"`
+    Filename    string `json:"filename"`
+    ContentType string `json:"content_type"`
+    Size        int64  `json:"size"`
+    SHA256      string `json:"sha256"`
+    CreatedAt   string `json:"created_at"`
+}
+
+func attachmentResponse(a database.Attachment) AttachmentResponse {
+    return AttachmentResponse{
+        ID:          a.ID.String(),
+        ExpenseID:   a.ExpenseID.String(),
+        Filename:    a.Filename,
+        ContentType: a.ContentType,
+        Size:        a.SizeBytes,
+        SHA256:      a.Sha256,
+        CreatedAt:   a.CreatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+}
+
+// HandleUploadAttachment accepts a multipart form with a single "file"
+// part and streams it straight through without buffering it in memory.
+func (s *Service) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    expenseID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    
+    http.NewResponseController(w).SetReadDeadline(time.Now().Add(transferTimeout))
+    
+    // Leave room for the multipart framing around the file itself
+    r.Body = http.MaxBytesReader(w, r.Body, s.maxSize+1<<20)
+    reader, err := r.MultipartReader()
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Expected a multipart/form-data upload")
+        return
+    }
+    
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            utils.RespondWithError(w, http.StatusBadRequest, "Missing file")
+            return
+        }
+        if err != nil {
+            respondWithUploadError(w, err)
+            return
+        }
+        if part.FormName() != "file" {
+            part.Close()
+            continue
+        }
+        
+        attachment, err := s.Upload(r.Context(), ledger.ID, user.ID, expenseID, part.FileName(), part)
+        part.Close()
+        if err != nil {
+            respondWithUploadError(w, err)
+            return
+        }
+        utils.RespondWithJSON(w, http.StatusCreated, attachmentResponse(*attachment))
+        return
+    }
+}
+
+func respondWithUploadError(w http.ResponseWriter, err error) {
+    var maxBytesErr *http.MaxBytesError
+    switch {
+    case errors.Is(err, ErrExpenseNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Expense not found")
+    case errors.Is(err, ErrEmptyFile):
+        utils.RespondWithError(w, http.StatusBadRequest, "File is empty")
+    case errors.Is(err, ErrFileTooLarge), errors.As(err, &maxBytesErr):
+        utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+    case errors.Is(err, ErrUnsupportedType):
+        utils.RespondWithError(w, http.StatusUnsupportedMediaType, "Unsupported file type, upload a PDF or an image")
+    default:
+        log.Printf("Attachment upload failed: %v", err)
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to upload attachment")
+    }
+}
+
+func (s *Service) HandleGetAttachments(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    expenseID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    
+    attachments, err := s.GetAttachments(r.Context(), ledger.ID, expenseID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get attachments")
+        return
+    }
+    
+    response := make([]AttachmentResponse, len(attachments))
+    for i, a := range attachments {
+        response[i] = attachmentResponse(a)
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    vars := mux.Vars(r)
+    expenseID, err := uuid.Parse(vars["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    attachmentID, err := uuid.Parse(vars["attachmentId"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid attachment ID")
+        return
+    }
+    
+    attachment, body, err := s.Open(r.Context(), ledger.ID, expenseID, attachmentID)
+    if err != nil {
+        if errors.Is(err, ErrAttachmentNotFound) {
+            utils.RespondWithError(w, http.StatusNotFound, "Attachment not found")
+            return
+        }
+        log.Printf("Attachment download failed: %v", err)
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get attachment")
+        return
+    }
+    defer body.Close()
+    
+    http.NewResponseController(w).SetWriteDeadline(time.Now().Add(transferTimeout))
+    w.Header().Set("Content-Type", attachment.ContentType)
+    w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.Header().Set("Cache-Control", "private, max-age=3600")
+    w.WriteHeader(http.StatusOK)
+    if _, err := io.Copy(w, body); err != nil {
+        log.Printf("Attachment download interrupted: %v", err)
+    }
+}
+
+func (s *Service) HandleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    vars := mux.Vars(r)
+    expenseID, err := uuid.Parse(vars["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    attachmentID, err := uuid.Parse(vars["attachmentId"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid attachment ID")
+        return
+    }
+    
+    if err := s.Delete(r.Context(), ledger.ID, expenseID, attachmentID); err != nil {
+        if errors.Is(err, ErrAttachmentNotFound) {
+            utils.RespondWithError(w, http.StatusNotFound, "Attachment not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete attachment")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Attachment deleted successfully",
+    })
+}
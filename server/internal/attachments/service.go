@@ -0,0 +1,279 @@
+package attachments
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "unicode"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/storage"
+    "github.com/google/uuid"
+)
+
+var (
+    ErrExpenseNotFound    = errors.New("expense not found")
+    ErrAttachmentNotFound = errors.New("attachment not found")
+    ErrEmptyFile          = errors.New("file is empty")
+    ErrFileTooLarge       = errors.New("file is too large")
+    ErrUnsupportedType    = errors.New("unsupported file type")
+)
+
+// allowedTypes are the sniffed content types accepted as receipts.
+var allowedTypes = map[string]bool{
+    "application/pdf": true,
+    "image/jpeg":      true,
+    "image/png":       true,
+    "image/gif":       true,
+    "image/webp":      true,
+}
+
+type Service struct {
+    db      *sql.DB
+    queries *database.Queries
+    store   storage.Store
+    maxSize int64
+}
+
+func NewService(db *sql.DB, queries *database.Queries, store storage.Store, maxSize int64) *Service {
+    return &Service{db: db, queries: queries, store: store, maxSize: maxSize}
+}
+
+// MaxSize is the largest accepted upload in bytes.
+func (s *Service) MaxSize() int64 {
+    return s.maxSize
+}
+
+// blobKey addresses blobs by content so identical files share storage.
+func blobKey(sum string) string {
+    return "sha256/" + sum[:2] + "/" + sum
+}
+
+// Upload stores body as a new attachment, uploaded by userID, on an expense
+// in the ledger. The file is spooled to disk to hash and sniff it before
+// anything is written.
+func (s *Service) Upload(ctx context.Context, ledgerID, userID, expenseID uuid.UUID, filename string, body io.Reader) (*database.Attachment, error) {
+    if _, err := s.queries.GetExpenseByID(ctx, database.GetExpenseByIDParams{ID: expenseID, LedgerID: ledgerID}); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrExpenseNotFound
+        }
+        return nil, err
+    }
+    
+    tmp, err := os.CreateTemp("", "attachment-*")
+    if err != nil {
+        return nil, err
+    }
+    defer os.Remove(tmp.Name())
+    defer tmp.Close()
+    
+    hash := sha256.New()
+    size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, s.maxSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if size == 0 {
+        return nil, ErrEmptyFile
+    }
+    if size > s.maxSize {
+        return nil, ErrFileTooLarge
+    }
+    sum := hex.EncodeToString(hash.Sum(nil))
+    
+    // Trust the bytes, not the client's Content-Type
+    head := make([]byte, 512)
+    n, err := tmp.ReadAt(head, 0)
+    if err != nil && err != io.EOF {
+        return nil, err
+    }
+    contentType := http.DetectContentType(head[:n])
+    if !allowedTypes[contentType] {
+        return nil, ErrUnsupportedType
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.LockBlob(ctx, sum); err != nil {
+        return nil, err
+    }
+    existing, err := qtx.CountAttachmentsBySHA256(ctx, sum)
+    if err != nil {
+        return nil, err
+    }
+    if existing == 0 {
+        if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+            return nil, err
+        }
+        if err := s.store.Put(ctx, blobKey(sum), tmp, size, contentType); err != nil {
+            return nil, err
+        }
+    }
+    
+    attachment, err := qtx.CreateAttachment(ctx, database.CreateAttachmentParams{
+        ExpenseID:   expenseID,
+        UserID:      userID,
+        Filename:    cleanFilename(filename),
+        ContentType: contentType,
+        SizeBytes:   size,
+        Sha256:      sum,
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &attachment, nil
+}
+
+func (s *Service) GetAttachments(ctx context.Context, ledgerID, expenseID uuid.UUID) ([]database.Attachment, error) {
+    return s.queries.GetAttachmentsByExpense(ctx, database.GetAttachmentsByExpenseParams{
+        ExpenseID: expenseID,
+        LedgerID:  ledgerID,
+    })
+}
+
+// Open returns the attachment's metadata and content. The caller closes
+// the reader.
+func (s *Service) Open(ctx context.Context, ledgerID, expenseID, attachmentID uuid.UUID) (*database.Attachment, io.ReadCloser, error) {
+    attachment, err := s.queries.GetAttachmentByID(ctx, database.GetAttachmentByIDParams{
+        ID:        attachmentID,
+        ExpenseID: expenseID,
+        LedgerID:  ledgerID,
+    })
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, nil, ErrAttachmentNotFound
+        }
+        return nil, nil, err
+    }
+    
+    body, err := s.store.Get(ctx, blobKey(attachment.Sha256))
+    if err != nil {
+        return nil, nil, err
+    }
+    return &attachment, body, nil
+}
+
+func (s *Service) Delete(ctx context.Context, ledgerID, expenseID, attachmentID uuid.UUID) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    attachment, err := qtx.GetAttachmentByID(ctx, database.GetAttachmentByIDParams{
+        ID:        attachmentID,
+        ExpenseID: expenseID,
+        LedgerID:  ledgerID,
+    })
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrAttachmentNotFound
+        }
+        return err
+    }
+    
+    if err := qtx.DeleteAttachment(ctx, database.DeleteAttachmentParams{ID: attachment.ID, LedgerID: ledgerID}); err != nil {
+        return err
+    }
+    unused, err := s.ReleaseBlobs(ctx, qtx, []string{attachment.Sha256})
+    if err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    s.DeleteBlobs(ctx, unused)
+    return nil
+}
+
+// ReleaseBlobs reports which of hashes no attachment refers to any more.
+// Call it inside the transaction that removed the attachment rows, after
+// removing them, and pass the result to DeleteBlobs once that transaction
+// has committed. Deleting any sooner would lose the files of rows that a
+// failed commit puts back.
+func (s *Service) ReleaseBlobs(ctx context.Context, queries *database.Queries, hashes []string) ([]string, error) {
+    var unused []string
+    for _, sum := range hashes {
+        remaining, err := queries.CountAttachmentsBySHA256(ctx, sum)
+        if err != nil {
+            return nil, err
+        }
+        if remaining == 0 {
+            unused = append(unused, sum)
+        }
+    }
+    return unused, nil
+}
+
+// DeleteBlobs removes released blobs from the store. Each one is counted
+// again under its blob lock, since an upload of the same file may have
+// claimed it in the meantime. A blob that fails to delete is only logged:
+// an orphaned file is harmless, a missing one is not.
+func (s *Service) DeleteBlobs(ctx context.Context, hashes []string) {
+    for _, sum := range hashes {
+        if err := s.deleteBlob(ctx, sum); err != nil {
+            log.Printf("Failed to delete attachment blob %s: %v", sum, err)
+        }
+    }
+}
+
+func (s *Service) deleteBlob(ctx context.Context, sum string) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.LockBlob(ctx, sum); err != nil {
+        return err
+    }
+    remaining, err := qtx.CountAttachmentsBySHA256(ctx, sum)
+    if err != nil {
+        return err
+    }
+    if remaining > 0 {
+        return nil
+    }
+    if err := s.store.Delete(ctx, blobKey(sum)); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// cleanFilename keeps the base name and drops control characters so the
+// name is safe to echo back in a Content-Disposition header.
+func cleanFilename(name string) string {
+    name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+    name = strings.Map(func(r rune) rune {
+        if unicode.IsControl(r) || r == '"' {
+            return -1
+        }
+        return r
+    }, name)
+    name = strings.TrimSpace(name)
+    if name == "" || name == "." || name == "/" {
+        return "attachment"
+    }
+    if runes := []rune(name); len(runes) > 255 {
+        name = string(runes[len(runes)-255:])
+    }
+    return name
+}
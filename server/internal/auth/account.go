@@ -0,0 +1,355 @@
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/lib/pq"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+// reauthWindow is how recently an account without a password must have
+// signed in to change its email, since it has no password to confirm with.
+const reauthWindow = 10 * time.Minute
+
+var (
+    ErrWrongPassword           = errors.New("current password is incorrect")
+    ErrSameEmail               = errors.New("new email matches the current one")
+    ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+    ErrReauthRequired          = errors.New("sign in again to confirm this change")
+)
+
+// CurrentPassword is left empty by accounts that only sign in through an
+// identity provider and have no password yet.
+type ChangePasswordRequest struct {
+    CurrentPassword string `json:"current_password"`
+    NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type ChangeEmailRequest struct {
+    NewEmail        string `json:"new_email" validate:"required,email"`
+    CurrentPassword string `json:"current_password"`
+}
+
+type ConfirmEmailChangeRequest struct {
+    Token string `json:"token" validate:"required"`
+}
+
+// checkPassword re-reads the user so a stale context copy can't be used to
+// verify against an old hash. Accounts without a password have nothing to
+// confirm, so the session alone is enough for them.
+func (s *Service) checkPassword(ctx context.Context, queries *database.Queries, userID uuid.UUID, password string) (*database.User, error) {
+    user, err := queries.GetUserByID(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if !hasPassword(&user) {
+        return &user, nil
+    }
+    if err := bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password)); err != nil {
+        return nil, ErrWrongPassword
+    }
+    return &user, nil
+}
+
+// ChangePassword replaces the password after checking the current one and
+// signs out every session except keepSession, the one making the change.
+func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, keepSession, currentPassword, newPassword string) error {
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    user, err := s.checkPassword(ctx, qtx, userID, currentPassword)
+    if err != nil {
+        return err
+    }
+    _, err = qtx.UpdateUser(ctx, database.UpdateUserParams{
+        ID:             user.ID,
+        Email:          user.Email,
+        HashedPassword: string(hashedPassword),
+    })
+    if err != nil {
+        return err
+    }
+    if err := qtx.RevokeOtherUserSessions(ctx, database.RevokeOtherUserSessionsParams{
+        UserID: user.ID,
+        Token:  keepSession,
+    }); err != nil {
+        return err
+    }
+    // A reset link requested before the change should not undo it
+    if err := qtx.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+        return err
+    }
+    
+    return tx.Commit()
+}
+
+// RequestEmailChange checks the current password and mails a confirmation
+// link to the new address. The email only changes once the link is used.
+// Accounts without a password have nothing to confirm with, so session must
+// be a recent sign-in instead; an old stolen session can't move the account.
+func (s *Service) RequestEmailChange(ctx context.Context, userID uuid.UUID, session, newEmail, currentPassword string) error {
+    user, err := s.checkPassword(ctx, s.queries, userID, currentPassword)
+    if err != nil {
+        return err
+    }
+    if !hasPassword(user) {
+        recent, err := s.queries.HasRecentSession(ctx, database.HasRecentSessionParams{
+            Token:         session,
+            UserID:        user.ID,
+            MaxAgeSeconds: reauthWindow.Seconds(),
+        })
+        if err != nil {
+            return err
+        }
+        if !recent {
+            return ErrReauthRequired
+        }
+    }
+    if strings.EqualFold(user.Email, newEmail) {
+        return ErrSameEmail
+    }
+    if _, err := s.queries.GetUserByEmail(ctx, newEmail); err == nil {
+        return ErrUserExists
+    } else if !errors.Is(err, sql.ErrNoRows) {
+        return err
+    }
+    
+    token, err := s.generateSessionToken()
+    if err != nil {
+        return err
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.InvalidateEmailChangeTokens(ctx, user.ID); err != nil {
+        return err
+    }
+    _, err = qtx.CreateEmailChangeToken(ctx, database.CreateEmailChangeTokenParams{
+        UserID:    user.ID,
+        NewEmail:  newEmail,
+        TokenHash: hashToken(token),
+        ExpiresAt: time.Now().Add(emailChangeTTL),
+    })
+    if err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    
+    return s.mailer.Send(ctx, mailer.Message{
+        To:      newEmail,
+        Subject: "Confirm your new email address",
+        Body: fmt.Sprintf("Open the link below to use this address for your account:\n\n%s\n\n"+
+            "The link expires in %d hours. If you didn't ask for this, ignore this email.\n",
+            s.appLink("/confirm-email", url.Values{"token": {token}}), int(emailChangeTTL.Hours())),
+    })
+}
+
+// ConfirmEmailChange applies a pending email change. The old address gets a
+// notice so a hijacked account doesn't change hands silently.
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) (*database.User, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    change, err := qtx.GetValidEmailChangeToken(ctx, hashToken(token))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrInvalidEmailChangeToken
+        }
+        return nil, err
+    }
+    if err := qtx.MarkEmailChangeTokenUsed(ctx, change.ID); err != nil {
+        return nil, err
+    }
+    
+    user, err := qtx.GetUserByID(ctx, change.UserID)
+    if err != nil {
+        return nil, err
+    }
+    oldEmail := user.Email
+    updated, err := qtx.UpdateUser(ctx, database.UpdateUserParams{
+        ID:             user.ID,
+        Email:          change.NewEmail,
+        HashedPassword: user.HashedPassword,
+    })
+    if err != nil {
+        // The address was taken after the change was requested
+        var pqErr *pq.Error
+        if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+            return nil, ErrUserExists
+        }
+        return nil, err
+    }
+    // Following the link proved the new address works
+    updated, err = qtx.MarkUserEmailVerified(ctx, database.MarkUserEmailVerifiedParams{
+        ID:    updated.ID,
+        Email: updated.Email,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    
+    msg := mailer.Message{
+        To:      oldEmail,
+        Subject: "Your email address was changed",
+        Body: fmt.Sprintf("The email address for your account was changed to %s.\n\n"+
+            "If you didn't do this, reset your password and contact support.\n", change.NewEmail),
+    }
+    if err := s.mailer.Send(ctx, msg); err != nil {
+        log.Printf("Failed to send email change notice: %v", err)
+    }
+    
+    return &updated, nil
+}
+
+func (s *Service) CleanupExpiredEmailChangeTokens(ctx context.Context) error {
+    return s.queries.CleanupExpiredEmailChangeTokens(ctx)
+}
+
+func (s *Service) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req ChangePasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    // AuthMiddleware already required the cookie
+    cookie, _ := r.Cookie("session_token")
+    
+    if err := s.ChangePassword(r.Context(), user.ID, cookie.Value, req.CurrentPassword, req.NewPassword); err != nil {
+        if err == ErrWrongPassword {
+            utils.RespondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change password")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Password changed successfully",
+    })
+}
+
+func (s *Service) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req ChangeEmailRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    // API tokens have no session, so they can't stand in for a recent login
+    var session string
+    if cookie, err := r.Cookie("session_token"); err == nil {
+        session = cookie.Value
+    }
+    
+    if err := s.RequestEmailChange(r.Context(), user.ID, session, req.NewEmail, req.CurrentPassword); err != nil {
+        switch err {
+        case ErrWrongPassword:
+            utils.RespondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+        case ErrReauthRequired:
+            utils.RespondWithError(w, http.StatusForbidden, "Sign in again to change your email")
+        case ErrSameEmail:
+            utils.RespondWithError(w, http.StatusBadRequest, "New email matches the current one")
+        case ErrUserExists:
+            utils.RespondWithError(w, http.StatusConflict, "Email already in use")
+        default:
+            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change email")
+        }
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+        "message": "Check the new address for a confirmation link",
+    })
+}
+
+func (s *Service) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+    var req ConfirmEmailChangeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    user, err := s.ConfirmEmailChange(r.Context(), req.Token)
+    if err != nil {
+        switch err {
+        case ErrInvalidEmailChangeToken:
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired email change token")
+        case ErrUserExists:
+            utils.RespondWithError(w, http.StatusConflict, "Email already in use")
+        default:
+            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change email")
+        }
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
+        User: userResponse(user),
+        Message: "Email changed successfully",
+    })
+}
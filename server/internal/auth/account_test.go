@@ -0,0 +1,71 @@
+package auth
+
+import (
+    "context"
+    "database/sql/driver"
+    "testing"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/google/uuid"
+    "golang.org/x/crypto/bcrypt"
+)
+
+func TestRequestEmailChangeReauth(t *testing.T) {
+    hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name     string
+        password string
+        session  string
+        recent   bool
+        wantErr  error
+        wantMail bool
+    }{
+        {"passwordless, recent sign-in", "", "fresh", true, nil, true},
+        {"passwordless, old session", "", "stale", false, ErrReauthRequired, false},
+        {"passwordless, no session", "", "", false, ErrReauthRequired, false},
+        {"password, right password", string(hash), "stale", false, nil, true},
+    }
+
+    for _, tt := range tests {
+        user := database.User{ID: uuid.New(), Email: "ada@example.com", HashedPassword: tt.password}
+        f, db, queries := newFakeDB(t, func(name string, args []driver.Value) fakeResult {
+            switch name {
+            case "GetUserByID":
+                return userRow(user)
+            case "HasRecentSession":
+                if args[0] != tt.session || args[1] != user.ID.String() {
+                    t.Errorf("%s: HasRecentSession args = %v", tt.name, args)
+                }
+                return oneRow(tt.recent)
+            case "GetUserByEmail":
+                return noRows(8)
+            case "InvalidateEmailChangeTokens":
+                return fakeResult{}
+            case "CreateEmailChangeToken":
+                now := time.Now()
+                return oneRow(uuid.New().String(), user.ID.String(), "new@example.com", "hash", now.Add(emailChangeTTL), nil, now)
+            }
+            t.Fatalf("%s: unexpected query %s", tt.name, name)
+            return fakeResult{}
+        })
+        mail := &recordingMailer{}
+        s := &Service{db: db, queries: queries, mailer: mail}
+
+        password := ""
+        if tt.password != "" {
+            password = "secret"
+        }
+        err := s.RequestEmailChange(context.Background(), user.ID, tt.session, "new@example.com", password)
+        if err != tt.wantErr {
+            t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+        }
+        if sent := len(mail.Sent()) > 0; sent != tt.wantMail {
+            t.Errorf("%s: sent mail = %v, want %v (queries %v)", tt.name, sent, tt.wantMail, f.Calls())
+        }
+    }
+}
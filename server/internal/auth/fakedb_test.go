@@ -0,0 +1,146 @@
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "io"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+)
+
+// fakeResult is what a fake query returns: columns and rows for queries,
+// affected rows for statements.
+type fakeResult struct {
+    columns  []string
+    rows     [][]driver.Value
+    affected int64
+    err      error
+}
+
+// fakeQuery answers one statement. name is the sqlc query name taken from
+// the "-- name: X" header.
+type fakeQuery func(name string, args []driver.Value) fakeResult
+
+// fakeDB is a database/sql driver that hands every statement to a Go
+// function, so service code can run against real Queries without Postgres.
+type fakeDB struct {
+    mu     sync.Mutex
+    answer fakeQuery
+    calls  []string
+}
+
+func newFakeDB(t *testing.T, answer fakeQuery) (*fakeDB, *sql.DB, *database.Queries) {
+    t.Helper()
+    f := &fakeDB{answer: answer}
+    db := sql.OpenDB(f)
+    t.Cleanup(func() { db.Close() })
+    return f, db, database.New(db)
+}
+
+// Calls lists the query names run so far, in order.
+func (f *fakeDB) Calls() []string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return append([]string(nil), f.calls...)
+}
+
+func (f *fakeDB) run(query string, named []driver.NamedValue) fakeResult {
+    name := "?"
+    if rest, ok := strings.CutPrefix(strings.TrimSpace(query), "-- name: "); ok {
+        name, _, _ = strings.Cut(rest, " ")
+    }
+    args := make([]driver.Value, len(named))
+    for i, nv := range named {
+        args[i] = nv.Value
+    }
+
+    f.mu.Lock()
+    f.calls = append(f.calls, name)
+    f.mu.Unlock()
+    return f.answer(name, args)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use sql.OpenDB") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("prepare not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+    res := c.db.run(query, args)
+    if res.err != nil {
+        return nil, res.err
+    }
+    return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+    res := c.db.run(query, args)
+    if res.err != nil {
+        return nil, res.err
+    }
+    return driver.RowsAffected(res.affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+    columns []string
+    rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+    if len(r.rows) == 0 {
+        return io.EOF
+    }
+    copy(dest, r.rows[0])
+    r.rows = r.rows[1:]
+    return nil
+}
+
+// oneRow is a query result with a single row; the column names only need
+// the right count.
+func oneRow(values ...driver.Value) fakeResult {
+    columns := make([]string, len(values))
+    for i := range columns {
+        columns[i] = "c"
+    }
+    return fakeResult{columns: columns, rows: [][]driver.Value{values}}
+}
+
+// noRows makes a :one query fail with sql.ErrNoRows.
+func noRows(count int) fakeResult {
+    result := oneRow(make([]driver.Value, count)...)
+    result.rows = nil
+    return result
+}
+
+// userRow is a users row as GetUserByEmail and GetUserByID return it.
+func userRow(u database.User) fakeResult {
+    var verified, ledger driver.Value
+    if u.EmailVerifiedAt.Valid {
+        verified = u.EmailVerifiedAt.Time
+    }
+    if u.ActiveLedgerID.Valid {
+        ledger = u.ActiveLedgerID.UUID.String()
+    }
+    return oneRow(u.ID.String(), u.CreatedAt, u.UpdatedAt, u.Email, u.HashedPassword, u.BaseCurrency, verified, ledger)
+}
@@ -3,15 +3,20 @@ package auth
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     "time"
     
+   "github.com/LuisBAndrade/etracker/internal/database"
+   "github.com/LuisBAndrade/etracker/internal/money"
    "github.com/LuisBAndrade/etracker/internal/utils"
 )
 
 type RegisterRequest struct {
     Email    string `json:"email" validate:"required,email"`
     Password string `json:"password" validate:"required,min=6"`
+    // Category template set to start from; empty uses the default, "none" skips
+    CategoryTemplate string `json:"category_template"`
 }
 
 type LoginRequest struct {
@@ -19,15 +24,56 @@ type LoginRequest struct {
     Password string `json:"password" validate:"required"`
 }
 
+type UpdatePreferencesRequest struct {
+    BaseCurrency string `json:"base_currency" validate:"required"`
+}
+
+// TwoFactorChallengeResponse is returned by login instead of a session when
+// the account has 2FA enabled.
+type TwoFactorChallengeResponse struct {
+    TwoFactorRequired bool      `json:"two_factor_required"`
+    PendingToken      string    `json:"pending_token"`
+    ExpiresAt         time.Time `json:"expires_at"`
+    Message           string    `json:"message"`
+}
+
 type AuthResponse struct {
     User    UserResponse `json:"user"`
     Message string       `json:"message"`
 }
 
 type UserResponse struct {
-    ID        string    `json:"id"`
-    Email     string    `json:"email"`
-    CreatedAt time.Time `json:"created_at"`
+    ID              string     `json:"id"`
+    Email           string     `json:"email"`
+    EmailVerified   bool       `json:"email_verified"`
+    EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+    // False for accounts created through single sign-on that never set one
+    HasPassword     bool       `json:"has_password"`
+    BaseCurrency    string     `json:"base_currency"`
+    CreatedAt       time.Time  `json:"created_at"`
+    // The ledger requests work on; only set by /auth/me
+    Ledger          *LedgerResponse `json:"ledger,omitempty"`
+}
+
+type LedgerResponse struct {
+    ID   string `json:"id"`
+    Name string `json:"name"`
+    Role string `json:"role"`
+}
+
+func userResponse(user *database.User) UserResponse {
+    response := UserResponse{
+        ID:            user.ID.String(),
+        Email:         user.Email,
+        EmailVerified: user.EmailVerifiedAt.Valid,
+        HasPassword:   hasPassword(user),
+        BaseCurrency:  user.BaseCurrency,
+        CreatedAt:     user.CreatedAt,
+    }
+    if user.EmailVerifiedAt.Valid {
+        response.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+    }
+    return response
 }
 
 func (s *Service) HandleRegister(w http.ResponseWriter, r *http.Request) {
@@ -42,23 +88,20 @@ func (s *Service) HandleRegister(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    user, err := s.Register(r.Context(), req.Email, req.Password)
-    if err != nil {
-        if err == ErrUserExists {
-            utils.RespondWithError(w, http.StatusConflict, "User already exists")
+    // A taken email gets the same answer as a new one; the owner is told by
+    // mail instead, so the endpoint can't be used to find accounts
+    _, err := s.Register(r.Context(), req.Email, req.Password, req.CategoryTemplate)
+    if err != nil && err != ErrUserExists {
+        if err == ErrUnknownTemplate {
+            utils.RespondWithError(w, http.StatusBadRequest, "Unknown category template")
             return
         }
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create user")
         return
     }
 
-    utils.RespondWithJSON(w, http.StatusCreated, AuthResponse{
-        User: UserResponse{
-            ID:        user.ID.String(),
-            Email:     user.Email,
-            CreatedAt: user.CreatedAt,
-        },
-        Message: "User created successfully",
+    utils.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+        "message": "Check your email to finish signing up",
     })
 }
 
@@ -74,8 +117,13 @@ func (s *Service) HandleLogin(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    user, token, err := s.Login(r.Context(), req.Email, req.Password)
+    result, err := s.Login(r.Context(), req.Email, req.Password, s.clientInfo(r))
     if err != nil {
+        var limited *TooManyAttemptsError
+        if errors.As(err, &limited) {
+            respondTooManyAttempts(w, limited)
+            return
+        }
         if err == ErrInvalidCredentials {
             utils.RespondWithError(w, http.StatusUnauthorized, "Invalid credentials")
             return
@@ -84,6 +132,25 @@ func (s *Service) HandleLogin(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    if result.PendingToken != "" {
+        utils.RespondWithJSON(w, http.StatusOK, TwoFactorChallengeResponse{
+            TwoFactorRequired: true,
+            PendingToken:      result.PendingToken,
+            ExpiresAt:         result.PendingExpiresAt,
+            Message:           "Enter the code from your authenticator app",
+        })
+        return
+    }
+
+    setSessionCookie(w, result.SessionToken, result.SessionExpiresAt)
+
+    utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
+        User: userResponse(result.User),
+        Message: "Login successful",
+    })
+}
+
+func setSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
 	cookie := &http.Cookie{
 		Name:     "session_token",
 		Value:    token,
@@ -91,18 +158,9 @@ func (s *Service) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		Secure: false,                // must be false for localhost HTTP
 		SameSite: http.SameSiteLaxMode, // use Lax for local dev
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
+		Expires:  expiresAt,
 	}
     http.SetCookie(w, cookie)
-
-    utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
-        User: UserResponse{
-            ID:        user.ID.String(),
-            Email:     user.Email,
-            CreatedAt: user.CreatedAt,
-        },
-        Message: "Login successful",
-    })
 }
 
 func (s *Service) HandleLogout(w http.ResponseWriter, r *http.Request) {
@@ -139,11 +197,44 @@ func (s *Service) HandleMe(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    utils.RespondWithJSON(w, http.StatusOK, UserResponse{
-        ID:        user.ID.String(),
-        Email:     user.Email,
-        CreatedAt: user.CreatedAt,
-    })
+    response := userResponse(user)
+    if ledger, ok := GetLedgerFromContext(r.Context()); ok {
+        response.Ledger = &LedgerResponse{ID: ledger.ID.String(), Name: ledger.Name, Role: ledger.Role}
+    }
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+
+    var req UpdatePreferencesRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    currency, err := money.ParseCurrency(req.BaseCurrency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid base currency, use a three-letter ISO 4217 code")
+        return
+    }
+
+    updated, err := s.UpdateBaseCurrency(r.Context(), user.ID, currency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update preferences")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, userResponse(updated))
 }
 
 func (s *Service) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,194 @@
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "net/http"
+    "strings"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+// Ledger roles. Owners manage members and the ledger itself, editors change
+// its data and viewers only read it.
+const (
+    RoleOwner  = "owner"
+    RoleEditor = "editor"
+    RoleViewer = "viewer"
+)
+
+// LedgerHeader picks the ledger a request works on. Without it the user's
+// active ledger is used.
+const LedgerHeader = "X-Ledger-ID"
+
+const LedgerContextKey contextKey = "ledger"
+
+var (
+    ErrInvalidLedgerID = errors.New("invalid ledger ID")
+    ErrNotLedgerMember = errors.New("not a member of this ledger")
+)
+
+// LedgerAccess is the ledger a request works on and the user's role in it.
+type LedgerAccess struct {
+    ID   uuid.UUID
+    Name string
+    Role string
+}
+
+func (l *LedgerAccess) CanEdit() bool {
+    return l.Role == RoleOwner || l.Role == RoleEditor
+}
+
+func (l *LedgerAccess) CanManage() bool {
+    return l.Role == RoleOwner
+}
+
+// GetLedgerFromContext returns the active ledger set by AuthMiddleware. It
+// is missing only for users who belong to no ledger at all.
+func GetLedgerFromContext(ctx context.Context) (*LedgerAccess, bool) {
+    ledger, ok := ctx.Value(LedgerContextKey).(*LedgerAccess)
+    return ledger, ok
+}
+
+func ledgerAccess(row database.GetLedgerAccessRow) *LedgerAccess {
+    return &LedgerAccess{ID: row.ID, Name: row.Name, Role: row.Role}
+}
+
+// resolveLedger finds the ledger for a request: the one named in
+// X-Ledger-ID, else the user's active ledger, else their first own one.
+// It returns nil when the user has no ledger.
+func (s *Service) resolveLedger(ctx context.Context, r *http.Request, user *database.User) (*LedgerAccess, error) {
+    if header := r.Header.Get(LedgerHeader); header != "" {
+        id, err := uuid.Parse(strings.TrimSpace(header))
+        if err != nil {
+            return nil, ErrInvalidLedgerID
+        }
+        row, err := s.queries.GetLedgerAccess(ctx, database.GetLedgerAccessParams{
+            UserID:   user.ID,
+            LedgerID: uuid.NullUUID{UUID: id, Valid: true},
+        })
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrNotLedgerMember
+        } else if err != nil {
+            return nil, err
+        }
+        return ledgerAccess(row), nil
+    }
+
+    // The active ledger may be one the user has since left
+    if user.ActiveLedgerID.Valid {
+        row, err := s.queries.GetLedgerAccess(ctx, database.GetLedgerAccessParams{
+            UserID:   user.ID,
+            LedgerID: user.ActiveLedgerID,
+        })
+        if err == nil {
+            return ledgerAccess(row), nil
+        } else if !errors.Is(err, sql.ErrNoRows) {
+            return nil, err
+        }
+    }
+
+    row, err := s.queries.GetLedgerAccess(ctx, database.GetLedgerAccessParams{UserID: user.ID})
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, nil
+    } else if err != nil {
+        return nil, err
+    }
+    return ledgerAccess(row), nil
+}
+
+// serveWithUser finishes authentication: it resolves the active ledger,
+// refuses changes from viewers and passes both on in the context.
+func (s *Service) serveWithUser(w http.ResponseWriter, r *http.Request, user *database.User, next http.Handler) {
+    ctx := context.WithValue(r.Context(), UserContextKey, user)
+
+    ledger, err := s.resolveLedger(r.Context(), r, user)
+    switch {
+    case errors.Is(err, ErrInvalidLedgerID):
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid "+LedgerHeader+" header")
+        return
+    case errors.Is(err, ErrNotLedgerMember):
+        utils.RespondWithError(w, http.StatusForbidden, "You are not a member of this ledger")
+        return
+    case err != nil:
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load ledger")
+        return
+    }
+
+    if ledger != nil {
+        if routeNeedsEditor(r) && !ledger.CanEdit() {
+            utils.RespondWithError(w, http.StatusForbidden, "Viewers can't make changes to this ledger")
+            return
+        }
+        ctx = context.WithValue(ctx, LedgerContextKey, ledger)
+    }
+    next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// createPersonalLedger gives a new user a ledger of their own and makes it
+// their active one. Pass queries bound to the signup transaction.
+func createPersonalLedger(ctx context.Context, queries *database.Queries, userID uuid.UUID) (*database.Ledger, error) {
+    ledger, err := queries.CreateLedger(ctx, "Personal")
+    if err != nil {
+        return nil, err
+    }
+    if _, err := queries.AddLedgerMember(ctx, database.AddLedgerMemberParams{
+        LedgerID: ledger.ID,
+        UserID:   userID,
+        Role:     RoleOwner,
+    }); err != nil {
+        return nil, err
+    }
+    if err := queries.SetActiveLedger(ctx, database.SetActiveLedgerParams{
+        ID:             userID,
+        ActiveLedgerID: uuid.NullUUID{UUID: ledger.ID, Valid: true},
+    }); err != nil {
+        return nil, err
+    }
+    return &ledger, nil
+}
+
+func (s *Service) CleanupExpiredLedgerInvitations(ctx context.Context) error {
+    return s.queries.CleanupExpiredLedgerInvitations(ctx)
+}
+
+// editorHandler marks a route that changes ledger data.
+type editorHandler struct {
+    next http.Handler
+}
+
+func (h *editorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    h.next.ServeHTTP(w, r)
+}
+
+// RequireEditor keeps viewers off a route. Routes wrapped in RequireScope
+// with a write scope are covered already. Like scopes, the check happens in
+// AuthMiddleware.
+func RequireEditor(next http.HandlerFunc) http.Handler {
+    return &editorHandler{next: next}
+}
+
+// routeNeedsEditor reports whether the matched route changes ledger data.
+func routeNeedsEditor(r *http.Request) bool {
+    route := mux.CurrentRoute(r)
+    if route == nil {
+        return false
+    }
+    handler := route.GetHandler()
+    for {
+        switch h := handler.(type) {
+        case *editorHandler:
+            return true
+        case *scopedHandler:
+            return strings.HasSuffix(h.scope, ":write")
+        case *verifiedHandler:
+            handler = h.next
+        default:
+            return false
+        }
+    }
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "math"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/ratelimit"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// Backoff for failed logins. An account slows down quickly and locks for a
+// while after sustained guessing; an IP gets more room because offices and
+// mobile carriers put many people behind one address.
+var (
+    AccountLoginPolicy = ratelimit.Policy{
+        FreeAttempts:    5,
+        BaseDelay:       time.Second,
+        MaxDelay:        5 * time.Minute,
+        LockoutAfter:    15,
+        LockoutDuration: 30 * time.Minute,
+        Window:          time.Hour,
+    }
+    IPLoginPolicy = ratelimit.Policy{
+        FreeAttempts:    20,
+        BaseDelay:       time.Second,
+        MaxDelay:        15 * time.Minute,
+        LockoutAfter:    100,
+        LockoutDuration: time.Hour,
+        Window:          time.Hour,
+    }
+    // Verification mails can be resent a minute after the last one, with
+    // the wait doubling on every resend up to an hour.
+    ResendVerificationPolicy = ratelimit.Policy{
+        BaseDelay: time.Minute,
+        MaxDelay:  time.Hour,
+        Window:    24 * time.Hour,
+    }
+)
+
+// TooManyAttemptsError is returned while an IP or account is backing off
+// after failed logins.
+type TooManyAttemptsError struct {
+    RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+    return fmt.Sprintf("too many attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+var (
+    dummyHashOnce sync.Once
+    dummyHash     []byte
+)
+
+// equalizeTiming spends as long as a real password check so a login for an
+// unknown email can't be told apart by response time.
+func equalizeTiming(password string) {
+    dummyHashOnce.Do(func() {
+        dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+    })
+    bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func accountKey(email string) string {
+    return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginAllowed refuses attempts from a blocked IP or for a blocked
+// account before any password work is done.
+func (s *Service) checkLoginAllowed(ctx context.Context, ip, email string) error {
+    var wait time.Duration
+    if s.ipLimiter != nil && ip != "" {
+        d, err := s.ipLimiter.Check(ctx, ip)
+        if err != nil {
+            return err
+        }
+        wait = max(wait, d)
+    }
+    if s.accountLimiter != nil {
+        d, err := s.accountLimiter.Check(ctx, accountKey(email))
+        if err != nil {
+            return err
+        }
+        wait = max(wait, d)
+    }
+    if wait > 0 {
+        return &TooManyAttemptsError{RetryAfter: wait}
+    }
+    return nil
+}
+
+// recordLoginFailure counts a failed attempt against both the IP and the
+// account. Limiter errors are logged rather than turned into a 500 so the
+// caller still sees invalid credentials.
+func (s *Service) recordLoginFailure(ctx context.Context, ip, email string) {
+    if s.ipLimiter != nil && ip != "" {
+        if _, err := s.ipLimiter.Fail(ctx, ip); err != nil {
+            log.Printf("Failed to record login failure for IP: %v", err)
+        }
+    }
+    if s.accountLimiter != nil {
+        if _, err := s.accountLimiter.Fail(ctx, accountKey(email)); err != nil {
+            log.Printf("Failed to record login failure for account: %v", err)
+        }
+    }
+}
+
+// recordLoginSuccess clears the account's failures. The IP keeps its count
+// so owning one account doesn't reset guessing at others.
+func (s *Service) recordLoginSuccess(ctx context.Context, email string) {
+    if s.accountLimiter != nil {
+        if err := s.accountLimiter.Reset(ctx, accountKey(email)); err != nil {
+            log.Printf("Failed to reset login attempts: %v", err)
+        }
+    }
+}
+
+// clientIP is the address limits are keyed on. Forwarding headers are only
+// believed behind a trusted proxy, otherwise anyone could pick their own key.
+func (s *Service) clientIP(r *http.Request) string {
+    if s.trustProxy {
+        if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+            first, _, _ := strings.Cut(forwarded, ",")
+            return strings.TrimSpace(first)
+        }
+        if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+            return strings.TrimSpace(realIP)
+        }
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+func respondTooManyAttempts(w http.ResponseWriter, err *TooManyAttemptsError) {
+    respondRetryAfter(w, err.RetryAfter, "Too many failed attempts, try again later")
+}
+
+// respondRetryAfter answers 429 with the wait rounded up to whole seconds.
+func respondRetryAfter(w http.ResponseWriter, wait time.Duration, message string) {
+    seconds := int(math.Ceil(wait.Seconds()))
+    w.Header().Set("Retry-After", fmt.Sprint(max(seconds, 1)))
+    utils.RespondWithError(w, http.StatusTooManyRequests, message)
+}
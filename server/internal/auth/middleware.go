@@ -3,7 +3,10 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/LuisBAndrade/etracker/internal/database"
 	"github.com/LuisBAndrade/etracker/internal/utils"
@@ -13,8 +16,16 @@ type contextKey string
 
 const UserContextKey contextKey = "user"
 
+// AuthMiddleware accepts either the session cookie or a personal API token
+// in "Authorization: Bearer". Tokens only reach routes wrapped in
+// RequireScope, and only with that scope.
 func (s *Service) AuthMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if header := r.Header.Get("Authorization"); header != "" {
+            s.authenticateBearer(w, r, header, next)
+            return
+        }
+
         // Get session token from cookie
         cookie, err := r.Cookie("session_token")
         if err != nil {
@@ -37,12 +48,41 @@ func (s *Service) AuthMiddleware(next http.Handler) http.Handler {
             return
         }
 
-        // Add user to context
-        ctx := context.WithValue(r.Context(), UserContextKey, user)
-        next.ServeHTTP(w, r.WithContext(ctx))
+        s.touchSession(r.Context(), cookie.Value, s.clientInfo(r))
+
+        // Add user and ledger to context
+        s.serveWithUser(w, r, user, next)
     })
 }
 
+func (s *Service) authenticateBearer(w http.ResponseWriter, r *http.Request, header string, next http.Handler) {
+    scheme, token, ok := strings.Cut(header, " ")
+    if !ok || !strings.EqualFold(scheme, "Bearer") {
+        utils.RespondWithError(w, http.StatusUnauthorized, "Unsupported authorization scheme")
+        return
+    }
+
+    user, auth, err := s.authenticateAPIToken(r.Context(), strings.TrimSpace(token))
+    if err != nil {
+        w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+        utils.RespondWithError(w, http.StatusUnauthorized, "Invalid API token")
+        return
+    }
+
+    scope := routeScope(r)
+    if scope == "" {
+        utils.RespondWithError(w, http.StatusForbidden, "This endpoint can't be used with an API token")
+        return
+    }
+    if !slices.Contains(auth.Scopes, scope) {
+        w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+        utils.RespondWithError(w, http.StatusForbidden, "API token is missing the "+scope+" scope")
+        return
+    }
+
+    s.serveWithUser(w, r, user, next)
+}
+
 func GetUserFromContext(ctx context.Context) (*database.User, bool) {
     user, ok := ctx.Value(UserContextKey).(*database.User)
     return user, ok
@@ -0,0 +1,310 @@
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/LuisBAndrade/etracker/internal/oidc"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/lib/pq"
+)
+
+const (
+    oidcStateCookie = "oidc_state"
+    oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+    ErrInvalidOIDCState     = errors.New("invalid or expired single sign-on request")
+    // The provider didn't vouch for the email, so it can't name an account
+    ErrOIDCEmailUnverified  = errors.New("identity provider did not return a verified email")
+    // An account with the email exists but never proved it owns the
+    // address; linking could hand over an account pre-registered by someone
+    // else
+    ErrOIDCLinkRefused      = errors.New("account email is not verified")
+)
+
+// oidcState rides in a signed cookie between the redirect to the provider
+// and the callback.
+type oidcState struct {
+    Request   oidc.AuthRequest `json:"req"`
+    Redirect  string           `json:"redirect"`
+    ExpiresAt int64            `json:"exp"`
+}
+
+// hasPassword is false for accounts created through single sign-on until
+// they set a password.
+func hasPassword(user *database.User) bool {
+    return user.HashedPassword != ""
+}
+
+func (s *Service) OIDCEnabled() bool {
+    return s.oidc != nil
+}
+
+// safeRedirect keeps post-login redirects on the frontend: only absolute
+// paths, never "//host" or "/\host" which browsers treat as another origin.
+func safeRedirect(path string) string {
+    if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+        return "/"
+    }
+    return path
+}
+
+// BeginOIDCLogin returns the provider URL to send the browser to and the
+// signed state for the oidc_state cookie.
+func (s *Service) BeginOIDCLogin(ctx context.Context, redirect string) (string, string, error) {
+    req, err := oidc.NewAuthRequest()
+    if err != nil {
+        return "", "", err
+    }
+    state, err := s.signClaims("oidc-state", oidcState{
+        Request:   *req,
+        Redirect:  safeRedirect(redirect),
+        ExpiresAt: time.Now().Add(oidcStateTTL).Unix(),
+    })
+    if err != nil {
+        return "", "", err
+    }
+    authURL, err := s.oidc.AuthCodeURL(ctx, req)
+    if err != nil {
+        return "", "", err
+    }
+    return authURL, state, nil
+}
+
+// CompleteOIDCLogin handles the provider callback. Like Login it returns
+// either a session or, for accounts with 2FA, a pending token. The second
+// value is where the frontend should go afterwards.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, cookieState, state, code string, client ClientInfo) (*LoginResult, string, error) {
+    var saved oidcState
+    if !s.parseClaims("oidc-state", cookieState, &saved) || time.Now().Unix() > saved.ExpiresAt {
+        return nil, "", ErrInvalidOIDCState
+    }
+    // The state parameter ties the callback to the browser that started it
+    if state == "" || state != saved.Request.State {
+        return nil, "", ErrInvalidOIDCState
+    }
+
+    claims, err := s.oidc.Exchange(ctx, code, &saved.Request)
+    if err != nil {
+        return nil, "", err
+    }
+
+    user, err := s.resolveOIDCUser(ctx, claims)
+    if err != nil {
+        return nil, "", err
+    }
+
+    // The provider stands in for the password, not for the second factor
+    enabled, err := s.twoFactorEnabled(ctx, user.ID)
+    if err != nil {
+        return nil, "", err
+    }
+    if enabled {
+        pending, expiresAt, err := s.createLoginChallenge(ctx, user.ID)
+        if err != nil {
+            return nil, "", err
+        }
+        return &LoginResult{User: user, PendingToken: pending, PendingExpiresAt: expiresAt}, saved.Redirect, nil
+    }
+
+    token, cookieExpiresAt, err := s.createSession(ctx, s.queries, user.ID, client)
+    if err != nil {
+        return nil, "", err
+    }
+    return &LoginResult{User: user, SessionToken: token, SessionExpiresAt: cookieExpiresAt}, saved.Redirect, nil
+}
+
+// resolveOIDCUser finds the account for a provider identity. Known
+// identities sign straight in; otherwise a verified email links to the
+// matching account, or a new password-less account is created.
+func (s *Service) resolveOIDCUser(ctx context.Context, claims *oidc.Claims) (*database.User, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    user, err := qtx.GetUserByIdentity(ctx, database.GetUserByIdentityParams{
+        Issuer:  claims.Issuer,
+        Subject: claims.Subject,
+    })
+    if err == nil {
+        if err := qtx.TouchUserIdentity(ctx, database.TouchUserIdentityParams{
+            Issuer:  claims.Issuer,
+            Subject: claims.Subject,
+            Email:   claims.Email,
+        }); err != nil {
+            return nil, err
+        }
+        if err := tx.Commit(); err != nil {
+            return nil, err
+        }
+        return &user, nil
+    } else if !errors.Is(err, sql.ErrNoRows) {
+        return nil, err
+    }
+
+    if claims.Email == "" || !claims.EmailVerified {
+        return nil, ErrOIDCEmailUnverified
+    }
+
+    linked := false
+    user, err = qtx.GetUserByEmail(ctx, claims.Email)
+    switch {
+    case err == nil:
+        if !user.EmailVerifiedAt.Valid {
+            return nil, ErrOIDCLinkRefused
+        }
+        linked = true
+    case errors.Is(err, sql.ErrNoRows):
+        user, err = qtx.CreateUser(ctx, database.CreateUserParams{
+            Email:          claims.Email,
+            HashedPassword: "",
+        })
+        if err != nil {
+            return nil, err
+        }
+        // The provider already verified the address
+        user, err = qtx.MarkUserEmailVerified(ctx, database.MarkUserEmailVerifiedParams{
+            ID:    user.ID,
+            Email: user.Email,
+        })
+        if err != nil {
+            return nil, err
+        }
+        ledger, err := createPersonalLedger(ctx, qtx, user.ID)
+        if err != nil {
+            return nil, err
+        }
+        if _, err := s.seeder.CreateDefaultCategories(ctx, qtx, ledger.ID, user.ID, ""); err != nil {
+            return nil, err
+        }
+    default:
+        return nil, err
+    }
+
+    _, err = qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+        UserID:  user.ID,
+        Issuer:  claims.Issuer,
+        Subject: claims.Subject,
+        Email:   claims.Email,
+    })
+    if err != nil {
+        var pqErr *pq.Error
+        // A concurrent callback for the same identity or email got there first
+        if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+            return nil, ErrInvalidOIDCState
+        }
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+
+    if linked {
+        s.sendInBackground(mailer.Message{
+            To:      user.Email,
+            Subject: "Single sign-on was linked to your account",
+            Body: fmt.Sprintf("You can now sign in with your %s account.\n\n"+
+                "If this wasn't you, reset your password and sign out other sessions:\n\n%s\n",
+                claims.Issuer, s.appLink("/reset-password", nil)),
+        })
+    }
+    return &user, nil
+}
+
+func oidcStateCookieFor(value string, maxAge int) *http.Cookie {
+    return &http.Cookie{
+        Name:     oidcStateCookie,
+        Value:    value,
+        Path:     "/api/auth/oidc",
+        MaxAge:   maxAge,
+        HttpOnly: true,
+        Secure:   false, // must be false for localhost HTTP
+        // Lax still sends it on the provider's top-level redirect back
+        SameSite: http.SameSiteLaxMode,
+    }
+}
+
+// redirectLoginError sends the browser back to the frontend login page,
+// since the callback is a navigation and not an API call.
+func (s *Service) redirectLoginError(w http.ResponseWriter, r *http.Request, code string) {
+    http.Redirect(w, r, s.appLink("/login", url.Values{"error": {code}}), http.StatusFound)
+}
+
+func (s *Service) HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+    if !s.OIDCEnabled() {
+        utils.RespondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+        return
+    }
+
+    authURL, state, err := s.BeginOIDCLogin(r.Context(), r.URL.Query().Get("redirect"))
+    if err != nil {
+        log.Printf("Failed to start single sign-on: %v", err)
+        s.redirectLoginError(w, r, "sso_unavailable")
+        return
+    }
+
+    http.SetCookie(w, oidcStateCookieFor(state, int(oidcStateTTL.Seconds())))
+    http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Service) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+    if !s.OIDCEnabled() {
+        utils.RespondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+        return
+    }
+
+    // The state is single use
+    http.SetCookie(w, oidcStateCookieFor("", -1))
+
+    query := r.URL.Query()
+    if query.Get("error") != "" {
+        // Usually the user cancelled at the provider
+        s.redirectLoginError(w, r, "sso_cancelled")
+        return
+    }
+    cookie, err := r.Cookie(oidcStateCookie)
+    if err != nil {
+        s.redirectLoginError(w, r, "sso_expired")
+        return
+    }
+
+    result, redirect, err := s.CompleteOIDCLogin(r.Context(), cookie.Value, query.Get("state"), query.Get("code"), s.clientInfo(r))
+    if err != nil {
+        switch {
+        case errors.Is(err, ErrInvalidOIDCState):
+            s.redirectLoginError(w, r, "sso_expired")
+        case errors.Is(err, ErrOIDCEmailUnverified):
+            s.redirectLoginError(w, r, "sso_email_unverified")
+        case errors.Is(err, ErrOIDCLinkRefused):
+            s.redirectLoginError(w, r, "sso_link_refused")
+        default:
+            log.Printf("Single sign-on failed: %v", err)
+            s.redirectLoginError(w, r, "sso_failed")
+        }
+        return
+    }
+
+    if result.PendingToken != "" {
+        // In the fragment so it stays out of logs and Referer headers
+        fragment := url.Values{"pending_token": {result.PendingToken}, "redirect": {redirect}}
+        http.Redirect(w, r, s.appLink("/login/2fa", nil)+"#"+fragment.Encode(), http.StatusFound)
+        return
+    }
+
+    setSessionCookie(w, result.SessionToken, result.SessionExpiresAt)
+    http.Redirect(w, r, s.appURL+redirect, http.StatusFound)
+}
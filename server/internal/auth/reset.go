@@ -0,0 +1,218 @@
+package auth
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type PasswordResetRequest struct {
+    Email string `json:"email" validate:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+    Token    string `json:"token" validate:"required"`
+    Password string `json:"password" validate:"required,min=6"`
+}
+
+// hashToken is how single-use tokens are stored, so a database leak does not
+// hand out working links.
+func hashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset mails a reset link if the email belongs to an
+// account. The account lookup, the token and the mail all happen in the
+// background and the call returns at once either way, so neither the
+// response nor how long it takes shows whether the account exists.
+func (s *Service) RequestPasswordReset(email string) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        defer cancel()
+        if err := s.sendPasswordReset(ctx, email); err != nil {
+            log.Printf("Failed to send password reset email: %v", err)
+        }
+    }()
+}
+
+// sendPasswordReset issues a reset token for the account with email and
+// mails the link. Unknown emails are silently ignored.
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+    user, err := s.queries.GetUserByEmail(ctx, email)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil
+        }
+        return err
+    }
+    
+    token, err := s.generateSessionToken()
+    if err != nil {
+        return err
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    // Only the most recent link stays valid
+    if err := qtx.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+        return err
+    }
+    _, err = qtx.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+        UserID:    user.ID,
+        TokenHash: hashToken(token),
+        ExpiresAt: time.Now().Add(passwordResetTTL),
+    })
+    if err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    
+    msg := mailer.Message{
+        To:      user.Email,
+        Subject: "Reset your password",
+        Body: fmt.Sprintf("Someone asked to reset the password for this account.\n\n"+
+            "Open the link below within %d minutes to choose a new password:\n\n%s\n\n"+
+            "If this wasn't you, you can ignore this email.\n",
+            int(passwordResetTTL.Minutes()), s.appLink("/reset-password", url.Values{"token": {token}})),
+    }
+    return s.mailer.Send(ctx, msg)
+}
+
+// sendInBackground delivers mail without making the request wait, so
+// responses take the same time whether or not a mail was sent.
+func (s *Service) sendInBackground(msg mailer.Message) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        defer cancel()
+        if err := s.mailer.Send(ctx, msg); err != nil {
+            log.Printf("Failed to send %q email: %v", msg.Subject, err)
+        }
+    }()
+}
+
+// ConfirmPasswordReset sets a new password using a token from
+// RequestPasswordReset, uses up the token and signs out every session.
+func (s *Service) ConfirmPasswordReset(ctx context.Context, token, password string) error {
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return err
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    reset, err := qtx.GetValidPasswordResetToken(ctx, hashToken(token))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrInvalidResetToken
+        }
+        return err
+    }
+    if err := qtx.MarkPasswordResetTokenUsed(ctx, reset.ID); err != nil {
+        return err
+    }
+    
+    user, err := qtx.GetUserByID(ctx, reset.UserID)
+    if err != nil {
+        return err
+    }
+    _, err = qtx.UpdateUser(ctx, database.UpdateUserParams{
+        ID:             user.ID,
+        Email:          user.Email,
+        HashedPassword: string(hashedPassword),
+    })
+    if err != nil {
+        return err
+    }
+    if err := qtx.RevokeAllUserSessions(ctx, user.ID); err != nil {
+        return err
+    }
+    
+    return tx.Commit()
+}
+
+func (s *Service) CleanupExpiredPasswordResetTokens(ctx context.Context) error {
+    return s.queries.CleanupExpiredPasswordResetTokens(ctx)
+}
+
+// appLink builds a link into the frontend for emails.
+func (s *Service) appLink(path string, query url.Values) string {
+    if len(query) == 0 {
+        return s.appURL + path
+    }
+    return s.appURL + path + "?" + query.Encode()
+}
+
+func (s *Service) HandleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+    var req PasswordResetRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    s.RequestPasswordReset(req.Email)
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "If an account exists for that email, a reset link has been sent",
+    })
+}
+
+func (s *Service) HandleConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+    var req ConfirmPasswordResetRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    if err := s.ConfirmPasswordReset(r.Context(), req.Token, req.Password); err != nil {
+        if err == ErrInvalidResetToken {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Password reset successfully",
+    })
+}
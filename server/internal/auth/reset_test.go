@@ -0,0 +1,102 @@
+package auth
+
+import (
+    "context"
+    "database/sql/driver"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/google/uuid"
+)
+
+// recordingMailer keeps every message instead of sending it.
+type recordingMailer struct {
+    mu   sync.Mutex
+    sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mailer.Message) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.sent = append(m.sent, msg)
+    return nil
+}
+
+func (m *recordingMailer) Sent() []mailer.Message {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return append([]mailer.Message(nil), m.sent...)
+}
+
+// The lookup is what tells known and unknown emails apart, so the request
+// must not wait for it.
+func TestRequestPasswordResetDoesNotWaitForLookup(t *testing.T) {
+    release := make(chan struct{})
+    looked := make(chan struct{})
+    _, db, queries := newFakeDB(t, func(name string, args []driver.Value) fakeResult {
+        close(looked)
+        <-release
+        return noRows(8)
+    })
+    s := &Service{db: db, queries: queries, mailer: &recordingMailer{}}
+
+    done := make(chan struct{})
+    go func() {
+        s.RequestPasswordReset("ada@example.com")
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("RequestPasswordReset waited for the account lookup")
+    }
+    <-looked
+    close(release)
+}
+
+func TestSendPasswordReset(t *testing.T) {
+    tests := []struct {
+        name     string
+        known    bool
+        wantMail bool
+    }{
+        {"known account", true, true},
+        {"unknown email", false, false},
+    }
+
+    for _, tt := range tests {
+        userID := uuid.New()
+        f, db, queries := newFakeDB(t, func(name string, args []driver.Value) fakeResult {
+            switch name {
+            case "GetUserByEmail":
+                if tt.known {
+                    return userRow(database.User{ID: userID, Email: "ada@example.com"})
+                }
+                return noRows(8)
+            case "CreatePasswordResetToken":
+                return oneRow(uuid.New().String(), userID.String(), args[1], args[2], nil, time.Now())
+            }
+            return fakeResult{}
+        })
+        mail := &recordingMailer{}
+        s := &Service{db: db, queries: queries, mailer: mail, appURL: "https://app.example"}
+
+        if err := s.sendPasswordReset(context.Background(), "ada@example.com"); err != nil {
+            t.Fatalf("%s: %v", tt.name, err)
+        }
+        sent := mail.Sent()
+        if !tt.wantMail {
+            if len(sent) != 0 || len(f.Calls()) != 1 {
+                t.Errorf("%s: sent %d mails after queries %v", tt.name, len(sent), f.Calls())
+            }
+            continue
+        }
+        if len(sent) != 1 || sent[0].To != "ada@example.com" || !strings.Contains(sent[0].Body, "https://app.example/reset-password?token=") {
+            t.Errorf("%s: sent %+v", tt.name, sent)
+        }
+    }
+}
@@ -3,12 +3,20 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/mailer"
+	"github.com/LuisBAndrade/etracker/internal/oidc"
+	"github.com/LuisBAndrade/etracker/internal/ratelimit"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,74 +24,220 @@ var (
     ErrInvalidCredentials = errors.New("invalid credentials")
     ErrUserExists        = errors.New("user already exists")
     ErrInvalidSession    = errors.New("invalid session")
+    ErrUnknownTemplate   = errors.New("unknown category template")
 )
 
+// CategorySeeder creates a new user's starter categories in their personal
+// ledger inside the registration transaction.
+type CategorySeeder interface {
+    ValidTemplate(name string) bool
+    CreateDefaultCategories(ctx context.Context, queries *database.Queries, ledgerID, userID uuid.UUID, template string) ([]database.Category, error)
+}
+
+// Options holds the deployment settings of the auth service.
+type Options struct {
+    // Base URL of the frontend, used for links in emails
+    AppURL string
+    // Key for signing stateless tokens such as email verification links
+    Secret []byte
+    // Block unverified accounts from routes wrapped in RequireVerifiedEmail
+    RequireVerifiedEmail bool
+    // Failed login tracking per client IP and per account; nil disables it
+    IPLimiter      ratelimit.Limiter
+    AccountLimiter ratelimit.Limiter
+    // Cooldown between verification mail resends per account; nil disables it
+    ResendLimiter ratelimit.Limiter
+    // Take the client IP from X-Forwarded-For / X-Real-IP
+    TrustProxy bool
+    // How long a session lasts, or with SlidingSessions how long it may sit
+    // idle; sliding never extends it past SessionMaxLifetime
+    SessionDuration    time.Duration
+    SlidingSessions    bool
+    SessionMaxLifetime time.Duration
+    // Single sign-on provider; nil disables the OIDC routes
+    OIDC *oidc.Provider
+}
+
 type Service struct {
-    queries *database.Queries
+    db              *sql.DB
+    queries         *database.Queries
+    seeder          CategorySeeder
+    mailer          mailer.Mailer
+    appURL          string
+    secret          []byte
+    requireVerified bool
+    ipLimiter       ratelimit.Limiter
+    accountLimiter  ratelimit.Limiter
+    resendLimiter   ratelimit.Limiter
+    trustProxy      bool
+    sessionDuration time.Duration
+    slidingSessions bool
+    sessionMaxLife  time.Duration
+    oidc            *oidc.Provider
 }
 
-func NewService(queries *database.Queries) *Service {
+func NewService(db *sql.DB, queries *database.Queries, seeder CategorySeeder, mail mailer.Mailer, opts Options) *Service {
+    if opts.SessionDuration <= 0 {
+        opts.SessionDuration = 7 * 24 * time.Hour
+    }
+    if !opts.SlidingSessions || opts.SessionMaxLifetime < opts.SessionDuration {
+        opts.SessionMaxLifetime = opts.SessionDuration
+    }
     return &Service{
-        queries: queries,
+        db:              db,
+        queries:         queries,
+        seeder:          seeder,
+        mailer:          mail,
+        appURL:          strings.TrimRight(opts.AppURL, "/"),
+        secret:          opts.Secret,
+        requireVerified: opts.RequireVerifiedEmail,
+        ipLimiter:       opts.IPLimiter,
+        accountLimiter:  opts.AccountLimiter,
+        resendLimiter:   opts.ResendLimiter,
+        trustProxy:      opts.TrustProxy,
+        sessionDuration: opts.SessionDuration,
+        slidingSessions: opts.SlidingSessions,
+        sessionMaxLife:  opts.SessionMaxLifetime,
+        oidc:            opts.OIDC,
     }
 }
 
-func (s *Service) Register(ctx context.Context, email, password string) (*database.User, error) {
+// Register creates the user and seeds categories from the named template
+// set ("" for the default) in one transaction. For a taken email it does the
+// same amount of work and mails the owner instead, so callers can answer
+// both cases identically; ErrUserExists is still returned for them to know.
+func (s *Service) Register(ctx context.Context, email, password, template string) (*database.User, error) {
+    if !s.seeder.ValidTemplate(template) {
+        return nil, ErrUnknownTemplate
+    }
+
+    // Hash password
+    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return nil, err
+    }
+
     // Check if user exists
-    _, err := s.queries.GetUserByEmail(ctx, email)
+    existing, err := s.queries.GetUserByEmail(ctx, email)
     if err == nil {
+        s.sendInBackground(mailer.Message{
+            To:      existing.Email,
+            Subject: "Someone tried to sign up with your email",
+            Body: fmt.Sprintf("An account already exists for this address, so no new one was created.\n\n"+
+                "If this was you, sign in or reset your password here:\n\n%s\n",
+                s.appLink("/reset-password", nil)),
+        })
         return nil, ErrUserExists
+    } else if !errors.Is(err, sql.ErrNoRows) {
+        return nil, err
     }
 
-    // Hash password
-    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    tx, err := s.db.BeginTx(ctx, nil)
     if err != nil {
         return nil, err
     }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
 
     // Create user
-    user, err := s.queries.CreateUser(ctx, database.CreateUserParams{
+    user, err := qtx.CreateUser(ctx, database.CreateUserParams{
         Email:          email,
         HashedPassword: string(hashedPassword),
     })
     if err != nil {
+        var pqErr *pq.Error
+        if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+            return nil, ErrUserExists
+        }
+        return nil, err
+    }
+
+    ledger, err := createPersonalLedger(ctx, qtx, user.ID)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := s.seeder.CreateDefaultCategories(ctx, qtx, ledger.ID, user.ID, template); err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
         return nil, err
     }
 
+    // The account works without it, so a mail failure doesn't undo signup
+    msg, err := s.verificationEmail(&user)
+    if err != nil {
+        log.Printf("Failed to create verification email: %v", err)
+    } else {
+        s.sendInBackground(msg)
+    }
+
     return &user, nil
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (*database.User, string, error) {
+// LoginResult is either a signed-in session or, for accounts with 2FA, a
+// pending token to pass to CompleteLogin with the second factor.
+type LoginResult struct {
+    User             *database.User
+    SessionToken     string
+    SessionExpiresAt time.Time
+    PendingToken     string
+    PendingExpiresAt time.Time
+}
+
+// Login checks the password of an account. client.IP is used for rate
+// limiting; a blocked IP or account gets a *TooManyAttemptsError.
+func (s *Service) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+    ip := client.IP
+    if err := s.checkLoginAllowed(ctx, ip, email); err != nil {
+        return nil, err
+    }
+
     // Get user by email
     user, err := s.queries.GetUserByEmail(ctx, email)
     if err != nil {
-        return nil, "", ErrInvalidCredentials
+        if !errors.Is(err, sql.ErrNoRows) {
+            return nil, err
+        }
+        equalizeTiming(password)
+        s.recordLoginFailure(ctx, ip, email)
+        return nil, ErrInvalidCredentials
+    }
+
+    // Accounts created through an identity provider can't log in with a
+    // password until they set one
+    if !hasPassword(&user) {
+        equalizeTiming(password)
+        s.recordLoginFailure(ctx, ip, email)
+        return nil, ErrInvalidCredentials
     }
 
     // Verify password
     err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password))
     if err != nil {
-        return nil, "", ErrInvalidCredentials
+        s.recordLoginFailure(ctx, ip, email)
+        return nil, ErrInvalidCredentials
     }
 
-    // Create session token
-    token, err := s.generateSessionToken()
+    enabled, err := s.twoFactorEnabled(ctx, user.ID)
     if err != nil {
-        return nil, "", err
+        return nil, err
+    }
+    if enabled {
+        pending, expiresAt, err := s.createLoginChallenge(ctx, user.ID)
+        if err != nil {
+            return nil, err
+        }
+        return &LoginResult{User: &user, PendingToken: pending, PendingExpiresAt: expiresAt}, nil
     }
 
-    // Save session
-    expiresAt := time.Now().Add(24 * time.Hour * 7) // 7 days
-    err = s.queries.CreateSession(ctx, database.CreateSessionParams{
-        Token:     token,
-        UserID:    user.ID,
-        ExpiresAt: expiresAt,
-    })
+    token, cookieExpiresAt, err := s.createSession(ctx, s.queries, user.ID, client)
     if err != nil {
-        return nil, "", err
+        return nil, err
     }
+    s.recordLoginSuccess(ctx, email)
 
-    return &user, token, nil
+    return &LoginResult{User: &user, SessionToken: token, SessionExpiresAt: cookieExpiresAt}, nil
 }
 
 func (s *Service) GetUserBySession(ctx context.Context, token string) (*database.User, error) {
@@ -102,6 +256,17 @@ func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
     return s.queries.RevokeAllUserSessions(ctx, userID)
 }
 
+func (s *Service) UpdateBaseCurrency(ctx context.Context, userID uuid.UUID, currency string) (*database.User, error) {
+    user, err := s.queries.UpdateUserBaseCurrency(ctx, database.UpdateUserBaseCurrencyParams{
+        ID:           userID,
+        BaseCurrency: currency,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &user, nil
+}
+
 func (s *Service) generateSessionToken() (string, error) {
     bytes := make([]byte, 32)
     if _, err := rand.Read(bytes); err != nil {
@@ -112,4 +277,44 @@ func (s *Service) generateSessionToken() (string, error) {
 
 func (s *Service) CleanupExpiredSessions(ctx context.Context) error {
     return s.queries.CleanupExpiredSessions(ctx)
-}
\ No newline at end of file
+}
+// RunCleanup periodically deletes expired sessions, tokens, invitations and
+// forgotten login attempts until ctx is cancelled.
+func (s *Service) RunCleanup(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        s.cleanup(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (s *Service) cleanup(ctx context.Context) {
+    type task struct {
+        name string
+        run  func(context.Context) error
+    }
+    tasks := []task{
+        {"sessions", s.CleanupExpiredSessions},
+        {"password reset tokens", s.CleanupExpiredPasswordResetTokens},
+        {"email change tokens", s.CleanupExpiredEmailChangeTokens},
+        {"login challenges", s.CleanupExpiredLoginChallenges},
+        {"ledger invitations", s.CleanupExpiredLedgerInvitations},
+    }
+    // Only limiters that keep state outside the process need it
+    for _, limiter := range []ratelimit.Limiter{s.ipLimiter, s.accountLimiter, s.resendLimiter} {
+        if c, ok := limiter.(interface{ Cleanup(context.Context) error }); ok {
+            tasks = append(tasks, task{"rate limit attempts", c.Cleanup})
+        }
+    }
+    for _, t := range tasks {
+        if err := t.run(ctx); err != nil {
+            log.Printf("Failed to clean up %s: %v", t.name, err)
+        }
+    }
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+    "context"
+    "errors"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+const maxUserAgentLength = 512
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// ClientInfo describes where a request came from, for rate limiting and the
+// session list.
+type ClientInfo struct {
+    IP        string
+    UserAgent string
+}
+
+func (s *Service) clientInfo(r *http.Request) ClientInfo {
+    userAgent := r.UserAgent()
+    if len(userAgent) > maxUserAgentLength {
+        userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+    }
+    return ClientInfo{IP: s.clientIP(r), UserAgent: userAgent}
+}
+
+// createSession starts a session and returns its token along with when the
+// cookie should expire. The cookie lives until the absolute expiry; the
+// server decides earlier expiry.
+func (s *Service) createSession(ctx context.Context, queries *database.Queries, userID uuid.UUID, client ClientInfo) (string, time.Time, error) {
+    // Create session token
+    token, err := s.generateSessionToken()
+    if err != nil {
+        return "", time.Time{}, err
+    }
+
+    // Save session
+    now := time.Now()
+    absoluteExpiresAt := now.Add(s.sessionMaxLife)
+    err = queries.CreateSession(ctx, database.CreateSessionParams{
+        Token:             token,
+        UserID:            userID,
+        ExpiresAt:         now.Add(s.sessionDuration),
+        AbsoluteExpiresAt: absoluteExpiresAt,
+        UserAgent:         client.UserAgent,
+        IpAddress:         client.IP,
+    })
+    if err != nil {
+        return "", time.Time{}, err
+    }
+
+    return token, absoluteExpiresAt, nil
+}
+
+// touchSession records activity on a session and, with sliding sessions,
+// pushes its expiry out again. It is best effort: a failure is logged and
+// the request carries on.
+func (s *Service) touchSession(ctx context.Context, token string, client ClientInfo) {
+    err := s.queries.TouchSession(ctx, database.TouchSessionParams{
+        IpAddress:   client.IP,
+        Slide:       s.slidingSessions,
+        IdleSeconds: s.sessionDuration.Seconds(),
+        Token:       token,
+    })
+    if err != nil {
+        log.Printf("Failed to update session activity: %v", err)
+    }
+}
+
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID, currentToken string) ([]database.ListUserSessionsRow, error) {
+    sessions, err := s.queries.ListUserSessions(ctx, database.ListUserSessionsParams{
+        CurrentToken: currentToken,
+        UserID:       userID,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if sessions == nil {
+        sessions = []database.ListUserSessionsRow{}
+    }
+    return sessions, nil
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+    revoked, err := s.queries.RevokeUserSession(ctx, database.RevokeUserSessionParams{
+        ID:     sessionID,
+        UserID: userID,
+    })
+    if err != nil {
+        return err
+    }
+    if revoked == 0 {
+        return ErrSessionNotFound
+    }
+    return nil
+}
+
+type SessionResponse struct {
+    ID         string    `json:"id"`
+    UserAgent  string    `json:"user_agent"`
+    IPAddress  string    `json:"ip_address"`
+    CreatedAt  time.Time `json:"created_at"`
+    LastSeenAt time.Time `json:"last_seen_at"`
+    ExpiresAt  time.Time `json:"expires_at"`
+    Current    bool      `json:"current"`
+}
+
+func (s *Service) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    // AuthMiddleware already required the cookie
+    cookie, _ := r.Cookie("session_token")
+    
+    sessions, err := s.ListSessions(r.Context(), user.ID, cookie.Value)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get sessions")
+        return
+    }
+    
+    response := make([]SessionResponse, len(sessions))
+    for i, session := range sessions {
+        response[i] = SessionResponse{
+            ID:         session.ID.String(),
+            UserAgent:  session.UserAgent,
+            IPAddress:  session.IpAddress,
+            CreatedAt:  session.CreatedAt,
+            LastSeenAt: session.LastSeenAt,
+            ExpiresAt:  session.ExpiresAt,
+            Current:    session.Current,
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid session ID")
+        return
+    }
+    
+    if err := s.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+        if err == ErrSessionNotFound {
+            utils.RespondWithError(w, http.StatusNotFound, "Session not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Session revoked successfully",
+    })
+}
@@ -0,0 +1,360 @@
+package auth
+
+import (
+    "context"
+    "crypto/rand"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math/big"
+    "net/http"
+    "slices"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+// Scopes a personal API token can be granted. Session cookies have them all.
+const (
+    ScopeExpensesRead    = "expenses:read"
+    ScopeExpensesWrite   = "expenses:write"
+    ScopeCategoriesRead  = "categories:read"
+    ScopeCategoriesWrite = "categories:write"
+)
+
+var AllScopes = []string{ScopeExpensesRead, ScopeExpensesWrite, ScopeCategoriesRead, ScopeCategoriesWrite}
+
+const (
+    // Tokens look like "etk_" followed by 40 base62 characters
+    apiTokenPrefix      = "etk_"
+    apiTokenLength      = 40
+    // Characters of the token kept in the clear for display
+    apiTokenPrefixShown = len(apiTokenPrefix) + 8
+    maxAPITokensPerUser = 50
+)
+
+var (
+    ErrInvalidScope     = errors.New("invalid scope")
+    ErrAPITokenNotFound = errors.New("API token not found")
+    ErrTooManyAPITokens = errors.New("too many API tokens")
+)
+
+// APIToken is a newly created token; Token is only available here.
+type APIToken struct {
+    database.ApiToken
+    Token string
+}
+
+// tokenAuth is what a bearer token grants.
+type tokenAuth struct {
+    ID     uuid.UUID
+    Scopes []string
+}
+
+func generateAPIToken() (string, error) {
+    const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+    var b strings.Builder
+    b.WriteString(apiTokenPrefix)
+    base := big.NewInt(int64(len(alphabet)))
+    for i := 0; i < apiTokenLength; i++ {
+        n, err := rand.Int(rand.Reader, base)
+        if err != nil {
+            return "", err
+        }
+        b.WriteByte(alphabet[n.Int64()])
+    }
+    return b.String(), nil
+}
+
+// normalizeScopes validates scopes and returns them sorted without
+// duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+    if len(scopes) == 0 {
+        return nil, ErrInvalidScope
+    }
+    out := make([]string, 0, len(scopes))
+    for _, scope := range scopes {
+        if !slices.Contains(AllScopes, scope) {
+            return nil, ErrInvalidScope
+        }
+        if !slices.Contains(out, scope) {
+            out = append(out, scope)
+        }
+    }
+    slices.Sort(out)
+    return out, nil
+}
+
+func (s *Service) CreateAPIToken(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*APIToken, error) {
+    scopes, err := normalizeScopes(scopes)
+    if err != nil {
+        return nil, err
+    }
+    
+    existing, err := s.queries.GetAPITokensByUser(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if len(existing) >= maxAPITokensPerUser {
+        return nil, ErrTooManyAPITokens
+    }
+    
+    token, err := generateAPIToken()
+    if err != nil {
+        return nil, err
+    }
+    params := database.CreateAPITokenParams{
+        UserID:    userID,
+        Name:      name,
+        Prefix:    token[:apiTokenPrefixShown],
+        TokenHash: hashToken(token),
+        Scopes:    scopes,
+    }
+    if expiresAt != nil {
+        params.ExpiresAt = sql.NullTime{Time: *expiresAt, Valid: true}
+    }
+    created, err := s.queries.CreateAPIToken(ctx, params)
+    if err != nil {
+        return nil, err
+    }
+    return &APIToken{ApiToken: created, Token: token}, nil
+}
+
+func (s *Service) GetAPITokens(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+    tokens, err := s.queries.GetAPITokensByUser(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if tokens == nil {
+        tokens = []database.ApiToken{}
+    }
+    return tokens, nil
+}
+
+func (s *Service) RevokeAPIToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+    deleted, err := s.queries.DeleteAPIToken(ctx, database.DeleteAPITokenParams{
+        ID:     tokenID,
+        UserID: userID,
+    })
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrAPITokenNotFound
+    }
+    return nil
+}
+
+// authenticateAPIToken resolves a bearer token to its user and scopes.
+func (s *Service) authenticateAPIToken(ctx context.Context, token string) (*database.User, *tokenAuth, error) {
+    if !strings.HasPrefix(token, apiTokenPrefix) {
+        return nil, nil, ErrInvalidSession
+    }
+    row, err := s.queries.GetUserByAPIToken(ctx, hashToken(token))
+    if err != nil {
+        return nil, nil, ErrInvalidSession
+    }
+    if err := s.queries.TouchAPIToken(ctx, row.TokenID); err != nil {
+        log.Printf("Failed to update API token activity: %v", err)
+    }
+    
+    user := database.User{
+        ID:              row.ID,
+        CreatedAt:       row.CreatedAt,
+        UpdatedAt:       row.UpdatedAt,
+        Email:           row.Email,
+        HashedPassword:  row.HashedPassword,
+        BaseCurrency:    row.BaseCurrency,
+        EmailVerifiedAt: row.EmailVerifiedAt,
+        ActiveLedgerID:  row.ActiveLedgerID,
+    }
+    return &user, &tokenAuth{ID: row.TokenID, Scopes: row.Scopes}, nil
+}
+
+// scopedHandler marks a route as reachable with an API token holding scope.
+type scopedHandler struct {
+    scope string
+    next  http.Handler
+}
+
+func (h *scopedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    h.next.ServeHTTP(w, r)
+}
+
+// RequireScope opens a route to API tokens that carry scope. Routes not
+// wrapped this way only accept the session cookie. The check itself happens
+// in AuthMiddleware, which sees the matched route's handler.
+func RequireScope(scope string, next http.HandlerFunc) http.Handler {
+    return &scopedHandler{scope: scope, next: next}
+}
+
+// routeScope is the scope the matched route requires from API tokens, or
+// "" if tokens may not use it.
+func routeScope(r *http.Request) string {
+    route := mux.CurrentRoute(r)
+    if route == nil {
+        return ""
+    }
+    handler := route.GetHandler()
+    // Handlers can be wrapped further, e.g. by RequireVerifiedEmail
+    for {
+        switch h := handler.(type) {
+        case *scopedHandler:
+            return h.scope
+        case *verifiedHandler:
+            handler = h.next
+        case *editorHandler:
+            handler = h.next
+        default:
+            return ""
+        }
+    }
+}
+
+const (
+    maxAPITokenNameLength = 100
+    maxAPITokenDays       = 3650
+)
+
+type CreateAPITokenRequest struct {
+    Name   string   `json:"name" validate:"required"` // at most maxAPITokenNameLength characters
+    Scopes []string `json:"scopes"`
+    // Optional lifetime in days, 1 to maxAPITokenDays; omitted means the
+    // token doesn't expire
+    ExpiresInDays *int `json:"expires_in_days"`
+}
+
+type APITokenResponse struct {
+    ID         string     `json:"id"`
+    Name       string     `json:"name"`
+    Prefix     string     `json:"prefix"`
+    Scopes     []string   `json:"scopes"`
+    ExpiresAt  *time.Time `json:"expires_at"`
+    LastUsedAt *time.Time `json:"last_used_at"`
+    CreatedAt  time.Time  `json:"created_at"`
+    // Only set in the response to creation
+    Token string `json:"token,omitempty"`
+}
+
+func apiTokenResponse(token database.ApiToken) APITokenResponse {
+    response := APITokenResponse{
+        ID:        token.ID.String(),
+        Name:      token.Name,
+        Prefix:    token.Prefix,
+        Scopes:    token.Scopes,
+        CreatedAt: token.CreatedAt,
+    }
+    if token.ExpiresAt.Valid {
+        response.ExpiresAt = &token.ExpiresAt.Time
+    }
+    if token.LastUsedAt.Valid {
+        response.LastUsedAt = &token.LastUsedAt.Time
+    }
+    return response
+}
+
+func (s *Service) HandleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req CreateAPITokenRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    req.Name = strings.TrimSpace(req.Name)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if utf8.RuneCountInString(req.Name) > maxAPITokenNameLength {
+        utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Name must be at most %d characters", maxAPITokenNameLength))
+        return
+    }
+    if req.ExpiresInDays != nil && (*req.ExpiresInDays < 1 || *req.ExpiresInDays > maxAPITokenDays) {
+        utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("expires_in_days must be between 1 and %d", maxAPITokenDays))
+        return
+    }
+    
+    var expiresAt *time.Time
+    if req.ExpiresInDays != nil {
+        t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+        expiresAt = &t
+    }
+    
+    token, err := s.CreateAPIToken(r.Context(), user.ID, req.Name, req.Scopes, expiresAt)
+    if err != nil {
+        switch err {
+        case ErrInvalidScope:
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid scopes, use: "+strings.Join(AllScopes, ", "))
+        case ErrTooManyAPITokens:
+            utils.RespondWithError(w, http.StatusConflict, "Too many API tokens, revoke one first")
+        default:
+            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create API token")
+        }
+        return
+    }
+    
+    response := apiTokenResponse(token.ApiToken)
+    response.Token = token.Token
+    utils.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (s *Service) HandleGetAPITokens(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    tokens, err := s.GetAPITokens(r.Context(), user.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get API tokens")
+        return
+    }
+    
+    response := make([]APITokenResponse, len(tokens))
+    for i, token := range tokens {
+        response[i] = apiTokenResponse(token)
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    tokenID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid token ID")
+        return
+    }
+    
+    if err := s.RevokeAPIToken(r.Context(), user.ID, tokenID); err != nil {
+        if err == ErrAPITokenNotFound {
+            utils.RespondWithError(w, http.StatusNotFound, "API token not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke API token")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "API token revoked successfully",
+    })
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+    "context"
+    "database/sql/driver"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+func TestAPITokenUsesActiveLedger(t *testing.T) {
+    userID := uuid.New()
+    tokenID := uuid.New()
+    ownLedger := uuid.New()
+    sharedLedger := uuid.New()
+    now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name        string
+        header      string
+        stillMember bool
+        want        uuid.UUID
+    }{
+        {"active ledger", "", true, sharedLedger},
+        {"left the active ledger", "", false, ownLedger},
+        {"header wins", ownLedger.String(), true, ownLedger},
+    }
+
+    for _, tt := range tests {
+        _, _, queries := newFakeDB(t, func(name string, args []driver.Value) fakeResult {
+            switch name {
+            case "GetUserByAPIToken":
+                return oneRow(userID.String(), now, now, "ada@example.com", "hash", "EUR", now,
+                    sharedLedger.String(), tokenID.String(), []byte("{expenses:read}"))
+            case "TouchAPIToken":
+                return fakeResult{affected: 1}
+            case "GetLedgerAccess":
+                switch args[1] {
+                case nil, ownLedger.String():
+                    return oneRow(ownLedger.String(), "Personal", RoleOwner)
+                case sharedLedger.String():
+                    if tt.stillMember {
+                        return oneRow(sharedLedger.String(), "Shared", RoleEditor)
+                    }
+                }
+                return noRows(3)
+            }
+            t.Fatalf("unexpected query %s", name)
+            return fakeResult{}
+        })
+        s := &Service{queries: queries}
+
+        user, token, err := s.authenticateAPIToken(context.Background(), apiTokenPrefix+"secret")
+        if err != nil {
+            t.Fatalf("%s: authenticateAPIToken: %v", tt.name, err)
+        }
+        if token.ID != tokenID || !reflect.DeepEqual(token.Scopes, []string{ScopeExpensesRead}) {
+            t.Errorf("%s: token = %+v", tt.name, token)
+        }
+        if !user.ActiveLedgerID.Valid || user.ActiveLedgerID.UUID != sharedLedger {
+            t.Errorf("%s: ActiveLedgerID = %v, want %s", tt.name, user.ActiveLedgerID, sharedLedger)
+        }
+
+        // Every users column the query selects must reach the user, so
+        // a column added later cannot silently go missing here.
+        v := reflect.ValueOf(*user)
+        for i := 0; i < v.NumField(); i++ {
+            if v.Field(i).IsZero() {
+                t.Errorf("%s: user.%s was not copied from the token row", tt.name, v.Type().Field(i).Name)
+            }
+        }
+
+        r := httptest.NewRequest("GET", "/expenses", nil)
+        if tt.header != "" {
+            r.Header.Set(LedgerHeader, tt.header)
+        }
+        ledger, err := s.resolveLedger(r.Context(), r, user)
+        if err != nil {
+            t.Fatalf("%s: resolveLedger: %v", tt.name, err)
+        }
+        if ledger == nil || ledger.ID != tt.want {
+            t.Errorf("%s: ledger = %+v, want %s", tt.name, ledger, tt.want)
+        }
+    }
+}
+
+func TestAPITokenRejectsForeignPrefix(t *testing.T) {
+    f, _, queries := newFakeDB(t, func(string, []driver.Value) fakeResult { return noRows(10) })
+    s := &Service{queries: queries}
+
+    if _, _, err := s.authenticateAPIToken(context.Background(), "not-a-token"); err != ErrInvalidSession {
+        t.Errorf("error = %v, want %v", err, ErrInvalidSession)
+    }
+    if calls := f.Calls(); len(calls) != 0 {
+        t.Errorf("queried the database for a malformed token: %v", calls)
+    }
+}
@@ -0,0 +1,566 @@
+package auth
+
+import (
+    "context"
+    "database/sql"
+    "crypto/rand"
+    "encoding/base32"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+)
+
+const (
+    loginChallengeTTL         = 5 * time.Minute
+    maxLoginChallengeAttempts = 5
+    recoveryCodeCount         = 10
+)
+
+var (
+    ErrTwoFactorEnabled      = errors.New("two-factor authentication already enabled")
+    ErrTwoFactorNotEnabled   = errors.New("two-factor authentication not enabled")
+    ErrTwoFactorNotStarted   = errors.New("two-factor enrollment not started")
+    ErrInvalidTwoFactorCode  = errors.New("invalid two-factor code")
+    ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+)
+
+type TOTPEnrollment struct {
+    Secret string
+    URI    string
+}
+
+type TwoFactorStatus struct {
+    Enabled                bool
+    RecoveryCodesRemaining int64
+}
+
+// normalizeRecoveryCode makes codes match however the user typed them.
+func normalizeRecoveryCode(code string) string {
+    code = strings.ToLower(code)
+    code = strings.ReplaceAll(code, "-", "")
+    return strings.ReplaceAll(code, " ", "")
+}
+
+// generateRecoveryCodes returns codes formatted for display ("xxxxx-xxxxx")
+// and the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+    encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+    codes := make([]string, recoveryCodeCount)
+    hashes := make([]string, recoveryCodeCount)
+    for i := range codes {
+        raw := make([]byte, 7)
+        if _, err := rand.Read(raw); err != nil {
+            return nil, nil, err
+        }
+        code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+        codes[i] = code[:5] + "-" + code[5:]
+        hashes[i] = hashToken(code)
+    }
+    return codes, hashes, nil
+}
+
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID uuid.UUID) (*TwoFactorStatus, error) {
+    totp, err := s.queries.GetTOTPByUser(ctx, userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return &TwoFactorStatus{}, nil
+        }
+        return nil, err
+    }
+    if !totp.ConfirmedAt.Valid {
+        return &TwoFactorStatus{}, nil
+    }
+    remaining, err := s.queries.CountUnusedRecoveryCodes(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    return &TwoFactorStatus{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+// StartTOTPEnrollment creates a new secret for the user. It is not enforced
+// until ConfirmTOTPEnrollment proves the authenticator app has it.
+func (s *Service) StartTOTPEnrollment(ctx context.Context, user *database.User, password string) (*TOTPEnrollment, error) {
+    if _, err := s.checkPassword(ctx, s.queries, user.ID, password); err != nil {
+        return nil, err
+    }
+    
+    secret, err := generateTOTPSecret()
+    if err != nil {
+        return nil, err
+    }
+    if _, err := s.queries.StartTOTPEnrollment(ctx, database.StartTOTPEnrollmentParams{
+        UserID: user.ID,
+        Secret: secret,
+    }); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrTwoFactorEnabled
+        }
+        return nil, err
+    }
+    
+    return &TOTPEnrollment{Secret: secret, URI: totpURI(secret, user.Email)}, nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA once the user enters a valid code and
+// returns the recovery codes, which are only ever shown this once.
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    totp, err := qtx.GetTOTPByUserForUpdate(ctx, userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrTwoFactorNotStarted
+        }
+        return nil, err
+    }
+    if totp.ConfirmedAt.Valid {
+        return nil, ErrTwoFactorEnabled
+    }
+    step, ok := validateTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+    if !ok {
+        return nil, ErrInvalidTwoFactorCode
+    }
+    if err := qtx.ConfirmTOTP(ctx, database.ConfirmTOTPParams{UserID: userID, LastUsedStep: step}); err != nil {
+        return nil, err
+    }
+    
+    codes, err := s.replaceRecoveryCodes(ctx, qtx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return codes, nil
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, queries *database.Queries, userID uuid.UUID) ([]string, error) {
+    codes, hashes, err := generateRecoveryCodes()
+    if err != nil {
+        return nil, err
+    }
+    if err := queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+        return nil, err
+    }
+    if err := queries.CreateRecoveryCodes(ctx, database.CreateRecoveryCodesParams{
+        UserID:     userID,
+        CodeHashes: hashes,
+    }); err != nil {
+        return nil, err
+    }
+    return codes, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code. Callers must run it inside a transaction.
+func (s *Service) verifySecondFactor(ctx context.Context, queries *database.Queries, userID uuid.UUID, code string) error {
+    totp, err := queries.GetTOTPByUserForUpdate(ctx, userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrTwoFactorNotEnabled
+        }
+        return err
+    }
+    if !totp.ConfirmedAt.Valid {
+        return ErrTwoFactorNotEnabled
+    }
+    
+    code = strings.TrimSpace(code)
+    if len(code) == totpDigits {
+        step, ok := validateTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+        if !ok {
+            return ErrInvalidTwoFactorCode
+        }
+        return queries.SetTOTPLastUsedStep(ctx, database.SetTOTPLastUsedStepParams{
+            UserID:       userID,
+            LastUsedStep: step,
+        })
+    }
+    
+    used, err := queries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+        UserID:   userID,
+        CodeHash: hashToken(normalizeRecoveryCode(code)),
+    })
+    if err != nil {
+        return err
+    }
+    if used == 0 {
+        return ErrInvalidTwoFactorCode
+    }
+    return nil
+}
+
+// DisableTwoFactor needs both the password and a second factor so a stolen
+// session alone can't turn it off.
+func (s *Service) DisableTwoFactor(ctx context.Context, userID uuid.UUID, password, code string) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if _, err := s.checkPassword(ctx, qtx, userID, password); err != nil {
+        return err
+    }
+    if err := s.verifySecondFactor(ctx, qtx, userID, code); err != nil {
+        return err
+    }
+    if err := qtx.DeleteTOTP(ctx, userID); err != nil {
+        return err
+    }
+    if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := s.verifySecondFactor(ctx, qtx, userID, code); err != nil {
+        return nil, err
+    }
+    codes, err := s.replaceRecoveryCodes(ctx, qtx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return codes, nil
+}
+
+// twoFactorEnabled reports whether Login must stop at a challenge.
+func (s *Service) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+    totp, err := s.queries.GetTOTPByUser(ctx, userID)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return false, nil
+        }
+        return false, err
+    }
+    return totp.ConfirmedAt.Valid, nil
+}
+
+func (s *Service) createLoginChallenge(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+    token, err := s.generateSessionToken()
+    if err != nil {
+        return "", time.Time{}, err
+    }
+    expiresAt := time.Now().Add(loginChallengeTTL)
+    err = s.queries.CreateLoginChallenge(ctx, database.CreateLoginChallengeParams{
+        TokenHash: hashToken(token),
+        UserID:    userID,
+        ExpiresAt: expiresAt,
+    })
+    if err != nil {
+        return "", time.Time{}, err
+    }
+    return token, expiresAt, nil
+}
+
+// CompleteLogin exchanges a pending token from Login and a TOTP or recovery
+// code for a session. A challenge allows a few wrong codes before it is
+// thrown away and the password has to be entered again.
+func (s *Service) CompleteLogin(ctx context.Context, pendingToken, code string, client ClientInfo) (*LoginResult, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    tokenHash := hashToken(pendingToken)
+    challenge, err := qtx.GetLoginChallenge(ctx, tokenHash)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrInvalidLoginChallenge
+        }
+        return nil, err
+    }
+    
+    user, err := qtx.GetUserByID(ctx, challenge.UserID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.checkLoginAllowed(ctx, client.IP, user.Email); err != nil {
+        return nil, err
+    }
+    
+    if err := s.verifySecondFactor(ctx, qtx, challenge.UserID, code); err != nil {
+        if err != ErrInvalidTwoFactorCode {
+            return nil, err
+        }
+        // Wrong codes count towards the same backoff as wrong passwords
+        s.recordLoginFailure(ctx, client.IP, user.Email)
+        // Nothing was written for a wrong code, so committing only records the attempt
+        if challenge.Attempts+1 >= maxLoginChallengeAttempts {
+            err = qtx.DeleteLoginChallenge(ctx, tokenHash)
+        } else {
+            err = qtx.IncrementLoginChallengeAttempts(ctx, tokenHash)
+        }
+        if err != nil {
+            return nil, err
+        }
+        if err := tx.Commit(); err != nil {
+            return nil, err
+        }
+        return nil, ErrInvalidTwoFactorCode
+    }
+    
+    if err := qtx.DeleteLoginChallenge(ctx, tokenHash); err != nil {
+        return nil, err
+    }
+    token, cookieExpiresAt, err := s.createSession(ctx, qtx, user.ID, client)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    s.recordLoginSuccess(ctx, user.Email)
+    return &LoginResult{User: &user, SessionToken: token, SessionExpiresAt: cookieExpiresAt}, nil
+}
+
+func (s *Service) CleanupExpiredLoginChallenges(ctx context.Context) error {
+    return s.queries.CleanupExpiredLoginChallenges(ctx)
+}
+
+type TwoFactorStatusResponse struct {
+    Enabled                bool  `json:"enabled"`
+    RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type StartTwoFactorRequest struct {
+    // Empty for accounts without a password
+    Password string `json:"password"`
+}
+
+type StartTwoFactorResponse struct {
+    Secret     string `json:"secret"`
+    OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+    Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+    Password string `json:"password"`
+    Code     string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+    RecoveryCodes []string `json:"recovery_codes"`
+    Message       string   `json:"message"`
+}
+
+type CompleteLoginRequest struct {
+    PendingToken string `json:"pending_token" validate:"required"`
+    // TOTP code or one of the recovery codes
+    Code string `json:"code" validate:"required"`
+}
+
+func respondWithTwoFactorError(w http.ResponseWriter, err error, message string) {
+    switch err {
+    case ErrWrongPassword:
+        utils.RespondWithError(w, http.StatusUnauthorized, "Current password is incorrect")
+    case ErrInvalidTwoFactorCode:
+        utils.RespondWithError(w, http.StatusUnauthorized, "Invalid two-factor code")
+    case ErrTwoFactorEnabled:
+        utils.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+    case ErrTwoFactorNotEnabled:
+        utils.RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+    case ErrTwoFactorNotStarted:
+        utils.RespondWithError(w, http.StatusBadRequest, "Start two-factor setup first")
+    case ErrInvalidLoginChallenge:
+        utils.RespondWithError(w, http.StatusUnauthorized, "Login expired, sign in again")
+    default:
+        utils.RespondWithError(w, http.StatusInternalServerError, message)
+    }
+}
+
+func (s *Service) HandleGetTwoFactor(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    status, err := s.GetTwoFactorStatus(r.Context(), user.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get two-factor status")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, TwoFactorStatusResponse{
+        Enabled:                status.Enabled,
+        RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+    })
+}
+
+func (s *Service) HandleStartTwoFactor(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req StartTwoFactorRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    enrollment, err := s.StartTOTPEnrollment(r.Context(), user, req.Password)
+    if err != nil {
+        respondWithTwoFactorError(w, err, "Failed to start two-factor setup")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, StartTwoFactorResponse{
+        Secret:     enrollment.Secret,
+        OTPAuthURI: enrollment.URI,
+    })
+}
+
+func (s *Service) HandleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req TwoFactorCodeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    codes, err := s.ConfirmTOTPEnrollment(r.Context(), user.ID, req.Code)
+    if err != nil {
+        respondWithTwoFactorError(w, err, "Failed to enable two-factor authentication")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, RecoveryCodesResponse{
+        RecoveryCodes: codes,
+        Message:       "Two-factor authentication enabled. Store these recovery codes somewhere safe",
+    })
+}
+
+func (s *Service) HandleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req DisableTwoFactorRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    if err := s.DisableTwoFactor(r.Context(), user.ID, req.Password, req.Code); err != nil {
+        respondWithTwoFactorError(w, err, "Failed to disable two-factor authentication")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Two-factor authentication disabled",
+    })
+}
+
+func (s *Service) HandleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    var req TwoFactorCodeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    codes, err := s.RegenerateRecoveryCodes(r.Context(), user.ID, req.Code)
+    if err != nil {
+        respondWithTwoFactorError(w, err, "Failed to regenerate recovery codes")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, RecoveryCodesResponse{
+        RecoveryCodes: codes,
+        Message:       "New recovery codes generated, the old ones no longer work",
+    })
+}
+
+func (s *Service) HandleCompleteLogin(w http.ResponseWriter, r *http.Request) {
+    var req CompleteLoginRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    result, err := s.CompleteLogin(r.Context(), req.PendingToken, req.Code, s.clientInfo(r))
+    if err != nil {
+        var limited *TooManyAttemptsError
+        if errors.As(err, &limited) {
+            respondTooManyAttempts(w, limited)
+            return
+        }
+        respondWithTwoFactorError(w, err, "Login failed")
+        return
+    }
+    
+    setSessionCookie(w, result.SessionToken, result.SessionExpiresAt)
+    
+    utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
+        User:    userResponse(result.User),
+        Message: "Login successful",
+    })
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+)
+
+const verificationTTL = 72 * time.Hour
+
+var (
+    ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+    ErrAlreadyVerified          = errors.New("email already verified")
+)
+
+type VerifyEmailRequest struct {
+    Token string `json:"token" validate:"required"`
+}
+
+// verificationClaims is the payload of a verification token. Tokens are
+// stateless: the HMAC proves we issued them and the email ties them to the
+// address they were sent to.
+type verificationClaims struct {
+    UserID    uuid.UUID `json:"uid"`
+    Email     string    `json:"email"`
+    ExpiresAt int64     `json:"exp"`
+}
+
+// signClaims encodes claims as payload.signature. The purpose is mixed into
+// the MAC so a token issued for one use is never accepted for another.
+func (s *Service) signClaims(purpose string, claims interface{}) (string, error) {
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        return "", err
+    }
+    mac := hmac.New(sha256.New, s.secret)
+    mac.Write([]byte(purpose + "."))
+    mac.Write(payload)
+    return base64.RawURLEncoding.EncodeToString(payload) + "." +
+        base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseClaims checks a token from signClaims and decodes it into claims.
+// Expiry is left to the caller.
+func (s *Service) parseClaims(purpose, token string, claims interface{}) bool {
+    encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+    if !ok {
+        return false
+    }
+    payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+    if err != nil {
+        return false
+    }
+    sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+    if err != nil {
+        return false
+    }
+    
+    mac := hmac.New(sha256.New, s.secret)
+    mac.Write([]byte(purpose + "."))
+    mac.Write(payload)
+    if !hmac.Equal(sig, mac.Sum(nil)) {
+        return false
+    }
+    return json.Unmarshal(payload, claims) == nil
+}
+
+func (s *Service) signVerificationToken(claims verificationClaims) (string, error) {
+    return s.signClaims("email-verification", claims)
+}
+
+func (s *Service) parseVerificationToken(token string) (*verificationClaims, error) {
+    var claims verificationClaims
+    if !s.parseClaims("email-verification", token, &claims) {
+        return nil, ErrInvalidVerificationToken
+    }
+    if time.Now().Unix() > claims.ExpiresAt {
+        return nil, ErrInvalidVerificationToken
+    }
+    return &claims, nil
+}
+
+func (s *Service) verificationEmail(user *database.User) (mailer.Message, error) {
+    token, err := s.signVerificationToken(verificationClaims{
+        UserID:    user.ID,
+        Email:     user.Email,
+        ExpiresAt: time.Now().Add(verificationTTL).Unix(),
+    })
+    if err != nil {
+        return mailer.Message{}, err
+    }
+    
+    return mailer.Message{
+        To:      user.Email,
+        Subject: "Verify your email address",
+        Body: fmt.Sprintf("Welcome! Open the link below to verify your email address:\n\n%s\n\n"+
+            "The link expires in %d hours.\n",
+            s.appLink("/verify-email", url.Values{"token": {token}}), int(verificationTTL.Hours())),
+    }, nil
+}
+
+// VerifyEmail marks the address in token as verified. Verifying twice is
+// not an error.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*database.User, error) {
+    claims, err := s.parseVerificationToken(token)
+    if err != nil {
+        return nil, err
+    }
+    
+    user, err := s.queries.MarkUserEmailVerified(ctx, database.MarkUserEmailVerifiedParams{
+        ID:    claims.UserID,
+        Email: claims.Email,
+    })
+    if err != nil {
+        // The account is gone or its email changed since the token was sent
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrInvalidVerificationToken
+        }
+        return nil, err
+    }
+    return &user, nil
+}
+
+// ResendVerificationEmail mails a fresh verification link. Resends are
+// spaced out per account so a session can't make the server flood an inbox;
+// a resend during the cooldown returns TooManyAttemptsError.
+func (s *Service) ResendVerificationEmail(ctx context.Context, user *database.User) error {
+    if user.EmailVerifiedAt.Valid {
+        return ErrAlreadyVerified
+    }
+    if s.resendLimiter != nil {
+        key := user.ID.String()
+        wait, err := s.resendLimiter.Check(ctx, key)
+        if err != nil {
+            return err
+        }
+        if wait > 0 {
+            return &TooManyAttemptsError{RetryAfter: wait}
+        }
+        // Counted before sending so a failing mailer can't be retried in a loop
+        if _, err := s.resendLimiter.Fail(ctx, key); err != nil {
+            return err
+        }
+    }
+    msg, err := s.verificationEmail(user)
+    if err != nil {
+        return err
+    }
+    return s.mailer.Send(ctx, msg)
+}
+
+// RequireVerifiedEmail guards routes that unverified accounts may not use
+// when verification is enforced. It must run after AuthMiddleware.
+func (s *Service) RequireVerifiedEmail(next http.Handler) http.Handler {
+    return &verifiedHandler{service: s, next: next}
+}
+
+type verifiedHandler struct {
+    service *Service
+    next    http.Handler
+}
+
+func (h *verifiedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if h.service.requireVerified {
+        user, ok := GetUserFromContext(r.Context())
+        if !ok {
+            utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+            return
+        }
+        if !user.EmailVerifiedAt.Valid {
+            utils.RespondWithError(w, http.StatusForbidden, "Verify your email address to use this feature")
+            return
+        }
+    }
+    h.next.ServeHTTP(w, r)
+}
+
+func (s *Service) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+    var req VerifyEmailRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    user, err := s.VerifyEmail(r.Context(), req.Token)
+    if err != nil {
+        if err == ErrInvalidVerificationToken {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid or expired verification token")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, AuthResponse{
+        User:    userResponse(user),
+        Message: "Email verified successfully",
+    })
+}
+
+func (s *Service) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+    user, ok := GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found in context")
+        return
+    }
+    
+    if err := s.ResendVerificationEmail(r.Context(), user); err != nil {
+        if err == ErrAlreadyVerified {
+            utils.RespondWithError(w, http.StatusBadRequest, "Email already verified")
+            return
+        }
+        var limited *TooManyAttemptsError
+        if errors.As(err, &limited) {
+            respondRetryAfter(w, limited.RetryAfter, "A verification email was sent recently, try again later")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Verification email sent",
+    })
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/ratelimit"
+    "github.com/google/uuid"
+)
+
+func TestResendVerificationCooldown(t *testing.T) {
+    mail := &recordingMailer{}
+    s := &Service{
+        mailer:        mail,
+        secret:        []byte("test secret"),
+        resendLimiter: ratelimit.NewMemoryLimiter(ResendVerificationPolicy),
+    }
+    ada := &database.User{ID: uuid.New(), Email: "ada@example.com"}
+    bob := &database.User{ID: uuid.New(), Email: "bob@example.com"}
+
+    tests := []struct {
+        name       string
+        user       *database.User
+        wantStatus int
+        wantRetry  string
+    }{
+        {"first resend", ada, http.StatusOK, ""},
+        {"resend during cooldown", ada, http.StatusTooManyRequests, "60"},
+        {"other account is not throttled", bob, http.StatusOK, ""},
+    }
+
+    for _, tt := range tests {
+        r := httptest.NewRequest("POST", "/api/auth/verify-email/resend", nil)
+        r = r.WithContext(context.WithValue(r.Context(), UserContextKey, tt.user))
+        w := httptest.NewRecorder()
+        s.HandleResendVerification(w, r)
+
+        if w.Code != tt.wantStatus {
+            t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+        }
+        if got := w.Header().Get("Retry-After"); got != tt.wantRetry {
+            t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.wantRetry)
+        }
+    }
+    if sent := mail.Sent(); len(sent) != 2 {
+        t.Errorf("sent %d mails, want 2", len(sent))
+    }
+}
@@ -0,0 +1,202 @@
+package budgets
+
+import (
+    "database/sql"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+type SetBudgetRequest struct {
+    CategoryID string       `json:"category_id" validate:"required"`
+    Month      string       `json:"month" validate:"required"` // YYYY-MM
+    Amount     money.Amount `json:"amount" validate:"required"` // "12.34" or integer cents
+    Rollover   bool         `json:"rollover"`
+}
+
+type BudgetResponse struct {
+    ID         string       `json:"id"`
+    CategoryID string       `json:"category_id"`
+    Month      string       `json:"month"`
+    Amount     money.Amount `json:"amount"`
+    Rollover   bool         `json:"rollover"`
+    CreatedAt  string       `json:"created_at"`
+    UpdatedAt  string       `json:"updated_at"`
+}
+
+type BudgetProgressResponse struct {
+    CategoryID    string       `json:"category_id"`
+    CategoryName  string       `json:"category_name"`
+    CategoryColor string       `json:"category_color"`
+    BudgetID      *string      `json:"budget_id"`
+    Rollover      bool         `json:"rollover"`
+    Budgeted      money.Amount `json:"budgeted"`
+    RolledOver    money.Amount `json:"rolled_over"`
+    Available     money.Amount `json:"available"`
+    Spent         money.Amount `json:"spent"`
+    Remaining     money.Amount `json:"remaining"`
+    PercentUsed   float64      `json:"percent_used"`
+    Status        string       `json:"status"` // under, over or no_budget
+    ExpenseCount  int64        `json:"expense_count"`
+    Unconverted   []string     `json:"unconverted_currencies"`
+}
+
+type BudgetSummaryResponse struct {
+    Month      string                   `json:"month"`
+    Currency   string                   `json:"currency"`
+    Categories []BudgetProgressResponse `json:"categories"`
+}
+
+func (s *Service) HandleSetBudget(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    var req SetBudgetRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if !req.Amount.IsPositive() {
+        utils.RespondWithError(w, http.StatusBadRequest, "Amount must be greater than 0")
+        return
+    }
+    if !req.Amount.Storable() {
+        utils.RespondWithError(w, http.StatusBadRequest, "Amount must be at most "+money.MaxStored.String())
+        return
+    }
+
+    categoryID, err := uuid.Parse(req.CategoryID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
+        return
+    }
+
+    month, err := time.Parse("2006-01", req.Month)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid month format, use YYYY-MM")
+        return
+    }
+
+    budget, err := s.SetBudget(r.Context(), ledger.ID, user.ID, categoryID, month, req.Amount, req.Rollover)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save budget")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, BudgetResponse{
+        ID:         budget.ID.String(),
+        CategoryID: budget.CategoryID.String(),
+        Month:      budget.Month.Format("2006-01"),
+        Amount:     budget.Amount,
+        Rollover:   budget.Rollover,
+        CreatedAt:  budget.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        UpdatedAt:  budget.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+    })
+}
+
+func (s *Service) HandleGetBudgets(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    now := time.Now()
+    month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+    if m := r.URL.Query().Get("month"); m != "" {
+        parsed, err := time.Parse("2006-01", m)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid month format, use YYYY-MM")
+            return
+        }
+        month = parsed
+    }
+
+    progress, err := s.GetProgress(r.Context(), ledger.ID, user.BaseCurrency, month)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get budgets")
+        return
+    }
+
+    response := make([]BudgetProgressResponse, len(progress))
+    for i, p := range progress {
+        response[i] = BudgetProgressResponse{
+            CategoryID:    p.CategoryID.String(),
+            CategoryName:  p.CategoryName,
+            CategoryColor: p.CategoryColor,
+            Budgeted:      p.Budgeted,
+            RolledOver:    p.RolledOver,
+            Available:     p.Available,
+            Spent:         p.Spent,
+            Remaining:     p.Remaining,
+            PercentUsed:   p.PercentUsed,
+            Status:        p.Status,
+            ExpenseCount:  p.ExpenseCount,
+            Unconverted:   p.Unconverted,
+        }
+        if p.Budget != nil {
+            budgetID := p.Budget.ID.String()
+            response[i].BudgetID = &budgetID
+            response[i].Rollover = p.Budget.Rollover
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, BudgetSummaryResponse{
+        Month:      month.Format("2006-01"),
+        Currency:   user.BaseCurrency,
+        Categories: response,
+    })
+}
+
+func (s *Service) HandleDeleteBudget(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    vars := mux.Vars(r)
+    budgetID, err := uuid.Parse(vars["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid budget ID")
+        return
+    }
+
+    if err := s.DeleteBudget(r.Context(), budgetID, ledger.ID); err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete budget")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Budget deleted successfully",
+    })
+}
@@ -0,0 +1,235 @@
+package budgets
+
+import (
+    "context"
+    "sort"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/expenses"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/google/uuid"
+)
+
+const (
+    StatusUnder    = "under"
+    StatusOver     = "over"
+    StatusNoBudget = "no_budget"
+)
+
+// rolloverLookback bounds how many earlier months can feed unspent money
+// into the current one.
+const rolloverLookback = 12
+
+// Progress joins one category's budget for a month with its spending.
+// Amounts are in the user's base currency.
+type Progress struct {
+    CategoryID    uuid.UUID
+    CategoryName  string
+    CategoryColor string
+    Budget        *database.Budget
+    Budgeted      money.Amount
+    RolledOver    money.Amount
+    Available     money.Amount
+    Spent         money.Amount
+    Remaining     money.Amount
+    PercentUsed   float64
+    Status        string
+    ExpenseCount  int64
+    Unconverted   []string
+}
+
+type Service struct {
+    queries  *database.Queries
+    expenses *expenses.Service
+}
+
+func NewService(queries *database.Queries, expenses *expenses.Service) *Service {
+    return &Service{queries: queries, expenses: expenses}
+}
+
+// SetBudget creates or replaces the budget for a category and month.
+// It returns sql.ErrNoRows if the category is not in the ledger.
+func (s *Service) SetBudget(ctx context.Context, ledgerID, userID, categoryID uuid.UUID, month time.Time, amount money.Amount, rollover bool) (*database.Budget, error) {
+    budget, err := s.queries.UpsertBudget(ctx, database.UpsertBudgetParams{
+        Month:      month,
+        Amount:     amount,
+        Rollover:   rollover,
+        CategoryID: categoryID,
+        LedgerID:   ledgerID,
+        UserID:     userID,
+    })
+    return &budget, err
+}
+
+func (s *Service) DeleteBudget(ctx context.Context, budgetID, ledgerID uuid.UUID) error {
+    return s.queries.DeleteBudget(ctx, database.DeleteBudgetParams{
+        ID:       budgetID,
+        LedgerID: ledgerID,
+    })
+}
+
+// GetProgress reports every category that has a budget or spending in
+// month, most used first.
+func (s *Service) GetProgress(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, month time.Time) ([]Progress, error) {
+    first := month.AddDate(0, -rolloverLookback, 0)
+    rows, err := s.queries.GetBudgetsByLedgerAndMonthRange(ctx, database.GetBudgetsByLedgerAndMonthRangeParams{
+        LedgerID: ledgerID,
+        Month:    first,
+        Month_2:  month,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    l := &rolloverCache{
+        service:      s,
+        ledgerID:     ledgerID,
+        baseCurrency: baseCurrency,
+        first:        first,
+        budgets:      make(map[budgetKey]*database.Budget, len(rows)),
+        spent:        make(map[string]map[uuid.UUID]money.Amount),
+    }
+    for i := range rows {
+        l.budgets[budgetKey{rows[i].CategoryID, monthKey(rows[i].Month)}] = &rows[i]
+    }
+
+    categories, err := s.expenses.GetExpensesByCategory(ctx, ledgerID, baseCurrency, month, endOfMonth(month), true)
+    if err != nil {
+        return nil, err
+    }
+
+    progress := []Progress{}
+    for _, cat := range categories {
+        budget := l.budgets[budgetKey{cat.CategoryID, monthKey(month)}]
+        if budget == nil && cat.ExpenseCount == 0 {
+            continue
+        }
+
+        p := Progress{
+            CategoryID:    cat.CategoryID,
+            CategoryName:  cat.CategoryName,
+            CategoryColor: cat.CategoryColor,
+            Budget:        budget,
+            Spent:         cat.Total,
+            ExpenseCount:  cat.ExpenseCount,
+            Unconverted:   cat.Unconverted,
+            Status:        StatusNoBudget,
+        }
+
+        if budget != nil {
+            carried, err := l.carryInto(ctx, cat.CategoryID, month)
+            if err != nil {
+                return nil, err
+            }
+            p.Budgeted = budget.Amount
+            p.RolledOver = carried
+            if p.Available, err = budget.Amount.Add(carried); err != nil {
+                return nil, err
+            }
+            if p.Remaining, err = p.Available.Sub(p.Spent); err != nil {
+                return nil, err
+            }
+            p.PercentUsed = percent(p.Spent, p.Available)
+            p.Status = StatusUnder
+            if p.Spent > p.Available {
+                p.Status = StatusOver
+            }
+        }
+
+        progress = append(progress, p)
+    }
+
+    sort.SliceStable(progress, func(i, j int) bool {
+        return progress[i].PercentUsed > progress[j].PercentUsed
+    })
+    return progress, nil
+}
+
+type budgetKey struct {
+    categoryID uuid.UUID
+    month      string
+}
+
+// rolloverCache memoizes budgets and monthly spend while walking rollover chains.
+type rolloverCache struct {
+    service      *Service
+    ledgerID     uuid.UUID
+    baseCurrency string
+    first        time.Time
+    budgets      map[budgetKey]*database.Budget
+    spent        map[string]map[uuid.UUID]money.Amount
+}
+
+// carryInto returns the unspent amount rolled into month for a category.
+// Money only rolls over between consecutive budgeted months, and only when
+// the receiving month's budget has rollover enabled.
+func (l *rolloverCache) carryInto(ctx context.Context, categoryID uuid.UUID, month time.Time) (money.Amount, error) {
+    budget := l.budgets[budgetKey{categoryID, monthKey(month)}]
+    if budget == nil || !budget.Rollover {
+        return 0, nil
+    }
+
+    prev := month.AddDate(0, -1, 0)
+    prevBudget := l.budgets[budgetKey{categoryID, monthKey(prev)}]
+    if prevBudget == nil || prev.Before(l.first) {
+        return 0, nil
+    }
+
+    prevCarry, err := l.carryInto(ctx, categoryID, prev)
+    if err != nil {
+        return 0, err
+    }
+    prevAvailable, err := prevBudget.Amount.Add(prevCarry)
+    if err != nil {
+        return 0, err
+    }
+
+    spent, err := l.spentIn(ctx, prev)
+    if err != nil {
+        return 0, err
+    }
+    unspent, err := prevAvailable.Sub(spent[categoryID])
+    if err != nil {
+        return 0, err
+    }
+    if unspent.IsNegative() {
+        return 0, nil
+    }
+    return unspent, nil
+}
+
+func (l *rolloverCache) spentIn(ctx context.Context, month time.Time) (map[uuid.UUID]money.Amount, error) {
+    key := monthKey(month)
+    if spent, ok := l.spent[key]; ok {
+        return spent, nil
+    }
+
+    categories, err := l.service.expenses.GetExpensesByCategory(ctx, l.ledgerID, l.baseCurrency, month, endOfMonth(month), false)
+    if err != nil {
+        return nil, err
+    }
+    spent := make(map[uuid.UUID]money.Amount, len(categories))
+    for _, cat := range categories {
+        spent[cat.CategoryID] = cat.Total
+    }
+    l.spent[key] = spent
+    return spent, nil
+}
+
+// percent returns spent as a percentage of available, to two decimals.
+func percent(spent, available money.Amount) float64 {
+    if available <= 0 {
+        return 0
+    }
+    basisPoints := (spent.MinorUnits()*10000 + available.MinorUnits()/2) / available.MinorUnits()
+    return float64(basisPoints) / 100
+}
+
+func monthKey(t time.Time) string {
+    return t.Format("2006-01")
+}
+
+func endOfMonth(month time.Time) time.Time {
+    return month.AddDate(0, 1, -1)
+}
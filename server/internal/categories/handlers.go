@@ -2,30 +2,126 @@
 package categories
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/LuisBAndrade/etracker/internal/auth"
+	"github.com/LuisBAndrade/etracker/internal/database"
 	"github.com/LuisBAndrade/etracker/internal/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type CreateCategoryRequest struct {
-    Name  string `json:"name" validate:"required"`
-    Color string `json:"color"`
+    Name     string  `json:"name" validate:"required"`
+    Color    string  `json:"color"`
+    ParentID *string `json:"parent_id"`
 }
 
 type UpdateCategoryRequest struct {
-    Name  string `json:"name" validate:"required"`
-    Color string `json:"color"`
+    Name     string  `json:"name" validate:"required"`
+    Color    string  `json:"color"`
+    ParentID *string `json:"parent_id"` // omit to keep the current parent, "" for top-level
 }
 
 type CategoryResponse struct {
-    ID        string `json:"id"`
-    Name      string `json:"name"`
-    Color     string `json:"color"`
-    CreatedAt string `json:"created_at"`
+    ID        string  `json:"id"`
+    ParentID  *string `json:"parent_id"`
+    Name      string  `json:"name"`
+    Color     string  `json:"color"`
+    CreatedAt string  `json:"created_at"`
+}
+
+// MergeCategoryRequest folds the category in the URL into TargetID.
+type MergeCategoryRequest struct {
+    TargetID string `json:"target_id" validate:"required"`
+}
+
+type MergeCategoryResponse struct {
+    Target                 CategoryResponse `json:"target"`
+    ExpensesMoved          int64 `json:"expenses_moved"`
+    BudgetsMoved           int64 `json:"budgets_moved"`
+    RecurringExpensesMoved int64 `json:"recurring_expenses_moved"`
+}
+
+// CategoryTreeResponse is a category with its subcategories nested inside.
+type CategoryTreeResponse struct {
+    CategoryResponse
+    Children []CategoryTreeResponse `json:"children"`
+}
+
+func categoryResponse(category database.Category) CategoryResponse {
+    response := CategoryResponse{
+        ID:        category.ID.String(),
+        Name:      category.Name,
+        Color:     category.Color,
+        CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+    if category.ParentID.Valid {
+        parentID := category.ParentID.UUID.String()
+        response.ParentID = &parentID
+    }
+    return response
+}
+
+// buildTree nests categories under their parents, keeping the input order
+// among siblings.
+func buildTree(categories []database.Category) []CategoryTreeResponse {
+    known := make(map[uuid.UUID]bool, len(categories))
+    for _, category := range categories {
+        known[category.ID] = true
+    }
+    children := make(map[uuid.UUID][]database.Category)
+    var roots []database.Category
+    for _, category := range categories {
+        if category.ParentID.Valid && known[category.ParentID.UUID] {
+            children[category.ParentID.UUID] = append(children[category.ParentID.UUID], category)
+        } else {
+            roots = append(roots, category)
+        }
+    }
+    
+    var build func([]database.Category) []CategoryTreeResponse
+    build = func(nodes []database.Category) []CategoryTreeResponse {
+        tree := make([]CategoryTreeResponse, len(nodes))
+        for i, node := range nodes {
+            tree[i] = CategoryTreeResponse{
+                CategoryResponse: categoryResponse(node),
+                Children:         build(children[node.ID]),
+            }
+        }
+        return tree
+    }
+    return build(roots)
+}
+
+// parseParentID reads an optional parent_id, treating "" as top-level.
+func parseParentID(value *string) (*uuid.UUID, error) {
+    if value == nil || *value == "" {
+        return nil, nil
+    }
+    parentID, err := uuid.Parse(*value)
+    if err != nil {
+        return nil, err
+    }
+    return &parentID, nil
+}
+
+func respondWithCategoryError(w http.ResponseWriter, err error, message string) {
+    switch {
+    case errors.Is(err, ErrCategoryNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+    case errors.Is(err, ErrParentNotFound):
+        utils.RespondWithError(w, http.StatusBadRequest, "Parent category not found")
+    case errors.Is(err, ErrTargetNotFound):
+        utils.RespondWithError(w, http.StatusBadRequest, "Target category not found")
+    case errors.Is(err, ErrCategoryCycle), errors.Is(err, ErrMergeIntoSelf):
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+    default:
+        utils.RespondWithError(w, http.StatusInternalServerError, message)
+    }
 }
 
 func (s *Service) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
@@ -34,6 +130,11 @@ func (s *Service) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
         utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
         return
     }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
     
     var req CreateCategoryRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -46,50 +147,51 @@ func (s *Service) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
         return
     }
     
-    category, err := s.CreateCategory(r.Context(), user.ID, req.Name, req.Color)
+    parentID, err := parseParentID(req.ParentID)
     if err != nil {
-        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create category")
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid parent ID")
         return
     }
     
-    utils.RespondWithJSON(w, http.StatusCreated, CategoryResponse{
-        ID:        category.ID.String(),
-        Name:      category.Name,
-        Color:     category.Color,
-        CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z"),
-    })
+    category, err := s.CreateCategory(r.Context(), ledger.ID, user.ID, req.Name, req.Color, parentID)
+    if err != nil {
+        respondWithCategoryError(w, err, "Failed to create category")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusCreated, categoryResponse(*category))
 }
 
 func (s *Service) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
-    user, ok := auth.GetUserFromContext(r.Context())
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
     if !ok {
-        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
         return
     }
     
-    categories, err := s.GetUserCategories(r.Context(), user.ID)
+    categories, err := s.GetLedgerCategories(r.Context(), ledger.ID)
     if err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get categories")
         return
     }
     
+    if r.URL.Query().Get("tree") == "true" {
+        utils.RespondWithJSON(w, http.StatusOK, buildTree(categories))
+        return
+    }
+    
     response := make([]CategoryResponse, len(categories))
     for i, cat := range categories {
-        response[i] = CategoryResponse{
-            ID:        cat.ID.String(),
-            Name:      cat.Name,
-            Color:     cat.Color,
-            CreatedAt: cat.CreatedAt.Format("2006-01-02T15:04:05Z"),
-        }
+        response[i] = categoryResponse(cat)
     }
     
     utils.RespondWithJSON(w, http.StatusOK, response)
 }
 
 func (s *Service) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
-    user, ok := auth.GetUserFromContext(r.Context())
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
     if !ok {
-        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
         return
     }
     
@@ -111,24 +213,38 @@ func (s *Service) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
         return
     }
     
-    category, err := s.UpdateCategory(r.Context(), categoryID, user.ID, req.Name, req.Color)
+    var parentID *uuid.UUID
+    if req.ParentID == nil {
+        current, err := s.GetCategoryByID(r.Context(), categoryID, ledger.ID)
+        if err != nil {
+            if errors.Is(err, sql.ErrNoRows) {
+                utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+                return
+            }
+            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update category")
+            return
+        }
+        if current.ParentID.Valid {
+            parentID = &current.ParentID.UUID
+        }
+    } else if parentID, err = parseParentID(req.ParentID); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid parent ID")
+        return
+    }
+    
+    category, err := s.UpdateCategory(r.Context(), categoryID, ledger.ID, req.Name, req.Color, parentID)
     if err != nil {
-        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update category")
+        respondWithCategoryError(w, err, "Failed to update category")
         return
     }
     
-    utils.RespondWithJSON(w, http.StatusOK, CategoryResponse{
-        ID:        category.ID.String(),
-        Name:      category.Name,
-        Color:     category.Color,
-        CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z"),
-    })
+    utils.RespondWithJSON(w, http.StatusOK, categoryResponse(*category))
 }
 
 func (s *Service) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
-    user, ok := auth.GetUserFromContext(r.Context())
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
     if !ok {
-        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
         return
     }
     
@@ -139,12 +255,144 @@ func (s *Service) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
         return
     }
     
-    if err := s.DeleteCategory(r.Context(), categoryID, user.ID); err != nil {
-        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete category")
+    // ?reassign_to= keeps the category's expenses by merging it instead
+    if v := r.URL.Query().Get("reassign_to"); v != "" {
+        targetID, err := uuid.Parse(v)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid reassign_to category ID")
+            return
+        }
+        result, err := s.MergeCategories(r.Context(), ledger.ID, categoryID, targetID)
+        if err != nil {
+            respondWithCategoryError(w, err, "Failed to delete category")
+            return
+        }
+        utils.RespondWithJSON(w, http.StatusOK, mergeResponse(result))
+        return
+    }
+    
+    if err := s.DeleteCategory(r.Context(), categoryID, ledger.ID); err != nil {
+        respondWithCategoryError(w, err, "Failed to delete category")
         return
     }
     
     utils.RespondWithJSON(w, http.StatusOK, map[string]string{
         "message": "Category deleted successfully",
     })
-}
\ No newline at end of file
+}
+
+func (s *Service) HandleMergeCategory(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    vars := mux.Vars(r)
+    categoryID, err := uuid.Parse(vars["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
+        return
+    }
+    
+    var req MergeCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    targetID, err := uuid.Parse(req.TargetID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid target category ID")
+        return
+    }
+    
+    result, err := s.MergeCategories(r.Context(), ledger.ID, categoryID, targetID)
+    if err != nil {
+        respondWithCategoryError(w, err, "Failed to merge categories")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, mergeResponse(result))
+}
+
+func mergeResponse(result *MergeResult) MergeCategoryResponse {
+    return MergeCategoryResponse{
+        Target:                 categoryResponse(result.Target),
+        ExpensesMoved:          result.ExpensesMoved,
+        BudgetsMoved:           result.BudgetsMoved,
+        RecurringExpensesMoved: result.RecurringMoved,
+    }
+}
+
+type TemplateSetResponse struct {
+    Name        string             `json:"name"`
+    Description string             `json:"description"`
+    Default     bool               `json:"default"`
+    Categories  []TemplateCategory `json:"categories"`
+}
+
+type ApplyTemplateResponse struct {
+    Template   string             `json:"template"`
+    Categories []CategoryResponse `json:"categories"`
+}
+
+// HandleGetTemplates is public so signup forms can offer the choice.
+func (s *Service) HandleGetTemplates(w http.ResponseWriter, r *http.Request) {
+    sets := s.GetTemplates()
+    response := make([]TemplateSetResponse, len(sets))
+    for i, set := range sets {
+        response[i] = TemplateSetResponse{
+            Name:        set.Name,
+            Description: set.Description,
+            Default:     set.Name == s.templates.Default,
+            Categories:  set.Categories,
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleApplyTemplate(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    name := mux.Vars(r)["name"]
+    if name == NoTemplate {
+        utils.RespondWithError(w, http.StatusNotFound, "Category template not found")
+        return
+    }
+    
+    created, err := s.ApplyTemplate(r.Context(), ledger.ID, user.ID, name)
+    if err != nil {
+        if errors.Is(err, ErrUnknownTemplate) {
+            utils.RespondWithError(w, http.StatusNotFound, "Category template not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to apply category template")
+        return
+    }
+    
+    response := ApplyTemplateResponse{
+        Template:   name,
+        Categories: make([]CategoryResponse, len(created)),
+    }
+    for i, category := range created {
+        response.Categories[i] = categoryResponse(category)
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -2,81 +2,285 @@ package categories
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	"github.com/LuisBAndrade/etracker/internal/database"
 	"github.com/google/uuid"
 )
 
+var (
+    ErrCategoryNotFound = errors.New("category not found")
+    ErrParentNotFound   = errors.New("parent category not found")
+    ErrCategoryCycle    = errors.New("a category cannot be moved under itself or one of its subcategories")
+    ErrTargetNotFound   = errors.New("target category not found")
+    ErrMergeIntoSelf    = errors.New("a category cannot be merged into itself")
+)
+
+// MergeResult counts what a merge moved onto the target category.
+type MergeResult struct {
+    Target         database.Category
+    ExpensesMoved  int64
+    BudgetsMoved   int64
+    RecurringMoved int64
+}
+
 type Service struct {
-    queries *database.Queries
+    db        *sql.DB
+    queries   *database.Queries
+    templates *Templates
 }
 
-func NewService(queries *database.Queries) *Service {
-    return &Service{queries: queries}
+func NewService(db *sql.DB, queries *database.Queries, templates *Templates) *Service {
+    return &Service{db: db, queries: queries, templates: templates}
 }
 
-func (s *Service) CreateCategory(ctx context.Context, userID uuid.UUID, name, color string) (*database.Category, error) {
+func (s *Service) CreateCategory(ctx context.Context, ledgerID, userID uuid.UUID, name, color string, parentID *uuid.UUID) (*database.Category, error) {
     if color == "" {
         color = "#6B7280" // Default gray color
     }
     
+    if parentID != nil {
+        if _, err := s.queries.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: *parentID, LedgerID: ledgerID}); err != nil {
+            if errors.Is(err, sql.ErrNoRows) {
+                return nil, ErrParentNotFound
+            }
+            return nil, err
+        }
+    }
+    
     category, err := s.queries.CreateCategory(ctx, database.CreateCategoryParams{
-        UserID: userID,
-        Name:   name,
-        Color:  color,
+        LedgerID: ledgerID,
+        UserID:   userID,
+        Name:     name,
+        Color:    color,
+        ParentID: nullUUID(parentID),
     })
     return &category, err
 }
 
-func (s *Service) GetUserCategories(ctx context.Context, userID uuid.UUID) ([]database.Category, error) {
-    return s.queries.GetCategoriesByUser(ctx, userID)
+func (s *Service) GetLedgerCategories(ctx context.Context, ledgerID uuid.UUID) ([]database.Category, error) {
+    return s.queries.GetCategoriesByLedger(ctx, ledgerID)
 }
 
-func (s *Service) GetCategoryByID(ctx context.Context, categoryID, userID uuid.UUID) (*database.Category, error) {
+func (s *Service) GetCategoryByID(ctx context.Context, categoryID, ledgerID uuid.UUID) (*database.Category, error) {
     category, err := s.queries.GetCategoryByID(ctx, database.GetCategoryByIDParams{
-        ID:     categoryID,
-        UserID: userID,
+        ID:       categoryID,
+        LedgerID: ledgerID,
     })
     return &category, err
 }
 
-func (s *Service) UpdateCategory(ctx context.Context, categoryID, userID uuid.UUID, name, color string) (*database.Category, error) {
-    category, err := s.queries.UpdateCategory(ctx, database.UpdateCategoryParams{
-        ID:     categoryID,
-        Name:   name,
-        Color:  color,
-        UserID: userID,
+// UpdateCategory renames, recolors and moves a category. A nil parentID
+// makes it top-level. Moves that would create a cycle are rejected.
+func (s *Service) UpdateCategory(ctx context.Context, categoryID, ledgerID uuid.UUID, name, color string, parentID *uuid.UUID) (*database.Category, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    // Two concurrent moves could otherwise each pass the cycle check
+    if err := qtx.LockCategoriesByLedger(ctx, ledgerID); err != nil {
+        return nil, err
+    }
+    
+    if parentID != nil {
+        if _, err := qtx.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: *parentID, LedgerID: ledgerID}); err != nil {
+            if errors.Is(err, sql.ErrNoRows) {
+                return nil, ErrParentNotFound
+            }
+            return nil, err
+        }
+        cycle, err := qtx.CategoryHasAncestor(ctx, database.CategoryHasAncestorParams{
+            CategoryID: *parentID,
+            AncestorID: categoryID,
+        })
+        if err != nil {
+            return nil, err
+        }
+        if cycle {
+            return nil, ErrCategoryCycle
+        }
+    }
+    
+    category, err := qtx.UpdateCategory(ctx, database.UpdateCategoryParams{
+        ID:       categoryID,
+        Name:     name,
+        Color:    color,
+        LedgerID: ledgerID,
+        ParentID: nullUUID(parentID),
     })
-    return &category, err
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrCategoryNotFound
+        }
+        return nil, err
+    }
+    
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &category, nil
 }
 
-func (s *Service) DeleteCategory(ctx context.Context, categoryID, userID uuid.UUID) error {
-    return s.queries.DeleteCategory(ctx, database.DeleteCategoryParams{
-        ID:     categoryID,
-        UserID: userID,
-    })
+// DeleteCategory removes a category. Its subcategories move up to its
+// parent rather than becoming top-level.
+func (s *Service) DeleteCategory(ctx context.Context, categoryID, ledgerID uuid.UUID) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.LockCategoriesByLedger(ctx, ledgerID); err != nil {
+        return err
+    }
+    
+    category, err := qtx.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: categoryID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrCategoryNotFound
+        }
+        return err
+    }
+    
+    if err := qtx.ReparentChildCategories(ctx, database.ReparentChildCategoriesParams{
+        ParentID:   category.ParentID,
+        CategoryID: uuid.NullUUID{UUID: category.ID, Valid: true},
+        LedgerID:   ledgerID,
+    }); err != nil {
+        return err
+    }
+    
+    if err := qtx.DeleteCategory(ctx, database.DeleteCategoryParams{
+        ID:       categoryID,
+        LedgerID: ledgerID,
+    }); err != nil {
+        return err
+    }
+    return tx.Commit()
 }
 
-// CreateDefaultCategories creates default categories for new users
-func (s *Service) CreateDefaultCategories(ctx context.Context, userID uuid.UUID) error {
-    defaults := []struct {
-        name  string
-        color string
-    }{
-        {"Food & Dining", "#EF4444"},
-        {"Transportation", "#3B82F6"},
-        {"Shopping", "#8B5CF6"},
-        {"Entertainment", "#F59E0B"},
-        {"Bills & Utilities", "#10B981"},
-        {"Healthcare", "#EC4899"},
-        {"Other", "#6B7280"},
-    }
-    
-    for _, cat := range defaults {
-        _, err := s.CreateCategory(ctx, userID, cat.name, cat.color)
-        if err != nil {
-            return err
+// MergeCategories moves every expense, budget, recurring expense and
+// subcategory from source to target and deletes source, all in one
+// transaction. Budgets for a month both categories have are added together.
+func (s *Service) MergeCategories(ctx context.Context, ledgerID, sourceID, targetID uuid.UUID) (*MergeResult, error) {
+    if sourceID == targetID {
+        return nil, ErrMergeIntoSelf
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.LockCategoriesByLedger(ctx, ledgerID); err != nil {
+        return nil, err
+    }
+    
+    source, err := qtx.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: sourceID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrCategoryNotFound
+        }
+        return nil, err
+    }
+    if _, err := qtx.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: targetID, LedgerID: ledgerID}); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrTargetNotFound
         }
+        return nil, err
+    }
+    
+    // A target inside the source's subtree takes the source's place in
+    // the tree first, so handing it the source's children can't loop
+    inside, err := qtx.CategoryHasAncestor(ctx, database.CategoryHasAncestorParams{
+        CategoryID: targetID,
+        AncestorID: sourceID,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if inside {
+        if err := qtx.SetCategoryParent(ctx, database.SetCategoryParentParams{
+            ParentID: source.ParentID,
+            ID:       targetID,
+            LedgerID: ledgerID,
+        }); err != nil {
+            return nil, err
+        }
+    }
+    if err := qtx.ReparentChildCategories(ctx, database.ReparentChildCategoriesParams{
+        ParentID:   uuid.NullUUID{UUID: targetID, Valid: true},
+        CategoryID: uuid.NullUUID{UUID: sourceID, Valid: true},
+        LedgerID:   ledgerID,
+    }); err != nil {
+        return nil, err
+    }
+    
+    result := &MergeResult{}
+    nullSource, nullTarget := uuid.NullUUID{UUID: sourceID, Valid: true}, uuid.NullUUID{UUID: targetID, Valid: true}
+    if result.ExpensesMoved, err = qtx.MoveExpensesToCategory(ctx, database.MoveExpensesToCategoryParams{
+        TargetID: nullTarget,
+        SourceID: nullSource,
+        LedgerID: ledgerID,
+    }); err != nil {
+        return nil, err
     }
-    return nil
-}
\ No newline at end of file
+    if result.RecurringMoved, err = qtx.MoveRecurringExpensesToCategory(ctx, database.MoveRecurringExpensesToCategoryParams{
+        TargetID: nullTarget,
+        SourceID: nullSource,
+        LedgerID: ledgerID,
+    }); err != nil {
+        return nil, err
+    }
+    
+    merged, err := qtx.AddOverlappingBudgets(ctx, database.AddOverlappingBudgetsParams{
+        TargetID: targetID,
+        SourceID: sourceID,
+        LedgerID: ledgerID,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := qtx.DeleteOverlappingBudgets(ctx, database.DeleteOverlappingBudgetsParams{
+        SourceID: sourceID,
+        TargetID: targetID,
+        LedgerID: ledgerID,
+    }); err != nil {
+        return nil, err
+    }
+    moved, err := qtx.MoveBudgetsToCategory(ctx, database.MoveBudgetsToCategoryParams{
+        TargetID: targetID,
+        SourceID: sourceID,
+        LedgerID: ledgerID,
+    })
+    if err != nil {
+        return nil, err
+    }
+    result.BudgetsMoved = merged + moved
+    
+    if err := qtx.DeleteCategory(ctx, database.DeleteCategoryParams{ID: sourceID, LedgerID: ledgerID}); err != nil {
+        return nil, err
+    }
+    
+    if result.Target, err = qtx.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: targetID, LedgerID: ledgerID}); err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return result, nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+    if id == nil {
+        return uuid.NullUUID{}
+    }
+    return uuid.NullUUID{UUID: *id, Valid: true}
+}
@@ -0,0 +1,212 @@
+package categories
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/google/uuid"
+)
+
+var ErrUnknownTemplate = errors.New("unknown category template")
+
+// NoTemplate at signup leaves the new user without categories.
+const NoTemplate = "none"
+
+// TemplateCategory is one category of a template set, with optional
+// subcategories.
+type TemplateCategory struct {
+    Name     string             `json:"name"`
+    Color    string             `json:"color"`
+    Children []TemplateCategory `json:"children,omitempty"`
+}
+
+// TemplateSet is a named list of categories a user can start from.
+type TemplateSet struct {
+    Name        string             `json:"name"`
+    Description string             `json:"description"`
+    Categories  []TemplateCategory `json:"categories"`
+}
+
+// Templates holds the available sets and which one signup uses by default.
+type Templates struct {
+    Default string        `json:"default"`
+    Sets    []TemplateSet `json:"templates"`
+}
+
+// builtinTemplates is used when no template file is configured.
+var builtinTemplates = Templates{
+    Default: "personal",
+    Sets: []TemplateSet{{
+        Name:        "personal",
+        Description: "Everyday personal spending",
+        Categories: []TemplateCategory{
+            {Name: "Food & Dining", Color: "#EF4444"},
+            {Name: "Transportation", Color: "#3B82F6"},
+            {Name: "Shopping", Color: "#8B5CF6"},
+            {Name: "Entertainment", Color: "#F59E0B"},
+            {Name: "Bills & Utilities", Color: "#10B981"},
+            {Name: "Healthcare", Color: "#EC4899"},
+            {Name: "Other", Color: "#6B7280"},
+        },
+    }},
+}
+
+// LoadTemplates reads template sets from a JSON file. An empty path gives
+// the built-in "personal" set.
+func LoadTemplates(path string) (*Templates, error) {
+    if path == "" {
+        templates := builtinTemplates
+        return &templates, nil
+    }
+    
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var templates Templates
+    if err := json.Unmarshal(data, &templates); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    if err := templates.validate(); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &templates, nil
+}
+
+func (t *Templates) validate() error {
+    if len(t.Sets) == 0 {
+        return errors.New("no templates defined")
+    }
+    setNames := make(map[string]bool)
+    for _, set := range t.Sets {
+        if set.Name == "" || set.Name == NoTemplate {
+            return fmt.Errorf("invalid template name %q", set.Name)
+        }
+        if setNames[set.Name] {
+            return fmt.Errorf("duplicate template %q", set.Name)
+        }
+        setNames[set.Name] = true
+        
+        // Category names are unique per user, so also within a set
+        names := make(map[string]bool)
+        var check func([]TemplateCategory) error
+        check = func(categories []TemplateCategory) error {
+            for _, c := range categories {
+                if c.Name == "" {
+                    return fmt.Errorf("template %q has a category without a name", set.Name)
+                }
+                if names[c.Name] {
+                    return fmt.Errorf("template %q repeats category %q", set.Name, c.Name)
+                }
+                names[c.Name] = true
+                if err := check(c.Children); err != nil {
+                    return err
+                }
+            }
+            return nil
+        }
+        if err := check(set.Categories); err != nil {
+            return err
+        }
+    }
+    if t.Default != "" && t.Default != NoTemplate && !setNames[t.Default] {
+        return fmt.Errorf("default template %q is not defined", t.Default)
+    }
+    return nil
+}
+
+// Lookup finds a set by name; "" means the default set.
+func (t *Templates) Lookup(name string) (*TemplateSet, error) {
+    if name == "" {
+        name = t.Default
+    }
+    for i := range t.Sets {
+        if t.Sets[i].Name == name {
+            return &t.Sets[i], nil
+        }
+    }
+    return nil, ErrUnknownTemplate
+}
+
+func (s *Service) GetTemplates() []TemplateSet {
+    return s.templates.Sets
+}
+
+// ValidTemplate reports whether name can be passed to CreateDefaultCategories.
+func (s *Service) ValidTemplate(name string) bool {
+    if name == NoTemplate || (name == "" && (s.templates.Default == "" || s.templates.Default == NoTemplate)) {
+        return true
+    }
+    _, err := s.templates.Lookup(name)
+    return err == nil
+}
+
+// CreateDefaultCategories creates the categories of a template set in a
+// ledger through queries, which may be bound to the caller's transaction.
+// An empty name picks the default set and NoTemplate does nothing.
+// Categories the ledger already has are kept as they are.
+func (s *Service) CreateDefaultCategories(ctx context.Context, queries *database.Queries, ledgerID, userID uuid.UUID, template string) ([]database.Category, error) {
+    if template == NoTemplate || (template == "" && (s.templates.Default == "" || s.templates.Default == NoTemplate)) {
+        return []database.Category{}, nil
+    }
+    set, err := s.templates.Lookup(template)
+    if err != nil {
+        return nil, err
+    }
+    
+    created := []database.Category{}
+    var create func([]TemplateCategory, uuid.NullUUID) error
+    create = func(categories []TemplateCategory, parentID uuid.NullUUID) error {
+        for _, c := range categories {
+            color := c.Color
+            if color == "" {
+                color = "#6B7280"
+            }
+            category, err := queries.EnsureCategory(ctx, database.EnsureCategoryParams{
+                LedgerID: ledgerID,
+                UserID:   userID,
+                Name:     c.Name,
+                Color:    color,
+                ParentID: parentID,
+            })
+            if err != nil {
+                return err
+            }
+            created = append(created, category)
+            if err := create(c.Children, uuid.NullUUID{UUID: category.ID, Valid: true}); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+    if err := create(set.Categories, uuid.NullUUID{}); err != nil {
+        return nil, err
+    }
+    return created, nil
+}
+
+// ApplyTemplate adds a template set's categories to an existing ledger.
+func (s *Service) ApplyTemplate(ctx context.Context, ledgerID, userID uuid.UUID, template string) ([]database.Category, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    if err := qtx.LockCategoriesByLedger(ctx, ledgerID); err != nil {
+        return nil, err
+    }
+    categories, err := s.CreateDefaultCategories(ctx, qtx, ledgerID, userID, template)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return categories, nil
+}
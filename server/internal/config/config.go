@@ -2,17 +2,116 @@ package config
 
 import (
     "os"
+    "strconv"
+    "time"
 )
 
 type Config struct {
-    DatabaseURL string
-    Port        string
+    DatabaseURL       string
+    Port              string
+    ExchangeRatesFile string
+    RecurringInterval time.Duration
+    // JSON file with the category template sets offered at signup
+    CategoryTemplatesFile string
+
+    // Attachment storage: "local" (StorageDir) or "s3"
+    StorageBackend    string
+    StorageDir        string
+    S3Endpoint        string
+    S3Bucket          string
+    S3Region          string
+    S3AccessKeyID     string
+    S3SecretAccessKey string
+    S3PathStyle       bool
+    AttachmentMaxSize int64
+
+    // Outgoing mail: "log" (stdout), "file" (MailFile) or "smtp"
+    Mailer       string
+    MailFrom     string
+    MailFile     string
+    SMTPHost     string
+    SMTPPort     int64
+    SMTPUsername string
+    SMTPPassword string
+    // Frontend base URL used for links in emails
+    AppURL       string
+
+    // Key for signed tokens; a random one is used when empty, which
+    // invalidates outstanding links on restart
+    AppSecret            string
+    // Unverified users can still log in, but gated features are refused
+    RequireVerifiedEmail bool
+
+    // Failed login tracking: "memory" (single instance) or "postgres"
+    RateLimitBackend string
+    // Trust X-Forwarded-For / X-Real-IP for the client address
+    TrustProxy       bool
+
+    // Session lifetime; with SessionSliding it is an idle timeout renewed
+    // on activity, up to SessionMaxLifetime after login
+    SessionDuration    time.Duration
+    SessionSliding     bool
+    SessionMaxLifetime time.Duration
+
+    // OpenID Connect single sign-on, enabled when OIDCIssuer is set. For
+    // local testing point it at a mock IdP such as mock-oauth2-server
+    // (OIDC_ISSUER=http://localhost:8080/default, any client ID).
+    OIDCIssuer       string
+    OIDCClientID     string
+    OIDCClientSecret string
+    // Must be registered with the provider; it hits the API directly
+    OIDCRedirectURL  string
+    // Space separated, defaults to "openid email profile"
+    OIDCScopes       string
 }
 
 func Load() *Config {
     return &Config{
         DatabaseURL: getEnv("DATABASE_URL", "postgres://luis@localhost:5432/texpense?sslmode=disable"),
         Port:        getEnv("PORT", "3000"),
+        // Optional CSV or ECB XML file loaded into exchange_rates at startup
+        ExchangeRatesFile: getEnv("EXCHANGE_RATES_FILE", ""),
+        // How often due recurring expenses are turned into expenses
+        RecurringInterval: getDuration("RECURRING_INTERVAL", time.Hour),
+        // Empty falls back to the built-in "personal" set
+        CategoryTemplatesFile: getEnv("CATEGORY_TEMPLATES_FILE", "./config/category_templates.json"),
+
+        StorageBackend:    getEnv("STORAGE_BACKEND", "local"),
+        StorageDir:        getEnv("STORAGE_DIR", "./data/attachments"),
+        S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+        S3Bucket:          getEnv("S3_BUCKET", ""),
+        S3Region:          getEnv("S3_REGION", "us-east-1"),
+        S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+        S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+        // MinIO and most self-hosted servers only support path-style URLs
+        S3PathStyle:       getBool("S3_PATH_STYLE", true),
+        AttachmentMaxSize: getInt64("ATTACHMENT_MAX_SIZE", 10<<20),
+
+        Mailer:       getEnv("MAILER", "log"),
+        MailFrom:     getEnv("MAIL_FROM", "Expense Tracker <no-reply@localhost>"),
+        MailFile:     getEnv("MAIL_FILE", "./data/mail.log"),
+        // MailHog listens on 1025 without auth or TLS
+        SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+        SMTPPort:     getInt64("SMTP_PORT", 1025),
+        SMTPUsername: getEnv("SMTP_USERNAME", ""),
+        SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+        AppURL:       getEnv("APP_URL", "http://localhost:5173"),
+
+        AppSecret:            getEnv("APP_SECRET", ""),
+        RequireVerifiedEmail: getBool("REQUIRE_VERIFIED_EMAIL", false),
+
+        RateLimitBackend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+        TrustProxy:       getBool("TRUST_PROXY", false),
+
+        SessionDuration:    getDuration("SESSION_DURATION", 7*24*time.Hour),
+        SessionSliding:     getBool("SESSION_SLIDING", false),
+        SessionMaxLifetime: getDuration("SESSION_MAX_LIFETIME", 30*24*time.Hour),
+
+        OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+        OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+        OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+        OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/api/auth/oidc/callback"),
+        OIDCScopes:       getEnv("OIDC_SCOPES", ""),
     }
 }
 
@@ -21,4 +120,31 @@ func getEnv(key, defaultValue string) string {
         return value
     }
     return defaultValue
-}
\ No newline at end of file
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+    if value := os.Getenv(key); value != "" {
+        if d, err := time.ParseDuration(value); err == nil && d > 0 {
+            return d
+        }
+    }
+    return defaultValue
+}
+
+func getBool(key string, defaultValue bool) bool {
+    if value := os.Getenv(key); value != "" {
+        if b, err := strconv.ParseBool(value); err == nil {
+            return b
+        }
+    }
+    return defaultValue
+}
+
+func getInt64(key string, defaultValue int64) int64 {
+    if value := os.Getenv(key); value != "" {
+        if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+            return n
+        }
+    }
+    return defaultValue
+}
@@ -11,20 +11,74 @@ import (
 	"github.com/google/uuid"
 )
 
+const addOverlappingBudgets = `-- name: AddOverlappingBudgets :execrows
+UPDATE budgets t
+SET amount = t.amount + s.amount, updated_at = NOW()
+FROM budgets s
+WHERE t.category_id = $1 AND s.category_id = $2
+  AND t.month = s.month AND t.ledger_id = $3 AND s.ledger_id = $3
+`
+
+type AddOverlappingBudgetsParams struct {
+	TargetID uuid.UUID
+	SourceID uuid.UUID
+	LedgerID uuid.UUID
+}
+
+// Where both categories have a budget for the same month the target's
+// amount absorbs the source's.
+func (q *Queries) AddOverlappingBudgets(ctx context.Context, arg AddOverlappingBudgetsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addOverlappingBudgets, arg.TargetID, arg.SourceID, arg.LedgerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const categoryHasAncestor = `-- name: CategoryHasAncestor :one
+WITH RECURSIVE chain AS (
+    SELECT id, parent_id FROM categories WHERE id = $1
+    UNION
+    SELECT c.id, c.parent_id FROM categories c JOIN chain ON c.id = chain.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)
+`
+
+type CategoryHasAncestorParams struct {
+	CategoryID uuid.UUID
+	AncestorID uuid.UUID
+}
+
+// True when ancestor_id is category_id itself or one of its parents.
+func (q *Queries) CategoryHasAncestor(ctx context.Context, arg CategoryHasAncestorParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, categoryHasAncestor, arg.CategoryID, arg.AncestorID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createCategory = `-- name: CreateCategory :one
-INSERT INTO categories (user_id, name, color, created_at)
-VALUES ($1, $2, $3, NOW())
-RETURNING id, user_id, name, color, created_at
+INSERT INTO categories (ledger_id, user_id, name, color, parent_id, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+RETURNING id, user_id, name, color, created_at, search_vector, parent_id, ledger_id
 `
 
 type CreateCategoryParams struct {
-	UserID uuid.UUID
-	Name   string
-	Color  string
+	LedgerID uuid.UUID
+	UserID   uuid.UUID
+	Name     string
+	Color    string
+	ParentID uuid.NullUUID
 }
 
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
-	row := q.db.QueryRowContext(ctx, createCategory, arg.UserID, arg.Name, arg.Color)
+	row := q.db.QueryRowContext(ctx, createCategory,
+		arg.LedgerID,
+		arg.UserID,
+		arg.Name,
+		arg.Color,
+		arg.ParentID,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -32,33 +86,93 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
 const deleteCategory = `-- name: DeleteCategory :exec
 DELETE FROM categories 
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND ledger_id = $2
 `
 
 type DeleteCategoryParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+	ID       uuid.UUID
+	LedgerID uuid.UUID
 }
 
 func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams) error {
-	_, err := q.db.ExecContext(ctx, deleteCategory, arg.ID, arg.UserID)
+	_, err := q.db.ExecContext(ctx, deleteCategory, arg.ID, arg.LedgerID)
+	return err
+}
+
+const deleteOverlappingBudgets = `-- name: DeleteOverlappingBudgets :exec
+DELETE FROM budgets s
+USING budgets t
+WHERE s.category_id = $1 AND t.category_id = $2
+  AND s.month = t.month AND s.ledger_id = $3
+`
+
+type DeleteOverlappingBudgetsParams struct {
+	SourceID uuid.UUID
+	TargetID uuid.UUID
+	LedgerID uuid.UUID
+}
+
+func (q *Queries) DeleteOverlappingBudgets(ctx context.Context, arg DeleteOverlappingBudgetsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteOverlappingBudgets, arg.SourceID, arg.TargetID, arg.LedgerID)
 	return err
 }
 
-const getCategoriesByUser = `-- name: GetCategoriesByUser :many
-SELECT id, user_id, name, color, created_at FROM categories 
-WHERE user_id = $1
+const ensureCategory = `-- name: EnsureCategory :one
+INSERT INTO categories (ledger_id, user_id, name, color, parent_id, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (ledger_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, user_id, name, color, created_at, search_vector, parent_id, ledger_id
+`
+
+type EnsureCategoryParams struct {
+	LedgerID uuid.UUID
+	UserID   uuid.UUID
+	Name     string
+	Color    string
+	ParentID uuid.NullUUID
+}
+
+// Creates the category unless the ledger already has one by that name, in
+// which case the existing row is returned unchanged.
+func (q *Queries) EnsureCategory(ctx context.Context, arg EnsureCategoryParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, ensureCategory,
+		arg.LedgerID,
+		arg.UserID,
+		arg.Name,
+		arg.Color,
+		arg.ParentID,
+	)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Color,
+		&i.CreatedAt,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.LedgerID,
+	)
+	return i, err
+}
+
+const getCategoriesByLedger = `-- name: GetCategoriesByLedger :many
+SELECT id, user_id, name, color, created_at, search_vector, parent_id, ledger_id FROM categories 
+WHERE ledger_id = $1
 ORDER BY name
 `
 
-func (q *Queries) GetCategoriesByUser(ctx context.Context, userID uuid.UUID) ([]Category, error) {
-	rows, err := q.db.QueryContext(ctx, getCategoriesByUser, userID)
+func (q *Queries) GetCategoriesByLedger(ctx context.Context, ledgerID uuid.UUID) ([]Category, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesByLedger, ledgerID)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +186,9 @@ func (q *Queries) GetCategoriesByUser(ctx context.Context, userID uuid.UUID) ([]
 			&i.Name,
 			&i.Color,
 			&i.CreatedAt,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.LedgerID,
 		); err != nil {
 			return nil, err
 		}
@@ -87,17 +204,17 @@ func (q *Queries) GetCategoriesByUser(ctx context.Context, userID uuid.UUID) ([]
 }
 
 const getCategoryByID = `-- name: GetCategoryByID :one
-SELECT id, user_id, name, color, created_at FROM categories 
-WHERE id = $1 AND user_id = $2
+SELECT id, user_id, name, color, created_at, search_vector, parent_id, ledger_id FROM categories 
+WHERE id = $1 AND ledger_id = $2
 `
 
 type GetCategoryByIDParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+	ID       uuid.UUID
+	LedgerID uuid.UUID
 }
 
 func (q *Queries) GetCategoryByID(ctx context.Context, arg GetCategoryByIDParams) (Category, error) {
-	row := q.db.QueryRowContext(ctx, getCategoryByID, arg.ID, arg.UserID)
+	row := q.db.QueryRowContext(ctx, getCategoryByID, arg.ID, arg.LedgerID)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -105,22 +222,130 @@ func (q *Queries) GetCategoryByID(ctx context.Context, arg GetCategoryByIDParams
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
+const lockCategoriesByLedger = `-- name: LockCategoriesByLedger :exec
+SELECT id FROM categories WHERE ledger_id = $1 FOR UPDATE
+`
+
+// Serializes changes to a ledger's category tree for the transaction.
+func (q *Queries) LockCategoriesByLedger(ctx context.Context, ledgerID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockCategoriesByLedger, ledgerID)
+	return err
+}
+
+const moveBudgetsToCategory = `-- name: MoveBudgetsToCategory :execrows
+UPDATE budgets
+SET category_id = $1, updated_at = NOW()
+WHERE category_id = $2 AND ledger_id = $3
+`
+
+type MoveBudgetsToCategoryParams struct {
+	TargetID uuid.UUID
+	SourceID uuid.UUID
+	LedgerID uuid.UUID
+}
+
+func (q *Queries) MoveBudgetsToCategory(ctx context.Context, arg MoveBudgetsToCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveBudgetsToCategory, arg.TargetID, arg.SourceID, arg.LedgerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveExpensesToCategory = `-- name: MoveExpensesToCategory :execrows
+UPDATE expenses
+SET category_id = $1, updated_at = NOW()
+WHERE category_id = $2 AND ledger_id = $3
+`
+
+type MoveExpensesToCategoryParams struct {
+	TargetID uuid.NullUUID
+	SourceID uuid.NullUUID
+	LedgerID uuid.UUID
+}
+
+func (q *Queries) MoveExpensesToCategory(ctx context.Context, arg MoveExpensesToCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveExpensesToCategory, arg.TargetID, arg.SourceID, arg.LedgerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveRecurringExpensesToCategory = `-- name: MoveRecurringExpensesToCategory :execrows
+UPDATE recurring_expenses
+SET category_id = $1, updated_at = NOW()
+WHERE category_id = $2 AND ledger_id = $3
+`
+
+type MoveRecurringExpensesToCategoryParams struct {
+	TargetID uuid.NullUUID
+	SourceID uuid.NullUUID
+	LedgerID uuid.UUID
+}
+
+func (q *Queries) MoveRecurringExpensesToCategory(ctx context.Context, arg MoveRecurringExpensesToCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveRecurringExpensesToCategory, arg.TargetID, arg.SourceID, arg.LedgerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reparentChildCategories = `-- name: ReparentChildCategories :exec
+UPDATE categories
+SET parent_id = $1
+WHERE parent_id = $2 AND ledger_id = $3
+`
+
+type ReparentChildCategoriesParams struct {
+	ParentID   uuid.NullUUID
+	CategoryID uuid.NullUUID
+	LedgerID   uuid.UUID
+}
+
+func (q *Queries) ReparentChildCategories(ctx context.Context, arg ReparentChildCategoriesParams) error {
+	_, err := q.db.ExecContext(ctx, reparentChildCategories, arg.ParentID, arg.CategoryID, arg.LedgerID)
+	return err
+}
+
+const setCategoryParent = `-- name: SetCategoryParent :exec
+UPDATE categories
+SET parent_id = $1
+WHERE id = $2 AND ledger_id = $3
+`
+
+type SetCategoryParentParams struct {
+	ParentID uuid.NullUUID
+	ID       uuid.UUID
+	LedgerID uuid.UUID
+}
+
+func (q *Queries) SetCategoryParent(ctx context.Context, arg SetCategoryParentParams) error {
+	_, err := q.db.ExecContext(ctx, setCategoryParent, arg.ParentID, arg.ID, arg.LedgerID)
+	return err
+}
+
 const updateCategory = `-- name: UpdateCategory :one
 UPDATE categories
-SET name = $2, color = $3
-WHERE id = $1 AND user_id = $4
-RETURNING id, user_id, name, color, created_at
+SET name = $2, color = $3, parent_id = $5
+WHERE id = $1 AND ledger_id = $4
+RETURNING id, user_id, name, color, created_at, search_vector, parent_id, ledger_id
 `
 
 type UpdateCategoryParams struct {
-	ID     uuid.UUID
-	Name   string
-	Color  string
-	UserID uuid.UUID
+	ID       uuid.UUID
+	Name     string
+	Color    string
+	LedgerID uuid.UUID
+	ParentID uuid.NullUUID
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
@@ -128,7 +353,8 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		arg.ID,
 		arg.Name,
 		arg.Color,
-		arg.UserID,
+		arg.LedgerID,
+		arg.ParentID,
 	)
 	var i Category
 	err := row.Scan(
@@ -137,6 +363,9 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.LedgerID,
 	)
 	return i, err
 }
@@ -10,30 +10,36 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/LuisBAndrade/etracker/internal/money"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createExpense = `-- name: CreateExpense :one
-INSERT INTO expenses (user_id, category_id, amount, description, date, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-RETURNING id, user_id, category_id, amount, description, date, created_at, updated_at
+INSERT INTO expenses (ledger_id, user_id, category_id, amount, description, date, currency, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+RETURNING id, user_id, category_id, amount, description, date, created_at, updated_at, currency, recurring_expense_id, occurrence_date, search_vector, ledger_id
 `
 
 type CreateExpenseParams struct {
+	LedgerID    uuid.UUID
 	UserID      uuid.UUID
 	CategoryID  uuid.NullUUID
-	Amount      string
+	Amount      money.Amount
 	Description string
 	Date        time.Time
+	Currency    string
 }
 
 func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (Expense, error) {
 	row := q.db.QueryRowContext(ctx, createExpense,
+		arg.LedgerID,
 		arg.UserID,
 		arg.CategoryID,
 		arg.Amount,
 		arg.Description,
 		arg.Date,
+		arg.Currency,
 	)
 	var i Expense
 	err := row.Scan(
@@ -45,98 +51,160 @@ func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (E
 		&i.Date,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.RecurringExpenseID,
+		&i.OccurrenceDate,
+		&i.SearchVector,
+		&i.LedgerID,
 	)
 	return i, err
 }
 
 const deleteExpense = `-- name: DeleteExpense :exec
-DELETE FROM expenses WHERE id = $1 AND user_id = $2
+DELETE FROM expenses WHERE id = $1 AND ledger_id = $2
 `
 
 type DeleteExpenseParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+	ID       uuid.UUID
+	LedgerID uuid.UUID
 }
 
 func (q *Queries) DeleteExpense(ctx context.Context, arg DeleteExpenseParams) error {
-	_, err := q.db.ExecContext(ctx, deleteExpense, arg.ID, arg.UserID)
+	_, err := q.db.ExecContext(ctx, deleteExpense, arg.ID, arg.LedgerID)
 	return err
 }
 
 const getExpenseByID = `-- name: GetExpenseByID :one
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.id = $1 AND e.user_id = $2
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.id = $1 AND e.ledger_id = $2
 `
 
 type GetExpenseByIDParams struct {
-	ID     uuid.UUID
-	UserID uuid.UUID
+	ID       uuid.UUID
+	LedgerID uuid.UUID
 }
 
 type GetExpenseByIDRow struct {
 	ID            uuid.UUID
 	UserID        uuid.UUID
 	CategoryID    uuid.NullUUID
-	Amount        string
+	Amount        money.Amount
+	Currency      string
 	Description   string
 	Date          time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	CategoryName  sql.NullString
 	CategoryColor sql.NullString
+	Tags          []string
 }
 
 func (q *Queries) GetExpenseByID(ctx context.Context, arg GetExpenseByIDParams) (GetExpenseByIDRow, error) {
-	row := q.db.QueryRowContext(ctx, getExpenseByID, arg.ID, arg.UserID)
+	row := q.db.QueryRowContext(ctx, getExpenseByID, arg.ID, arg.LedgerID)
 	var i GetExpenseByIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.CategoryID,
 		&i.Amount,
+		&i.Currency,
 		&i.Description,
 		&i.Date,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.CategoryName,
 		&i.CategoryColor,
+		pq.Array(&i.Tags),
 	)
 	return i, err
 }
 
-const getExpenseTotalByUser = `-- name: GetExpenseTotalByUser :one
-SELECT COALESCE(SUM(amount), 0) as total
-FROM expenses
-WHERE user_id = $1
+const getExpenseTotalsByLedger = `-- name: GetExpenseTotalsByLedger :many
+SELECT e.currency, e.date, COALESCE(SUM(e.amount), 0)::NUMERIC(20, 2) as total, COUNT(e.id) as expense_count
+FROM expenses e
+WHERE e.ledger_id = $1
+  AND ($2::date IS NULL OR e.date >= $2)
+  AND ($3::date IS NULL OR e.date <= $3)
+  AND (
+      (cardinality($4::uuid[]) = 0 AND NOT $5::boolean)
+      OR e.category_id = ANY($4::uuid[])
+      OR ($5::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT $6::boolean OR e.amount >= $7)
+  AND (NOT $8::boolean OR e.amount <= $9)
+  AND ($10::text = '' OR e.description ILIKE '%' || $10 || '%')
+  AND (
+      cardinality($11::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY($11::text[])) = cardinality($11::text[])
+  )
+GROUP BY e.currency, e.date
 `
 
-func (q *Queries) GetExpenseTotalByUser(ctx context.Context, userID uuid.UUID) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, getExpenseTotalByUser, userID)
-	var total interface{}
-	err := row.Scan(&total)
-	return total, err
+type GetExpenseTotalsByLedgerParams struct {
+	LedgerID      uuid.UUID
+	StartDate     sql.NullTime
+	EndDate       sql.NullTime
+	CategoryIds   []uuid.UUID
+	Uncategorized bool
+	HasMinAmount  bool
+	MinAmount     money.Amount
+	HasMaxAmount  bool
+	MaxAmount     money.Amount
+	Query         string
+	Tags          []string
 }
 
-const getExpenseTotalByUserAndDateRange = `-- name: GetExpenseTotalByUserAndDateRange :one
-SELECT COALESCE(SUM(amount), 0) as total
-FROM expenses
-WHERE user_id = $1 AND date BETWEEN $2 AND $3
-`
-
-type GetExpenseTotalByUserAndDateRangeParams struct {
-	UserID uuid.UUID
-	Date   time.Time
-	Date_2 time.Time
+type GetExpenseTotalsByLedgerRow struct {
+	Currency     string
+	Date         time.Time
+	Total        money.Amount
+	ExpenseCount int64
 }
 
-func (q *Queries) GetExpenseTotalByUserAndDateRange(ctx context.Context, arg GetExpenseTotalByUserAndDateRangeParams) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, getExpenseTotalByUserAndDateRange, arg.UserID, arg.Date, arg.Date_2)
-	var total interface{}
-	err := row.Scan(&total)
-	return total, err
+// Takes the same filters as GetExpensesByLedger.
+func (q *Queries) GetExpenseTotalsByLedger(ctx context.Context, arg GetExpenseTotalsByLedgerParams) ([]GetExpenseTotalsByLedgerRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpenseTotalsByLedger,
+		arg.LedgerID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.CategoryIds),
+		arg.Uncategorized,
+		arg.HasMinAmount,
+		arg.MinAmount,
+		arg.HasMaxAmount,
+		arg.MaxAmount,
+		arg.Query,
+		pq.Array(arg.Tags),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpenseTotalsByLedgerRow
+	for rows.Next() {
+		var i GetExpenseTotalsByLedgerRow
+		if err := rows.Scan(
+			&i.Currency,
+			&i.Date,
+			&i.Total,
+			&i.ExpenseCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getExpensesByCategory = `-- name: GetExpensesByCategory :many
@@ -144,37 +212,44 @@ SELECT
     c.id as category_id,
     c.name as category_name,
     c.color as category_color,
-    COALESCE(SUM(e.amount), 0) as total_amount,
+    c.parent_id,
+    e.currency,
+    e.date,
+    COALESCE(SUM(e.amount), 0)::NUMERIC(20, 2) as total_amount,
     COUNT(e.id) as expense_count
 FROM categories c
-LEFT JOIN expenses e ON c.id = e.category_id AND e.user_id = $1 AND e.date BETWEEN $2 AND $3
-WHERE c.user_id = $1
-GROUP BY c.id, c.name, c.color
-HAVING COUNT(e.id) > 0 OR $4 = true
-ORDER BY total_amount DESC
+LEFT JOIN expenses e ON c.id = e.category_id AND e.ledger_id = $1 AND e.date BETWEEN $2 AND $3
+WHERE c.ledger_id = $1
+GROUP BY c.id, c.name, c.color, c.parent_id, e.currency, e.date
+HAVING COUNT(e.id) > 0 OR $4::boolean
 `
 
 type GetExpensesByCategoryParams struct {
-	UserID  uuid.UUID
-	Date    time.Time
-	Date_2  time.Time
-	Column4 interface{}
+	LedgerID     uuid.UUID
+	StartDate    time.Time
+	EndDate      time.Time
+	IncludeEmpty bool
 }
 
 type GetExpensesByCategoryRow struct {
 	CategoryID    uuid.UUID
 	CategoryName  string
 	CategoryColor string
-	TotalAmount   interface{}
+	ParentID      uuid.NullUUID
+	Currency      sql.NullString
+	Date          sql.NullTime
+	TotalAmount   money.Amount
 	ExpenseCount  int64
 }
 
+// One row per category, currency and day so totals can be converted at
+// the rate in effect on the day the money was spent.
 func (q *Queries) GetExpensesByCategory(ctx context.Context, arg GetExpensesByCategoryParams) ([]GetExpensesByCategoryRow, error) {
 	rows, err := q.db.QueryContext(ctx, getExpensesByCategory,
-		arg.UserID,
-		arg.Date,
-		arg.Date_2,
-		arg.Column4,
+		arg.LedgerID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.IncludeEmpty,
 	)
 	if err != nil {
 		return nil, err
@@ -187,6 +262,9 @@ func (q *Queries) GetExpensesByCategory(ctx context.Context, arg GetExpensesByCa
 			&i.CategoryID,
 			&i.CategoryName,
 			&i.CategoryColor,
+			&i.ParentID,
+			&i.Currency,
+			&i.Date,
 			&i.TotalAmount,
 			&i.ExpenseCount,
 		); err != nil {
@@ -203,55 +281,227 @@ func (q *Queries) GetExpensesByCategory(ctx context.Context, arg GetExpensesByCa
 	return items, nil
 }
 
-const getExpensesByUser = `-- name: GetExpensesByUser :many
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+const getExpensesByLedger = `-- name: GetExpensesByLedger :many
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
+FROM expenses e
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.ledger_id = $1
+  AND ($2::date IS NULL OR e.date >= $2)
+  AND ($3::date IS NULL OR e.date <= $3)
+  AND (
+      (cardinality($4::uuid[]) = 0 AND NOT $5::boolean)
+      OR e.category_id = ANY($4::uuid[])
+      OR ($5::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT $6::boolean OR e.amount >= $7)
+  AND (NOT $8::boolean OR e.amount <= $9)
+  AND ($10::text = '' OR e.description ILIKE '%' || $10 || '%')
+  AND (
+      cardinality($11::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY($11::text[])) = cardinality($11::text[])
+  )
+  AND (
+      $12::uuid IS NULL
+      OR (e.date, e.created_at, e.id) < ($13::date, $14::timestamp, $12::uuid)
+  )
+ORDER BY e.date DESC, e.created_at DESC, e.id DESC
+LIMIT $15 OFFSET $16
+`
+
+type GetExpensesByLedgerParams struct {
+	LedgerID        uuid.UUID
+	StartDate       sql.NullTime
+	EndDate         sql.NullTime
+	CategoryIds     []uuid.UUID
+	Uncategorized   bool
+	HasMinAmount    bool
+	MinAmount       money.Amount
+	HasMaxAmount    bool
+	MaxAmount       money.Amount
+	Query           string
+	Tags            []string
+	CursorID        uuid.NullUUID
+	CursorDate      sql.NullTime
+	CursorCreatedAt sql.NullTime
+	PageLimit       int32
+	PageOffset      int32
+}
+
+type GetExpensesByLedgerRow struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	CategoryID    uuid.NullUUID
+	Amount        money.Amount
+	Currency      string
+	Description   string
+	Date          time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CategoryName  sql.NullString
+	CategoryColor sql.NullString
+	Tags          []string
+}
+
+// Every filter is optional: NULL dates, an empty category list with
+// uncategorized unset, has_*_amount false and empty query and tags match
+// all. An expense must carry every listed tag.
+// The cursor, when given, returns rows after it in list order.
+func (q *Queries) GetExpensesByLedger(ctx context.Context, arg GetExpensesByLedgerParams) ([]GetExpensesByLedgerRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpensesByLedger,
+		arg.LedgerID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.CategoryIds),
+		arg.Uncategorized,
+		arg.HasMinAmount,
+		arg.MinAmount,
+		arg.HasMaxAmount,
+		arg.MaxAmount,
+		arg.Query,
+		pq.Array(arg.Tags),
+		arg.CursorID,
+		arg.CursorDate,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpensesByLedgerRow
+	for rows.Next() {
+		var i GetExpensesByLedgerRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Currency,
+			&i.Description,
+			&i.Date,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpensesByLedgerBackward = `-- name: GetExpensesByLedgerBackward :many
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.user_id = $1
-ORDER BY e.date DESC, e.created_at DESC
-LIMIT $2 OFFSET $3
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.ledger_id = $1
+  AND ($2::date IS NULL OR e.date >= $2)
+  AND ($3::date IS NULL OR e.date <= $3)
+  AND (
+      (cardinality($4::uuid[]) = 0 AND NOT $5::boolean)
+      OR e.category_id = ANY($4::uuid[])
+      OR ($5::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT $6::boolean OR e.amount >= $7)
+  AND (NOT $8::boolean OR e.amount <= $9)
+  AND ($10::text = '' OR e.description ILIKE '%' || $10 || '%')
+  AND (
+      cardinality($11::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY($11::text[])) = cardinality($11::text[])
+  )
+  AND (e.date, e.created_at, e.id) > ($12::date, $13::timestamp, $14::uuid)
+ORDER BY e.date ASC, e.created_at ASC, e.id ASC
+LIMIT $15
 `
 
-type GetExpensesByUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
-	Offset int32
+type GetExpensesByLedgerBackwardParams struct {
+	LedgerID        uuid.UUID
+	StartDate       sql.NullTime
+	EndDate         sql.NullTime
+	CategoryIds     []uuid.UUID
+	Uncategorized   bool
+	HasMinAmount    bool
+	MinAmount       money.Amount
+	HasMaxAmount    bool
+	MaxAmount       money.Amount
+	Query           string
+	Tags            []string
+	CursorDate      time.Time
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	PageLimit       int32
 }
 
-type GetExpensesByUserRow struct {
+type GetExpensesByLedgerBackwardRow struct {
 	ID            uuid.UUID
 	UserID        uuid.UUID
 	CategoryID    uuid.NullUUID
-	Amount        string
+	Amount        money.Amount
+	Currency      string
 	Description   string
 	Date          time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	CategoryName  sql.NullString
 	CategoryColor sql.NullString
+	Tags          []string
 }
 
-func (q *Queries) GetExpensesByUser(ctx context.Context, arg GetExpensesByUserParams) ([]GetExpensesByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getExpensesByUser, arg.UserID, arg.Limit, arg.Offset)
+// GetExpensesByLedger for the rows before the cursor. They come back
+// oldest first and the caller reverses them.
+func (q *Queries) GetExpensesByLedgerBackward(ctx context.Context, arg GetExpensesByLedgerBackwardParams) ([]GetExpensesByLedgerBackwardRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpensesByLedgerBackward,
+		arg.LedgerID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.CategoryIds),
+		arg.Uncategorized,
+		arg.HasMinAmount,
+		arg.MinAmount,
+		arg.HasMaxAmount,
+		arg.MaxAmount,
+		arg.Query,
+		pq.Array(arg.Tags),
+		arg.CursorDate,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetExpensesByUserRow
+	var items []GetExpensesByLedgerBackwardRow
 	for rows.Next() {
-		var i GetExpensesByUserRow
+		var i GetExpensesByLedgerBackwardRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.CategoryID,
 			&i.Amount,
+			&i.Currency,
 			&i.Description,
 			&i.Date,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.CategoryName,
 			&i.CategoryColor,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -266,54 +516,80 @@ func (q *Queries) GetExpensesByUser(ctx context.Context, arg GetExpensesByUserPa
 	return items, nil
 }
 
-const getExpensesByUserAndDateRange = `-- name: GetExpensesByUserAndDateRange :many
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+const searchExpenses = `-- name: SearchExpenses :many
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags,
+       ts_rank(e.search_vector || COALESCE(c.search_vector, ''::tsvector), q)::real as rank,
+       ts_headline('english', e.description, q,
+           'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxWords=30, MinWords=10, MaxFragments=2') as snippet,
+       COUNT(*) OVER () as total_count
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.user_id = $1 AND e.date BETWEEN $2 AND $3
-ORDER BY e.date DESC, e.created_at DESC
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+CROSS JOIN to_tsquery('english', $1) q
+WHERE e.ledger_id = $2
+  AND (e.search_vector @@ q OR c.search_vector @@ q)
+ORDER BY rank DESC, e.date DESC, e.created_at DESC, e.id DESC
+LIMIT $3 OFFSET $4
 `
 
-type GetExpensesByUserAndDateRangeParams struct {
-	UserID uuid.UUID
-	Date   time.Time
-	Date_2 time.Time
+type SearchExpensesParams struct {
+	Query      string
+	LedgerID   uuid.UUID
+	PageLimit  int32
+	PageOffset int32
 }
 
-type GetExpensesByUserAndDateRangeRow struct {
+type SearchExpensesRow struct {
 	ID            uuid.UUID
 	UserID        uuid.UUID
 	CategoryID    uuid.NullUUID
-	Amount        string
+	Amount        money.Amount
+	Currency      string
 	Description   string
 	Date          time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	CategoryName  sql.NullString
 	CategoryColor sql.NullString
+	Tags          []string
+	Rank          float32
+	Snippet       string
+	TotalCount    int64
 }
 
-func (q *Queries) GetExpensesByUserAndDateRange(ctx context.Context, arg GetExpensesByUserAndDateRangeParams) ([]GetExpensesByUserAndDateRangeRow, error) {
-	rows, err := q.db.QueryContext(ctx, getExpensesByUserAndDateRange, arg.UserID, arg.Date, arg.Date_2)
+// query is tsquery syntax built by the caller. Highlights in snippet are
+// wrapped in U+E000/U+E001 so the caller can escape the text first.
+func (q *Queries) SearchExpenses(ctx context.Context, arg SearchExpensesParams) ([]SearchExpensesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchExpenses,
+		arg.Query,
+		arg.LedgerID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetExpensesByUserAndDateRangeRow
+	var items []SearchExpensesRow
 	for rows.Next() {
-		var i GetExpensesByUserAndDateRangeRow
+		var i SearchExpensesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.CategoryID,
 			&i.Amount,
+			&i.Currency,
 			&i.Description,
 			&i.Date,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.CategoryName,
 			&i.CategoryColor,
+			pq.Array(&i.Tags),
+			&i.Rank,
+			&i.Snippet,
+			&i.TotalCount,
 		); err != nil {
 			return nil, err
 		}
@@ -330,28 +606,30 @@ func (q *Queries) GetExpensesByUserAndDateRange(ctx context.Context, arg GetExpe
 
 const updateExpense = `-- name: UpdateExpense :one
 UPDATE expenses
-SET amount = $3, description = $4, category_id = $5, date = $6, updated_at = NOW()
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, category_id, amount, description, date, created_at, updated_at
+SET amount = $3, description = $4, category_id = $5, date = $6, currency = $7, updated_at = NOW()
+WHERE id = $1 AND ledger_id = $2
+RETURNING id, user_id, category_id, amount, description, date, created_at, updated_at, currency, recurring_expense_id, occurrence_date, search_vector, ledger_id
 `
 
 type UpdateExpenseParams struct {
 	ID          uuid.UUID
-	UserID      uuid.UUID
-	Amount      string
+	LedgerID    uuid.UUID
+	Amount      money.Amount
 	Description string
 	CategoryID  uuid.NullUUID
 	Date        time.Time
+	Currency    string
 }
 
 func (q *Queries) UpdateExpense(ctx context.Context, arg UpdateExpenseParams) (Expense, error) {
 	row := q.db.QueryRowContext(ctx, updateExpense,
 		arg.ID,
-		arg.UserID,
+		arg.LedgerID,
 		arg.Amount,
 		arg.Description,
 		arg.CategoryID,
 		arg.Date,
+		arg.Currency,
 	)
 	var i Expense
 	err := row.Scan(
@@ -363,6 +641,11 @@ func (q *Queries) UpdateExpense(ctx context.Context, arg UpdateExpenseParams) (E
 		&i.Date,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.RecurringExpenseID,
+		&i.OccurrenceDate,
+		&i.SearchVector,
+		&i.LedgerID,
 	)
 	return i, err
 }
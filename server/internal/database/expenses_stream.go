@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// sqlc has no row iterator for database/sql, so the export query lives
+// here. It returns the same columns and takes the same filters as
+// GetExpensesByLedger, without pagination; expenses_stream_test.go fails
+// when the two drift apart.
+const streamExpensesByLedger = `
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
+FROM expenses e
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.ledger_id = $1
+  AND ($2::date IS NULL OR e.date >= $2)
+  AND ($3::date IS NULL OR e.date <= $3)
+  AND (
+      (cardinality($4::uuid[]) = 0 AND NOT $5::boolean)
+      OR e.category_id = ANY($4::uuid[])
+      OR ($5::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT $6::boolean OR e.amount >= $7)
+  AND (NOT $8::boolean OR e.amount <= $9)
+  AND ($10::text = '' OR e.description ILIKE '%' || $10 || '%')
+  AND (
+      cardinality($11::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY($11::text[])) = cardinality($11::text[])
+  )
+ORDER BY e.date DESC, e.created_at DESC, e.id DESC
+`
+
+type StreamExpensesByLedgerParams struct {
+	LedgerID      uuid.UUID
+	StartDate     sql.NullTime
+	EndDate       sql.NullTime
+	CategoryIds   []uuid.UUID
+	Uncategorized bool
+	HasMinAmount  bool
+	MinAmount     money.Amount
+	HasMaxAmount  bool
+	MaxAmount     money.Amount
+	Query         string
+	Tags          []string
+}
+
+// StreamExpensesByLedger calls fn for each matching row without buffering
+// the result set. Returning an error from fn stops the iteration.
+func (q *Queries) StreamExpensesByLedger(ctx context.Context, arg StreamExpensesByLedgerParams, fn func(GetExpensesByLedgerRow) error) error {
+	rows, err := q.db.QueryContext(ctx, streamExpensesByLedger,
+		arg.LedgerID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.CategoryIds),
+		arg.Uncategorized,
+		arg.HasMinAmount,
+		arg.MinAmount,
+		arg.HasMaxAmount,
+		arg.MaxAmount,
+		arg.Query,
+		pq.Array(arg.Tags),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i GetExpensesByLedgerRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Currency,
+			&i.Description,
+			&i.Date,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
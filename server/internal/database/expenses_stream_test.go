@@ -0,0 +1,71 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The export stream is written by hand, so these tests pin it to the
+// sqlc-generated list query: same columns, same filter clauses with the same
+// placeholders, and the same filter parameters in the same order.
+
+func TestStreamExpensesMatchesListColumns(t *testing.T) {
+	for name, query := range map[string]string{
+		"GetExpensesByLedger":         getExpensesByLedger,
+		"GetExpensesByLedgerBackward": getExpensesByLedgerBackward,
+	} {
+		if got, want := selectSQL(t, streamExpensesByLedger), selectSQL(t, query); got != want {
+			t.Errorf("stream columns differ from %s:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
+
+func TestStreamExpensesMatchesListFilters(t *testing.T) {
+	stream := filterSQL(t, streamExpensesByLedger)
+	for name, query := range map[string]string{
+		"GetExpensesByLedger":         getExpensesByLedger,
+		"GetExpensesByLedgerBackward": getExpensesByLedgerBackward,
+		"GetExpenseTotalsByLedger":    getExpenseTotalsByLedger,
+	} {
+		if want := filterSQL(t, query); stream != want {
+			t.Errorf("stream filters differ from %s:\n%s\nwant:\n%s", name, stream, want)
+		}
+	}
+}
+
+func TestStreamExpensesMatchesListParams(t *testing.T) {
+	stream := reflect.TypeOf(StreamExpensesByLedgerParams{})
+	for _, params := range []any{GetExpensesByLedgerParams{}, GetExpensesByLedgerBackwardParams{}, GetExpenseTotalsByLedgerParams{}} {
+		list := reflect.TypeOf(params)
+		for i := 0; i < stream.NumField(); i++ {
+			got, want := stream.Field(i), list.Field(i)
+			if got.Name != want.Name || got.Type != want.Type {
+				t.Errorf("%s field %d is %s %s, stream has %s %s", list.Name(), i, want.Name, want.Type, got.Name, got.Type)
+			}
+		}
+	}
+}
+
+// selectSQL is the column list and joins, up to the WHERE clause.
+func selectSQL(t *testing.T, query string) string {
+	t.Helper()
+	start := strings.Index(query, "SELECT ")
+	end := strings.Index(query, "\nWHERE ")
+	if start < 0 || end < start {
+		t.Fatalf("no SELECT ... WHERE in query:\n%s", query)
+	}
+	return query[start:end]
+}
+
+// filterSQL is the WHERE clause up to the end of the last shared filter,
+// the tag match on $11. Pagination and grouping come after it.
+func filterSQL(t *testing.T, query string) string {
+	t.Helper()
+	start := strings.Index(query, "\nWHERE ")
+	end := strings.LastIndex(query, "$11::text[])")
+	if start < 0 || end < start {
+		t.Fatalf("no shared filters in query:\n%s", query)
+	}
+	return query[start:end]
+}
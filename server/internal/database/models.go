@@ -5,41 +5,277 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 
+	"github.com/LuisBAndrade/etracker/internal/money"
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type Attachment struct {
+	ID          uuid.UUID
+	ExpenseID   uuid.UUID
+	UserID      uuid.UUID
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	Sha256      string
+	CreatedAt   time.Time
+}
+
+type Budget struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CategoryID uuid.UUID
+	Month      time.Time
+	Amount     money.Amount
+	Rollover   bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	LedgerID   uuid.UUID
+}
+
 type Category struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Color        string
+	CreatedAt    time.Time
+	SearchVector interface{}
+	ParentID     uuid.NullUUID
+	LedgerID     uuid.UUID
+}
+
+type EmailChangeToken struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
-	Name      string
-	Color     string
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 	CreatedAt time.Time
 }
 
+type ExchangeRate struct {
+	BaseCurrency  string
+	QuoteCurrency string
+	RateDate      time.Time
+	Rate          string
+	CreatedAt     time.Time
+}
+
 type Expense struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	CategoryID         uuid.NullUUID
+	Amount             money.Amount
+	Description        string
+	Date               time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Currency           string
+	RecurringExpenseID uuid.NullUUID
+	OccurrenceDate     sql.NullTime
+	SearchVector       interface{}
+	LedgerID           uuid.UUID
+}
+
+type ExpenseSplit struct {
+	ExpenseID uuid.UUID
+	PaidBy    uuid.UUID
+	Method    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ExpenseSplitParticipant struct {
+	ExpenseID uuid.UUID
+	UserID    uuid.UUID
+	Share     sql.NullString
+	Amount    money.Amount
+}
+
+type ExpenseTag struct {
+	ExpenseID uuid.UUID
+	TagID     uuid.UUID
+}
+
+type Income struct {
 	ID          uuid.UUID
+	LedgerID    uuid.UUID
 	UserID      uuid.UUID
 	CategoryID  uuid.NullUUID
-	Amount      string
+	Amount      money.Amount
+	Currency    string
 	Description string
 	Date        time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
-type Session struct {
-	Token     string
+type IncomeCategory struct {
+	ID        uuid.UUID
+	LedgerID  uuid.UUID
 	UserID    uuid.UUID
+	Name      string
+	Color     string
+	CreatedAt time.Time
+}
+
+type Ledger struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type LedgerInvitation struct {
+	ID        uuid.UUID
+	LedgerID  uuid.UUID
+	Email     string
+	Role      string
+	InvitedBy uuid.UUID
 	ExpiresAt time.Time
 	CreatedAt time.Time
 }
 
+type LedgerMember struct {
+	LedgerID  uuid.UUID
+	UserID    uuid.UUID
+	Role      string
+	CreatedAt time.Time
+}
+
+type LoginAttempt struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	BlockedUntil  sql.NullTime
+}
+
+type LoginChallenge struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Attempts  int32
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type RecurringExpense struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	CategoryID      uuid.NullUUID
+	Amount          money.Amount
+	Currency        string
+	Description     string
+	Frequency       string
+	IntervalCount   int32
+	StartDate       time.Time
+	EndDate         sql.NullTime
+	OccurrenceLimit sql.NullInt32
+	AnchorDate      time.Time
+	AnchorIndex     int32
+	NextIndex       int32
+	NextDate        sql.NullTime
+	Paused          bool
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	LedgerID        uuid.UUID
+}
+
+type RecurringExpenseSkip struct {
+	RecurringExpenseID uuid.UUID
+	OccurrenceDate     time.Time
+	CreatedAt          time.Time
+}
+
+type Session struct {
+	Token             string
+	UserID            uuid.UUID
+	ExpiresAt         time.Time
+	CreatedAt         time.Time
+	ID                uuid.UUID
+	UserAgent         string
+	IpAddress         string
+	LastSeenAt        time.Time
+	AbsoluteExpiresAt time.Time
+}
+
+type Settlement struct {
+	ID         uuid.UUID
+	LedgerID   uuid.UUID
+	FromUserID uuid.UUID
+	ToUserID   uuid.UUID
+	Amount     money.Amount
+	Currency   string
+	Date       time.Time
+	Note       string
+	CreatedBy  uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	LedgerID  uuid.UUID
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	BaseCurrency    string
+	EmailVerifiedAt sql.NullTime
+	ActiveLedgerID  uuid.NullUUID
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Issuer      string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+	CreatedAt    time.Time
 }
@@ -22,18 +22,28 @@ func (q *Queries) CleanupExpiredSessions(ctx context.Context) error {
 }
 
 const createSession = `-- name: CreateSession :exec
-INSERT INTO sessions (token, user_id, expires_at, created_at)
-VALUES ($1, $2, $3, NOW())
+INSERT INTO sessions (token, user_id, expires_at, absolute_expires_at, user_agent, ip_address, last_seen_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
 `
 
 type CreateSessionParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	Token             string
+	UserID            uuid.UUID
+	ExpiresAt         time.Time
+	AbsoluteExpiresAt time.Time
+	UserAgent         string
+	IpAddress         string
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
-	_, err := q.db.ExecContext(ctx, createSession, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, createSession,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.AbsoluteExpiresAt,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	return err
 }
 
@@ -46,7 +56,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password
+RETURNING id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id
 `
 
 type CreateUserParams struct {
@@ -64,12 +74,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password FROM users
+SELECT id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id FROM users
 WHERE email = $1
 `
 
@@ -82,12 +95,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password FROM users
+SELECT id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id FROM users
 WHERE id = $1
 `
 
@@ -100,12 +116,15 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
 	)
 	return i, err
 }
 
 const getUserBySessionToken = `-- name: GetUserBySessionToken :one
-SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password FROM users u
+SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password, u.base_currency, u.email_verified_at, u.active_ledger_id FROM users u
 JOIN sessions s ON u.id = s.user_id
 WHERE s.token = $1 AND s.expires_at > NOW()
 `
@@ -119,6 +138,115 @@ func (q *Queries) GetUserBySessionToken(ctx context.Context, token string) (User
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
+	)
+	return i, err
+}
+
+const hasRecentSession = `-- name: HasRecentSession :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE token = $1 AND user_id = $2 AND expires_at > NOW()
+        AND created_at > NOW() - make_interval(secs => $3::float8)
+)
+`
+
+type HasRecentSessionParams struct {
+	Token         string
+	UserID        uuid.UUID
+	MaxAgeSeconds float64
+}
+
+// True when token is a live session of user_id that signed in within
+// max_age_seconds, for actions that need a recent login.
+func (q *Queries) HasRecentSession(ctx context.Context, arg HasRecentSessionParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasRecentSession, arg.Token, arg.UserID, arg.MaxAgeSeconds)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at,
+    (token = $1)::boolean AS current
+FROM sessions
+WHERE user_id = $2 AND expires_at > NOW()
+ORDER BY last_seen_at DESC
+`
+
+type ListUserSessionsParams struct {
+	CurrentToken string
+	UserID       uuid.UUID
+}
+
+type ListUserSessionsRow struct {
+	ID         uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	Current    bool
+}
+
+func (q *Queries) ListUserSessions(ctx context.Context, arg ListUserSessionsParams) ([]ListUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSessions, arg.CurrentToken, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserSessionsRow
+	for rows.Next() {
+		var i ListUserSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+			&i.ExpiresAt,
+			&i.Current,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+// Only verifies the address the token was issued for; a later email change
+// makes older tokens useless.
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
 	)
 	return i, err
 }
@@ -132,6 +260,20 @@ func (q *Queries) RevokeAllUserSessions(ctx context.Context, userID uuid.UUID) e
 	return err
 }
 
+const revokeOtherUserSessions = `-- name: RevokeOtherUserSessions :exec
+DELETE FROM sessions WHERE user_id = $1 AND token <> $2
+`
+
+type RevokeOtherUserSessionsParams struct {
+	UserID uuid.UUID
+	Token  string
+}
+
+func (q *Queries) RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherUserSessions, arg.UserID, arg.Token)
+	return err
+}
+
 const revokeSession = `-- name: RevokeSession :exec
 DELETE FROM sessions WHERE token = $1
 `
@@ -141,10 +283,73 @@ func (q *Queries) RevokeSession(ctx context.Context, token string) error {
 	return err
 }
 
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2
+`
+
+type RevokeUserSessionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setActiveLedger = `-- name: SetActiveLedger :exec
+UPDATE users SET active_ledger_id = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetActiveLedgerParams struct {
+	ID             uuid.UUID
+	ActiveLedgerID uuid.NullUUID
+}
+
+func (q *Queries) SetActiveLedger(ctx context.Context, arg SetActiveLedgerParams) error {
+	_, err := q.db.ExecContext(ctx, setActiveLedger, arg.ID, arg.ActiveLedgerID)
+	return err
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = NOW(),
+    ip_address = $1,
+    expires_at = CASE
+        WHEN $2::boolean
+            THEN LEAST(NOW() + make_interval(secs => $3::float8), absolute_expires_at)
+        ELSE expires_at
+    END
+WHERE token = $4 AND last_seen_at < NOW() - INTERVAL '1 minute'
+`
+
+type TouchSessionParams struct {
+	IpAddress   string
+	Slide       bool
+	IdleSeconds float64
+	Token       string
+}
+
+// Records activity at most once a minute. With slide set the session is
+// extended to idle_seconds from now, but never past its absolute expiry.
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.ExecContext(ctx, touchSession,
+		arg.IpAddress,
+		arg.Slide,
+		arg.IdleSeconds,
+		arg.Token,
+	)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password
+RETURNING id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id
 `
 
 type UpdateUserParams struct {
@@ -162,6 +367,36 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
+	)
+	return i, err
+}
+
+const updateUserBaseCurrency = `-- name: UpdateUserBaseCurrency :one
+UPDATE users SET base_currency = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, base_currency, email_verified_at, active_ledger_id
+`
+
+type UpdateUserBaseCurrencyParams struct {
+	ID           uuid.UUID
+	BaseCurrency string
+}
+
+func (q *Queries) UpdateUserBaseCurrency(ctx context.Context, arg UpdateUserBaseCurrencyParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserBaseCurrency, arg.ID, arg.BaseCurrency)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.BaseCurrency,
+		&i.EmailVerifiedAt,
+		&i.ActiveLedgerID,
 	)
 	return i, err
 }
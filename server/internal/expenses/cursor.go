@@ -0,0 +1,60 @@
+package expenses
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in the expense list by the sort key of one row.
+// A forward cursor continues with the rows after it, a backward cursor
+// with the rows before it. Clients only ever see the encoded form.
+type Cursor struct {
+    Date      time.Time
+    CreatedAt time.Time
+    ID        uuid.UUID
+    Backward  bool
+}
+
+type cursorPayload struct {
+    Date      string    `json:"d"`
+    CreatedAt time.Time `json:"c"`
+    ID        uuid.UUID `json:"i"`
+    Backward  bool      `json:"b,omitempty"`
+}
+
+func cursorFor(row database.GetExpensesByLedgerRow, backward bool) Cursor {
+    return Cursor{Date: row.Date, CreatedAt: row.CreatedAt, ID: row.ID, Backward: backward}
+}
+
+func (c Cursor) Encode() string {
+    data, _ := json.Marshal(cursorPayload{
+        Date:      c.Date.Format("2006-01-02"),
+        CreatedAt: c.CreatedAt,
+        ID:        c.ID,
+        Backward:  c.Backward,
+    })
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+    data, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+    var p cursorPayload
+    if err := json.Unmarshal(data, &p); err != nil || p.ID == uuid.Nil {
+        return nil, ErrInvalidCursor
+    }
+    date, err := time.Parse("2006-01-02", p.Date)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+    return &Cursor{Date: date, CreatedAt: p.CreatedAt, ID: p.ID, Backward: p.Backward}, nil
+}
@@ -0,0 +1,239 @@
+package expenses
+
+import (
+    "bufio"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/LuisBAndrade/etracker/internal/xlsx"
+    "github.com/google/uuid"
+)
+
+const (
+    // Rows written between flushes to the client
+    exportFlushEvery = 500
+    // Exports stream for longer than the server's default write timeout
+    exportWriteTimeout = 10 * time.Minute
+)
+
+var exportColumns = []string{
+    "id", "date", "description", "amount", "currency", "category_id", "category_name", "category_color", "tags", "created_at", "updated_at",
+}
+
+// rowWriter is implemented once per export format.
+type rowWriter interface {
+    WriteExpense(database.GetExpensesByLedgerRow) error
+    Flush() error
+    Close() error
+}
+
+// StreamExpenses feeds every expense matching filter to fn, newest first.
+func (s *Service) StreamExpenses(ctx context.Context, ledgerID uuid.UUID, filter ExpenseFilter, fn func(database.GetExpensesByLedgerRow) error) error {
+    return s.queries.StreamExpensesByLedger(ctx, database.StreamExpensesByLedgerParams(filter.params(ledgerID)), fn)
+}
+
+// HandleExportExpenses streams expenses as csv, jsonl or xlsx. It accepts
+// the same filters as the expense list.
+func (s *Service) HandleExportExpenses(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    format := r.URL.Query().Get("format")
+    if format == "" {
+        format = "csv"
+    }
+    contentType, ok := map[string]string{
+        "csv":   "text/csv; charset=utf-8",
+        "jsonl": "application/x-ndjson",
+        "xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+    }[format]
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid format, use csv, jsonl or xlsx")
+        return
+    }
+    
+    filter, err := parseExpenseFilter(r.URL.Query())
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+    
+    w.Header().Set("Content-Type", contentType)
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFilename(filter, format)))
+    w.Header().Set("Cache-Control", "no-store")
+    w.WriteHeader(http.StatusOK)
+    
+    out, err := newRowWriter(format, w)
+    if err != nil {
+        log.Printf("Export failed to start: %v", err)
+        return
+    }
+    
+    written := 0
+    err = s.StreamExpenses(r.Context(), ledger.ID, filter, func(row database.GetExpensesByLedgerRow) error {
+        if err := out.WriteExpense(row); err != nil {
+            return err
+        }
+        written++
+        if written%exportFlushEvery == 0 {
+            if err := out.Flush(); err != nil {
+                return err
+            }
+            if f, ok := w.(http.Flusher); ok {
+                f.Flush()
+            }
+        }
+        return nil
+    })
+    // Headers are already sent, so a failure can only truncate the download
+    if err != nil {
+        log.Printf("Export aborted after %d rows: %v", written, err)
+        return
+    }
+    if err := out.Close(); err != nil {
+        log.Printf("Export failed to finish: %v", err)
+    }
+}
+
+func exportFilename(filter ExpenseFilter, format string) string {
+    name := "expenses"
+    if filter.StartDate != nil {
+        name += "-from-" + filter.StartDate.Format("2006-01-02")
+    }
+    if filter.EndDate != nil {
+        name += "-to-" + filter.EndDate.Format("2006-01-02")
+    }
+    if filter.StartDate == nil && filter.EndDate == nil {
+        name += "-" + time.Now().Format("2006-01-02")
+    }
+    return name + "." + format
+}
+
+func newRowWriter(format string, w io.Writer) (rowWriter, error) {
+    switch format {
+    case "jsonl":
+        buf := bufio.NewWriter(w)
+        return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+    case "xlsx":
+        xw, err := xlsx.NewWriter(w, "Expenses")
+        if err != nil {
+            return nil, err
+        }
+        header := make([]xlsx.Cell, len(exportColumns))
+        for i, c := range exportColumns {
+            header[i] = xlsx.String(c)
+        }
+        if err := xw.WriteRow(header); err != nil {
+            return nil, err
+        }
+        return &xlsxWriter{w: xw}, nil
+    default:
+        cw := csv.NewWriter(w)
+        if err := cw.Write(exportColumns); err != nil {
+            return nil, err
+        }
+        return &csvWriter{w: cw}, nil
+    }
+}
+
+// exportFields renders a row in exportColumns order. Text users typed is
+// passed through spreadsheetSafe.
+func exportFields(row database.GetExpensesByLedgerRow) []string {
+    categoryID := ""
+    if row.CategoryID.Valid {
+        categoryID = row.CategoryID.UUID.String()
+    }
+    return []string{
+        row.ID.String(),
+        row.Date.Format("2006-01-02"),
+        spreadsheetSafe(row.Description),
+        row.Amount.String(),
+        row.Currency,
+        categoryID,
+        spreadsheetSafe(row.CategoryName.String),
+        spreadsheetSafe(row.CategoryColor.String),
+        spreadsheetSafe(strings.Join(row.Tags, ",")),
+        row.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        row.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+}
+
+// spreadsheetSafe keeps spreadsheet apps from evaluating text as a formula
+// by prefixing a ' to values that start like one.
+func spreadsheetSafe(s string) string {
+    if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+        return "'" + s
+    }
+    return s
+}
+
+type csvWriter struct {
+    w *csv.Writer
+}
+
+func (c *csvWriter) WriteExpense(row database.GetExpensesByLedgerRow) error {
+    return c.w.Write(exportFields(row))
+}
+
+func (c *csvWriter) Flush() error {
+    c.w.Flush()
+    return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+    return c.Flush()
+}
+
+type jsonlWriter struct {
+    buf *bufio.Writer
+    enc *json.Encoder
+}
+
+func (j *jsonlWriter) WriteExpense(row database.GetExpensesByLedgerRow) error {
+    return j.enc.Encode(expenseRowResponse(row))
+}
+
+func (j *jsonlWriter) Flush() error {
+    return j.buf.Flush()
+}
+
+func (j *jsonlWriter) Close() error {
+    return j.buf.Flush()
+}
+
+type xlsxWriter struct {
+    w *xlsx.Writer
+}
+
+func (x *xlsxWriter) WriteExpense(row database.GetExpensesByLedgerRow) error {
+    fields := exportFields(row)
+    cells := make([]xlsx.Cell, len(fields))
+    for i, f := range fields {
+        cells[i] = xlsx.String(f)
+    }
+    cells[3] = xlsx.Number(fields[3]) // amount
+    return x.w.WriteRow(cells)
+}
+
+func (x *xlsxWriter) Flush() error {
+    return x.w.Flush()
+}
+
+func (x *xlsxWriter) Close() error {
+    return x.w.Close()
+}
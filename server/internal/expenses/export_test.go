@@ -0,0 +1,56 @@
+package expenses
+
+import (
+    "database/sql"
+    "testing"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+)
+
+func TestSpreadsheetSafe(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        {"", ""},
+        {"Coffee", "Coffee"},
+        {"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+        {"+1+2", "'+1+2"},
+        {"-2+3", "'-2+3"},
+        {"@SUM(A1)", "'@SUM(A1)"},
+        {"\t=1", "'\t=1"},
+        {"\r=1", "'\r=1"},
+        {"a=b", "a=b"},
+        {"'quoted", "'quoted"},
+    }
+
+    for _, tt := range tests {
+        if got := spreadsheetSafe(tt.in); got != tt.want {
+            t.Errorf("spreadsheetSafe(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestExportFieldsEscapesUserText(t *testing.T) {
+    row := database.GetExpensesByLedgerRow{
+        Amount:       money.FromMinorUnits(1234),
+        Currency:     "USD",
+        Description:  "=cmd|' /C calc'!A0",
+        CategoryName: sql.NullString{String: "+Food", Valid: true},
+        Tags:         []string{"-x"},
+    }
+
+    fields := exportFields(row)
+    want := map[int]string{
+        2: "'=cmd|' /C calc'!A0",
+        3: "12.34",
+        6: "'+Food",
+        8: "'-x",
+    }
+    for i, w := range want {
+        if fields[i] != w {
+            t.Errorf("%s = %q, want %q", exportColumns[i], fields[i], w)
+        }
+    }
+}
@@ -0,0 +1,139 @@
+package expenses
+
+import (
+    "errors"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/LuisBAndrade/etracker/internal/tags"
+    "github.com/google/uuid"
+)
+
+// ExpenseFilter narrows the expense list, its totals and exports. Zero
+// values mean "no restriction". Category conditions are ORed together;
+// everything else is ANDed.
+type ExpenseFilter struct {
+    StartDate     *time.Time
+    EndDate       *time.Time
+    CategoryIDs   []uuid.UUID
+    Uncategorized bool
+    MinAmount     *money.Amount
+    MaxAmount     *money.Amount
+    Query         string // Case-insensitive substring of the description
+    Tags          []string // Expenses must carry all of these
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (f ExpenseFilter) params(ledgerID uuid.UUID) database.GetExpenseTotalsByLedgerParams {
+    p := database.GetExpenseTotalsByLedgerParams{
+        LedgerID:      ledgerID,
+        CategoryIds:   f.CategoryIDs,
+        Uncategorized: f.Uncategorized,
+        Query:         likeEscaper.Replace(f.Query),
+        Tags:          f.Tags,
+    }
+    if p.CategoryIds == nil {
+        p.CategoryIds = []uuid.UUID{}
+    }
+    if p.Tags == nil {
+        p.Tags = []string{}
+    }
+    if f.StartDate != nil {
+        p.StartDate.Time, p.StartDate.Valid = *f.StartDate, true
+    }
+    if f.EndDate != nil {
+        p.EndDate.Time, p.EndDate.Valid = *f.EndDate, true
+    }
+    if f.MinAmount != nil {
+        p.MinAmount, p.HasMinAmount = *f.MinAmount, true
+    }
+    if f.MaxAmount != nil {
+        p.MaxAmount, p.HasMaxAmount = *f.MaxAmount, true
+    }
+    return p
+}
+
+// parseExpenseFilter reads start_date, end_date, category_id (repeatable),
+// uncategorized, min_amount, max_amount, q and tag (repeatable) from a
+// query string.
+func parseExpenseFilter(query url.Values) (ExpenseFilter, error) {
+    var filter ExpenseFilter
+    
+    if v := query.Get("start_date"); v != "" {
+        startDate, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return filter, errors.New("Invalid start_date format")
+        }
+        filter.StartDate = &startDate
+    }
+    if v := query.Get("end_date"); v != "" {
+        endDate, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            return filter, errors.New("Invalid end_date format")
+        }
+        filter.EndDate = &endDate
+    }
+    if filter.StartDate != nil && filter.EndDate != nil && filter.EndDate.Before(*filter.StartDate) {
+        return filter, errors.New("end_date must not be before start_date")
+    }
+    
+    // Accept both ?category_id=a&category_id=b and ?category_id=a,b
+    for _, v := range query["category_id"] {
+        for _, id := range strings.Split(v, ",") {
+            if id = strings.TrimSpace(id); id == "" {
+                continue
+            }
+            categoryID, err := uuid.Parse(id)
+            if err != nil {
+                return filter, errors.New("Invalid category ID")
+            }
+            filter.CategoryIDs = append(filter.CategoryIDs, categoryID)
+        }
+    }
+    if v := query.Get("uncategorized"); v != "" {
+        switch strings.ToLower(v) {
+        case "true", "1":
+            filter.Uncategorized = true
+        case "false", "0":
+        default:
+            return filter, errors.New("uncategorized must be true or false")
+        }
+    }
+    
+    if v := query.Get("min_amount"); v != "" {
+        minAmount, err := money.Parse(v)
+        if err != nil {
+            return filter, errors.New("Invalid min_amount")
+        }
+        filter.MinAmount = &minAmount
+    }
+    if v := query.Get("max_amount"); v != "" {
+        maxAmount, err := money.Parse(v)
+        if err != nil {
+            return filter, errors.New("Invalid max_amount")
+        }
+        filter.MaxAmount = &maxAmount
+    }
+    if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MaxAmount < *filter.MinAmount {
+        return filter, errors.New("max_amount must not be less than min_amount")
+    }
+    
+    filter.Query = strings.TrimSpace(query.Get("q"))
+    
+    var tagNames []string
+    for _, v := range query["tag"] {
+        tagNames = append(tagNames, strings.Split(v, ",")...)
+    }
+    if tagNames != nil {
+        normalized, err := tags.Normalize(tagNames)
+        if err != nil {
+            return filter, err
+        }
+        filter.Tags = normalized
+    }
+    return filter, nil
+}
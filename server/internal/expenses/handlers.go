@@ -2,11 +2,16 @@ package expenses
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/LuisBAndrade/etracker/internal/auth"
+	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/splits"
+	"github.com/LuisBAndrade/etracker/internal/tags"
 	"github.com/LuisBAndrade/etracker/internal/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -14,16 +19,20 @@ import (
 
 type CreateExpenseRequest struct {
     CategoryID  *string `json:"category_id"`
-    Amount      float64 `json:"amount" validate:"required"` // Keep as float64 for JSON
+    Amount      money.Amount `json:"amount" validate:"required"` // "12.34" or integer cents
+    Currency    string  `json:"currency"` // ISO 4217, defaults to the user's base currency
     Description string  `json:"description" validate:"required"`
     Date        string  `json:"date"` // YYYY-MM-DD format
+    Tags        []string `json:"tags"` // names, created on first use
 }
 
 type UpdateExpenseRequest struct {
     CategoryID  *string `json:"category_id"`
-    Amount      float64 `json:"amount" validate:"required"` // Keep as float64 for JSON
+    Amount      money.Amount `json:"amount" validate:"required"` // "12.34" or integer cents
+    Currency    string  `json:"currency"` // ISO 4217, defaults to the user's base currency
     Description string  `json:"description" validate:"required"`
     Date        string  `json:"date"` // YYYY-MM-DD format
+    Tags        []string `json:"tags"` // omit to keep the current tags, [] clears them
 }
 
 type ExpenseResponse struct {
@@ -31,25 +40,58 @@ type ExpenseResponse struct {
     CategoryID   *string `json:"category_id"`
     CategoryName *string `json:"category_name"`
     CategoryColor *string `json:"category_color"`
-    Amount       string  `json:"amount"` // String from database
+    Amount       money.Amount `json:"amount"`
+    Currency     string  `json:"currency"`
     Description  string  `json:"description"`
     Date         string  `json:"date"`
     CreatedAt    string  `json:"created_at"`
     UpdatedAt    string  `json:"updated_at"`
+    Tags         []string `json:"tags"`
+}
+
+type CurrencyTotalResponse struct {
+    Currency     string       `json:"currency"`
+    Total        money.Amount `json:"total"`
+    ExpenseCount int64        `json:"expense_count"`
 }
 
 type ExpenseSummaryResponse struct {
-    Total       string `json:"total"` // String from database
-    Count       int    `json:"count"`
+    Total       money.Amount `json:"total"` // Converted into Currency
+    Currency    string `json:"currency"`
+    ByCurrency  []CurrencyTotalResponse `json:"by_currency"`
+    Unconverted []string `json:"unconverted_currencies"`
+    Count       int    `json:"count"` // Expenses on this page
+    TotalCount  int64  `json:"total_count"` // Expenses matching the filters
+    Limit       int32  `json:"limit"`
+    Offset      int32  `json:"offset"`
+    NextCursor  *string `json:"next_cursor"` // Pass as ?cursor= for the following page
+    PrevCursor  *string `json:"prev_cursor"`
     Expenses    []ExpenseResponse `json:"expenses"`
 }
 
 type CategorySummaryResponse struct {
     CategoryID    string `json:"category_id"`
+    ParentID      *string `json:"parent_id"`
     CategoryName  string `json:"category_name"`
     CategoryColor string `json:"category_color"`
-    TotalAmount   string `json:"total_amount"` // String from database
+    TotalAmount   money.Amount `json:"total_amount"` // Converted into Currency
+    Currency      string `json:"currency"`
+    ByCurrency    []CurrencyTotalResponse `json:"by_currency"`
+    Unconverted   []string `json:"unconverted_currencies"`
     ExpenseCount  int64  `json:"expense_count"`
+    // Set with ?rollup=true, where the fields above include subcategories
+    OwnTotalAmount  *money.Amount `json:"own_total_amount,omitempty"`
+    OwnExpenseCount *int64 `json:"own_expense_count,omitempty"`
+}
+
+type TagSummaryResponse struct {
+    TagID        string `json:"tag_id"`
+    TagName      string `json:"tag_name"`
+    TotalAmount  money.Amount `json:"total_amount"` // Converted into Currency
+    Currency     string `json:"currency"`
+    ByCurrency   []CurrencyTotalResponse `json:"by_currency"`
+    Unconverted  []string `json:"unconverted_currencies"`
+    ExpenseCount int64  `json:"expense_count"`
 }
 
 func (s *Service) HandleCreateExpense(w http.ResponseWriter, r *http.Request) {
@@ -58,51 +100,29 @@ func (s *Service) HandleCreateExpense(w http.ResponseWriter, r *http.Request) {
         utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
         return
     }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
     
     var req CreateExpenseRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        utils.RespondWithError(w, http.StatusBadRequest, invalidBodyMessage(err))
         return
     }
     
-    if err := utils.ValidateStruct(req); err != nil {
+    input, err := parseExpenseRequest(req, user.BaseCurrency)
+    if err != nil {
         utils.RespondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
     
-    if req.Amount <= 0 {
-        utils.RespondWithError(w, http.StatusBadRequest, "Amount must be greater than 0")
+    expense, err := s.CreateExpense(r.Context(), ledger.ID, user.ID, input.CategoryID, input.Amount, input.Currency, input.Description, input.Date, input.Tags)
+    if errors.Is(err, ErrCategoryNotFound) {
+        utils.RespondWithError(w, http.StatusBadRequest, "Category not found")
         return
     }
-    
-    // Convert float64 to string for database
-    amountStr := strconv.FormatFloat(req.Amount, 'f', 2, 64)
-    
-    // Parse date
-    var date time.Time
-    var err error
-    if req.Date != "" {
-        date, err = time.Parse("2006-01-02", req.Date)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
-            return
-        }
-    } else {
-        date = time.Now()
-    }
-    
-    // Parse category ID
-    var categoryID *uuid.UUID
-    if req.CategoryID != nil && *req.CategoryID != "" {
-        parsedID, err := uuid.Parse(*req.CategoryID)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
-            return
-        }
-        categoryID = &parsedID
-    }
-    
-    expense, err := s.CreateExpense(r.Context(), user.ID, categoryID, amountStr, req.Description, date)
     if err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create expense")
         return
@@ -111,10 +131,12 @@ func (s *Service) HandleCreateExpense(w http.ResponseWriter, r *http.Request) {
     response := ExpenseResponse{
         ID:          expense.ID.String(),
         Amount:      expense.Amount,
+        Currency:    expense.Currency,
         Description: expense.Description,
         Date:        expense.Date.Format("2006-01-02"),
         CreatedAt:   expense.CreatedAt.Format("2006-01-02T15:04:05Z"),
         UpdatedAt:   expense.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+        Tags:        expense.Tags,
     }
     
     if expense.CategoryID.Valid {
@@ -131,19 +153,22 @@ func (s *Service) HandleGetExpenses(w http.ResponseWriter, r *http.Request) {
         utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
         return
     }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
     
     // Parse query parameters
     limitStr := r.URL.Query().Get("limit")
     offsetStr := r.URL.Query().Get("offset")
-    startDateStr := r.URL.Query().Get("start_date")
-    endDateStr := r.URL.Query().Get("end_date")
     
     limit := int32(20) // default
     offset := int32(0) // default
     
     if limitStr != "" {
         if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-            limit = int32(l)
+            limit = int32(min(l, maxPageLimit))
         }
     }
     
@@ -153,103 +178,50 @@ func (s *Service) HandleGetExpenses(w http.ResponseWriter, r *http.Request) {
         }
     }
     
-    // If date range is provided, use date range query
-    if startDateStr != "" && endDateStr != "" {
-        startDate, err := time.Parse("2006-01-02", startDateStr)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid start_date format")
-            return
-        }
-        
-        endDate, err := time.Parse("2006-01-02", endDateStr)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid end_date format")
-            return
-        }
-        
-        expenses, err := s.GetExpensesByDateRange(r.Context(), user.ID, startDate, endDate)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expenses")
+    filter, err := parseExpenseFilter(r.URL.Query())
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    page := PageRequest{Limit: limit, Offset: offset}
+    if v := r.URL.Query().Get("cursor"); v != "" {
+        if page.Cursor, err = DecodeCursor(v); err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
             return
         }
-        
-        response := make([]ExpenseResponse, len(expenses))
-        
-        for i, exp := range expenses {
-            response[i] = ExpenseResponse{
-                ID:          exp.ID.String(),
-                Amount:      exp.Amount,
-                Description: exp.Description,
-                Date:        exp.Date.Format("2006-01-02"),
-                CreatedAt:   exp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-                UpdatedAt:   exp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-            }
-            
-            if exp.CategoryID.Valid {
-                categoryIDStr := exp.CategoryID.UUID.String()
-                response[i].CategoryID = &categoryIDStr
-            }
-            if exp.CategoryName.Valid {
-                categoryName := exp.CategoryName.String
-                response[i].CategoryName = &categoryName
-            }
-            if exp.CategoryColor.Valid {
-                categoryColor := exp.CategoryColor.String
-                response[i].CategoryColor = &categoryColor
-            }
-        }
-        
-        // Get total for date range
-        total, _ := s.GetExpenseTotalByDateRange(r.Context(), user.ID, startDate, endDate)
-        
-        utils.RespondWithJSON(w, http.StatusOK, ExpenseSummaryResponse{
-            Total:    total,
-            Count:    len(expenses),
-            Expenses: response,
-        })
-        return
     }
     
-    // Regular pagination query
-    expenses, err := s.GetUserExpenses(r.Context(), user.ID, limit, offset)
+    result, err := s.ListExpenses(r.Context(), ledger.ID, filter, page)
     if err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expenses")
         return
     }
     
-    response := make([]ExpenseResponse, len(expenses))
-    
-    for i, exp := range expenses {
-        response[i] = ExpenseResponse{
-            ID:          exp.ID.String(),
-            Amount:      exp.Amount,
-            Description: exp.Description,
-            Date:        exp.Date.Format("2006-01-02"),
-            CreatedAt:   exp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-            UpdatedAt:   exp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-        }
-        
-        if exp.CategoryID.Valid {
-            categoryIDStr := exp.CategoryID.UUID.String()
-            response[i].CategoryID = &categoryIDStr
-        }
-        if exp.CategoryName.Valid {
-            categoryName := exp.CategoryName.String
-            response[i].CategoryName = &categoryName
-        }
-        if exp.CategoryColor.Valid {
-            categoryColor := exp.CategoryColor.String
-            response[i].CategoryColor = &categoryColor
-        }
+    response := make([]ExpenseResponse, len(result.Expenses))
+    for i, exp := range result.Expenses {
+        response[i] = expenseRowResponse(exp)
     }
     
-    // Get total for user
-    total, _ := s.GetExpenseTotal(r.Context(), user.ID)
+    // Totals cover every matching expense, not just this page
+    totals, err := s.GetExpenseTotals(r.Context(), ledger.ID, user.BaseCurrency, filter)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expense total")
+        return
+    }
     
     utils.RespondWithJSON(w, http.StatusOK, ExpenseSummaryResponse{
-        Total:    total,
-        Count:    len(expenses),
-        Expenses: response,
+        Total:       totals.Total,
+        Currency:    totals.Currency,
+        ByCurrency:  currencyTotalResponses(totals.ByCurrency),
+        Unconverted: totals.Unconverted,
+        Count:       len(response),
+        TotalCount:  totals.ExpenseCount,
+        Limit:       limit,
+        Offset:      offset,
+        NextCursor:  encodeCursor(result.NextCursor),
+        PrevCursor:  encodeCursor(result.PrevCursor),
+        Expenses:    response,
     })
 }
 
@@ -259,6 +231,11 @@ func (s *Service) HandleUpdateExpense(w http.ResponseWriter, r *http.Request) {
         utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
         return
     }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
     
     vars := mux.Vars(r)
     expenseID, err := uuid.Parse(vars["id"])
@@ -269,47 +246,25 @@ func (s *Service) HandleUpdateExpense(w http.ResponseWriter, r *http.Request) {
     
     var req UpdateExpenseRequest
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        utils.RespondWithError(w, http.StatusBadRequest, invalidBodyMessage(err))
         return
     }
     
-    if err := utils.ValidateStruct(req); err != nil {
+    input, err := parseExpenseRequest(CreateExpenseRequest(req), user.BaseCurrency)
+    if err != nil {
         utils.RespondWithError(w, http.StatusBadRequest, err.Error())
         return
     }
     
-    if req.Amount <= 0 {
-        utils.RespondWithError(w, http.StatusBadRequest, "Amount must be greater than 0")
+    expense, err := s.UpdateExpense(r.Context(), expenseID, ledger.ID, user.ID, input.CategoryID, input.Amount, input.Currency, input.Description, input.Date, input.Tags)
+    if errors.Is(err, ErrCategoryNotFound) {
+        utils.RespondWithError(w, http.StatusBadRequest, "Category not found")
         return
     }
-    
-    // Convert float64 to string for database
-    amountStr := strconv.FormatFloat(req.Amount, 'f', 2, 64)
-    
-    // Parse date
-    var date time.Time
-    if req.Date != "" {
-        date, err = time.Parse("2006-01-02", req.Date)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
-            return
-        }
-    } else {
-        date = time.Now()
-    }
-    
-    // Parse category ID
-    var categoryID *uuid.UUID
-    if req.CategoryID != nil && *req.CategoryID != "" {
-        parsedID, err := uuid.Parse(*req.CategoryID)
-        if err != nil {
-            utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
-            return
-        }
-        categoryID = &parsedID
+    if errors.Is(err, splits.ErrSplitMismatch) {
+        utils.RespondWithError(w, http.StatusBadRequest, "The expense is split by exact amounts that no longer add up, update the split first")
+        return
     }
-    
-    expense, err := s.UpdateExpense(r.Context(), expenseID, user.ID, categoryID, amountStr, req.Description, date)
     if err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update expense")
         return
@@ -318,10 +273,12 @@ func (s *Service) HandleUpdateExpense(w http.ResponseWriter, r *http.Request) {
     response := ExpenseResponse{
         ID:          expense.ID.String(),
         Amount:      expense.Amount,
+        Currency:    expense.Currency,
         Description: expense.Description,
         Date:        expense.Date.Format("2006-01-02"),
         CreatedAt:   expense.CreatedAt.Format("2006-01-02T15:04:05Z"),
         UpdatedAt:   expense.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+        Tags:        expense.Tags,
     }
     
     if expense.CategoryID.Valid {
@@ -333,9 +290,9 @@ func (s *Service) HandleUpdateExpense(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) HandleDeleteExpense(w http.ResponseWriter, r *http.Request) {
-    user, ok := auth.GetUserFromContext(r.Context())
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
     if !ok {
-        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
         return
     }
     
@@ -346,7 +303,7 @@ func (s *Service) HandleDeleteExpense(w http.ResponseWriter, r *http.Request) {
         return
     }
     
-    if err := s.DeleteExpense(r.Context(), expenseID, user.ID); err != nil {
+    if err := s.DeleteExpense(r.Context(), expenseID, ledger.ID); err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete expense")
         return
     }
@@ -362,6 +319,11 @@ func (s *Service) HandleGetExpensesByCategory(w http.ResponseWriter, r *http.Req
         utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
         return
     }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
     
     startDateStr := r.URL.Query().Get("start_date")
     endDateStr := r.URL.Query().Get("end_date")
@@ -388,7 +350,24 @@ func (s *Service) HandleGetExpensesByCategory(w http.ResponseWriter, r *http.Req
         }
     }
     
-    categories, err := s.GetExpensesByCategory(r.Context(), user.ID, startDate, endDate)
+    if r.URL.Query().Get("rollup") == "true" {
+        categories, err := s.GetRolledUpExpensesByCategory(r.Context(), ledger.ID, user.BaseCurrency, startDate, endDate, false)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expenses by category")
+            return
+        }
+        
+        response := make([]CategorySummaryResponse, len(categories))
+        for i, cat := range categories {
+            response[i] = categorySummaryResponse(cat.CategoryTotals)
+            response[i].OwnTotalAmount = &cat.Own.Total
+            response[i].OwnExpenseCount = &cat.Own.ExpenseCount
+        }
+        utils.RespondWithJSON(w, http.StatusOK, response)
+        return
+    }
+    
+    categories, err := s.GetExpensesByCategory(r.Context(), ledger.ID, user.BaseCurrency, startDate, endDate, false)
     if err != nil {
         utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expenses by category")
         return
@@ -396,21 +375,309 @@ func (s *Service) HandleGetExpensesByCategory(w http.ResponseWriter, r *http.Req
     
     response := make([]CategorySummaryResponse, len(categories))
     for i, cat := range categories {
-        // Type assert TotalAmount to string
-        totalAmount, ok := cat.TotalAmount.(string)
-        if !ok {
-            utils.RespondWithError(w, http.StatusInternalServerError, "Invalid total amount format")
+        response[i] = categorySummaryResponse(cat)
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func categorySummaryResponse(cat CategoryTotals) CategorySummaryResponse {
+    response := CategorySummaryResponse{
+        CategoryID:    cat.CategoryID.String(),
+        CategoryName:  cat.CategoryName,
+        CategoryColor: cat.CategoryColor,
+        TotalAmount:   cat.Total,
+        Currency:      cat.Currency,
+        ByCurrency:    currencyTotalResponses(cat.ByCurrency),
+        Unconverted:   cat.Unconverted,
+        ExpenseCount:  cat.ExpenseCount,
+    }
+    if cat.ParentID != nil {
+        parentID := cat.ParentID.String()
+        response.ParentID = &parentID
+    }
+    return response
+}
+
+func (s *Service) HandleGetExpensesByTag(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    startDateStr := r.URL.Query().Get("start_date")
+    endDateStr := r.URL.Query().Get("end_date")
+    
+    var startDate, endDate time.Time
+    var err error
+    
+    if startDateStr == "" || endDateStr == "" {
+        // Default to current month
+        now := time.Now()
+        startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+        endDate = startDate.AddDate(0, 1, -1)
+    } else {
+        startDate, err = time.Parse("2006-01-02", startDateStr)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid start_date format")
             return
         }
         
-        response[i] = CategorySummaryResponse{
-            CategoryID:    cat.CategoryID.String(),
-            CategoryName:  cat.CategoryName,
-            CategoryColor: cat.CategoryColor,
-            TotalAmount:   totalAmount,
-            ExpenseCount:  cat.ExpenseCount,
+        endDate, err = time.Parse("2006-01-02", endDateStr)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid end_date format")
+            return
+        }
+    }
+    
+    tagTotals, err := s.GetExpensesByTag(r.Context(), ledger.ID, user.BaseCurrency, startDate, endDate)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get expenses by tag")
+        return
+    }
+    
+    response := make([]TagSummaryResponse, len(tagTotals))
+    for i, tag := range tagTotals {
+        response[i] = TagSummaryResponse{
+            TagID:        tag.TagID.String(),
+            TagName:      tag.TagName,
+            TotalAmount:  tag.Total,
+            Currency:     tag.Currency,
+            ByCurrency:   currencyTotalResponses(tag.ByCurrency),
+            Unconverted:  tag.Unconverted,
+            ExpenseCount: tag.ExpenseCount,
         }
     }
     
     utils.RespondWithJSON(w, http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// ExpenseInput is a create or update request that passed validation.
+type ExpenseInput struct {
+    CategoryID  *uuid.UUID
+    Amount      money.Amount
+    Currency    string
+    Description string
+    Date        time.Time
+    Tags        []string // nil when the request didn't mention tags
+}
+
+// parseExpenseRequest applies the validation rules shared by every way of
+// creating or editing an expense. Error messages are safe to show clients.
+func parseExpenseRequest(req CreateExpenseRequest, baseCurrency string) (*ExpenseInput, error) {
+    if err := utils.ValidateStruct(req); err != nil {
+        return nil, err
+    }
+    
+    if !req.Amount.IsPositive() {
+        return nil, errors.New("Amount must be greater than 0")
+    }
+    if !req.Amount.Storable() {
+        return nil, errors.New("Amount must be at most " + money.MaxStored.String())
+    }
+    
+    input := &ExpenseInput{
+        Amount:      req.Amount,
+        Currency:    baseCurrency,
+        Description: req.Description,
+        Date:        time.Now(),
+    }
+    
+    if req.Currency != "" {
+        currency, err := money.ParseCurrency(req.Currency)
+        if err != nil {
+            return nil, errors.New("Invalid currency, use a three-letter ISO 4217 code")
+        }
+        input.Currency = currency
+    }
+    
+    if req.Date != "" {
+        date, err := time.Parse("2006-01-02", req.Date)
+        if err != nil {
+            return nil, errors.New("Invalid date format, use YYYY-MM-DD")
+        }
+        input.Date = date
+    }
+    
+    if req.CategoryID != nil && *req.CategoryID != "" {
+        categoryID, err := uuid.Parse(*req.CategoryID)
+        if err != nil {
+            return nil, errors.New("Invalid category ID")
+        }
+        input.CategoryID = &categoryID
+    }
+    
+    tagNames, err := tags.Normalize(req.Tags)
+    if err != nil {
+        return nil, err
+    }
+    input.Tags = tagNames
+    
+    return input, nil
+}
+
+// invalidBodyMessage surfaces amount parse errors instead of a generic
+// "Invalid JSON" so clients know why a value was rejected.
+func invalidBodyMessage(err error) string {
+    if errors.Is(err, money.ErrInvalidAmount) || errors.Is(err, money.ErrTooPrecise) || errors.Is(err, money.ErrOverflow) {
+        return "Invalid amount: " + err.Error()
+    }
+    return "Invalid JSON"
+}
+
+func encodeCursor(c *Cursor) *string {
+    if c == nil {
+        return nil
+    }
+    encoded := c.Encode()
+    return &encoded
+}
+
+// expenseRowResponse renders a listed expense together with its category.
+func expenseRowResponse(exp database.GetExpensesByLedgerRow) ExpenseResponse {
+    response := ExpenseResponse{
+        ID:          exp.ID.String(),
+        Amount:      exp.Amount,
+        Currency:    exp.Currency,
+        Description: exp.Description,
+        Date:        exp.Date.Format("2006-01-02"),
+        CreatedAt:   exp.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        UpdatedAt:   exp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+        Tags:        exp.Tags,
+    }
+    
+    if exp.CategoryID.Valid {
+        categoryIDStr := exp.CategoryID.UUID.String()
+        response.CategoryID = &categoryIDStr
+    }
+    if exp.CategoryName.Valid {
+        categoryName := exp.CategoryName.String
+        response.CategoryName = &categoryName
+    }
+    if exp.CategoryColor.Valid {
+        categoryColor := exp.CategoryColor.String
+        response.CategoryColor = &categoryColor
+    }
+    if response.Tags == nil {
+        response.Tags = []string{}
+    }
+    return response
+}
+
+func currencyTotalResponses(totals []CurrencyTotal) []CurrencyTotalResponse {
+    response := make([]CurrencyTotalResponse, len(totals))
+    for i, t := range totals {
+        response[i] = CurrencyTotalResponse{
+            Currency:     t.Currency,
+            Total:        t.Total,
+            ExpenseCount: t.ExpenseCount,
+        }
+    }
+    return response
+}
+
+type ImportRowResponse struct {
+    Line        int               `json:"line"`
+    Valid       bool              `json:"valid"`
+    Values      map[string]string `json:"values"` // raw mapped cells
+    Date        string            `json:"date,omitempty"`
+    Amount      *money.Amount     `json:"amount,omitempty"`
+    Currency    string            `json:"currency,omitempty"`
+    Description string            `json:"description,omitempty"`
+    Category    string            `json:"category,omitempty"`
+    NewCategory bool              `json:"new_category"`
+    Errors      []string          `json:"errors"`
+}
+
+type ImportResponse struct {
+    DryRun        bool                `json:"dry_run"`
+    Committed     bool                `json:"committed"`
+    Imported      int                 `json:"imported"`
+    ValidRows     int                 `json:"valid_rows"`
+    InvalidRows   int                 `json:"invalid_rows"`
+    NewCategories []string            `json:"new_categories"`
+    Rows          []ImportRowResponse `json:"rows"`
+}
+
+// HandleImportExpenses accepts a multipart upload with a "file" part holding
+// the CSV and an "options" part holding ImportOptions as JSON.
+func (s *Service) HandleImportExpenses(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+    if err := r.ParseMultipartForm(maxImportSize); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Upload must be multipart/form-data and at most 5 MB")
+        return
+    }
+    
+    file, _, err := r.FormFile("file")
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Missing CSV file")
+        return
+    }
+    defer file.Close()
+    
+    var opts ImportOptions
+    if err := json.Unmarshal([]byte(r.FormValue("options")), &opts); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid options JSON")
+        return
+    }
+    
+    result, err := s.ImportExpenses(r.Context(), ledger.ID, user.ID, user.BaseCurrency, file, opts)
+    if err != nil {
+        if errors.Is(err, ErrInvalidImport) {
+            utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to import expenses")
+        return
+    }
+    
+    response := ImportResponse{
+        DryRun:        opts.DryRun,
+        Committed:     result.Committed,
+        Imported:      result.Imported,
+        ValidRows:     result.ValidRows,
+        InvalidRows:   result.InvalidRows,
+        NewCategories: result.NewCategories,
+        Rows:          make([]ImportRowResponse, len(result.Rows)),
+    }
+    for i, row := range result.Rows {
+        response.Rows[i] = ImportRowResponse{
+            Line:        row.Line,
+            Valid:       row.Input != nil,
+            Values:      row.Raw,
+            Category:    row.CategoryName,
+            NewCategory: row.NewCategory,
+            Errors:      row.Errors,
+        }
+        if row.Input != nil {
+            amount := row.Input.Amount
+            response.Rows[i].Amount = &amount
+            response.Rows[i].Date = row.Input.Date.Format("2006-01-02")
+            response.Rows[i].Currency = row.Input.Currency
+            response.Rows[i].Description = row.Input.Description
+        }
+    }
+    
+    status := http.StatusOK
+    if !opts.DryRun && !result.Committed {
+        status = http.StatusUnprocessableEntity
+    }
+    utils.RespondWithJSON(w, status, response)
+}
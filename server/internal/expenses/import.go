@@ -0,0 +1,416 @@
+package expenses
+
+import (
+    "context"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/google/uuid"
+)
+
+const (
+    maxImportSize = 5 << 20 // 5 MB
+    maxImportRows = 10000
+    importColor   = "#6B7280"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportMapping names the CSV column holding each field, by header name or,
+// for files without a header, by zero-based index.
+type ImportMapping struct {
+    Date        string `json:"date"`
+    Amount      string `json:"amount"`
+    Description string `json:"description"`
+    Category    string `json:"category"`
+    Currency    string `json:"currency"`
+}
+
+type ImportOptions struct {
+    Mapping          ImportMapping `json:"mapping"`
+    DateFormats      []string      `json:"date_formats"`      // e.g. "DD/MM/YYYY" or a Go layout, tried in order
+    DecimalSeparator string        `json:"decimal_separator"` // "." (default) or ","
+    Delimiter        string        `json:"delimiter"`         // "," (default), ";" or "\t"
+    HasHeader        *bool         `json:"has_header"`        // defaults to true
+    Currency         string        `json:"currency"`          // for rows without a currency column
+    CreateCategories bool          `json:"create_categories"`
+    DryRun           bool          `json:"dry_run"`
+}
+
+// ImportRow is one parsed CSV line. Input is nil when the row is invalid.
+type ImportRow struct {
+    Line         int
+    Raw          map[string]string
+    Input        *ExpenseInput
+    CategoryName string
+    NewCategory  bool
+    Errors       []string
+}
+
+type ImportResult struct {
+    Rows          []ImportRow
+    ValidRows     int
+    InvalidRows   int
+    NewCategories []string
+    Imported      int
+    Committed     bool
+}
+
+// ImportExpenses parses a CSV file into expenses. Unless opts.DryRun is set
+// and as long as every row is valid, all rows (and any new categories) are
+// written in one transaction; otherwise nothing is written.
+func (s *Service) ImportExpenses(ctx context.Context, ledgerID, userID uuid.UUID, baseCurrency string, file io.Reader, opts ImportOptions) (*ImportResult, error) {
+    result, err := s.previewImport(ctx, ledgerID, baseCurrency, file, opts)
+    if err != nil {
+        return nil, err
+    }
+    if opts.DryRun || result.InvalidRows > 0 || result.ValidRows == 0 {
+        return result, nil
+    }
+
+    if err := s.commitImport(ctx, ledgerID, userID, result); err != nil {
+        return nil, err
+    }
+    return result, nil
+}
+
+func (s *Service) previewImport(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, file io.Reader, opts ImportOptions) (*ImportResult, error) {
+    layouts, err := dateLayouts(opts.DateFormats)
+    if err != nil {
+        return nil, err
+    }
+
+    decimal := opts.DecimalSeparator
+    if decimal == "" {
+        decimal = "."
+    }
+    if decimal != "." && decimal != "," {
+        return nil, fmt.Errorf("%w: decimal_separator must be \".\" or \",\"", ErrInvalidImport)
+    }
+
+    defaultCurrency := baseCurrency
+    if opts.Currency != "" {
+        if defaultCurrency, err = money.ParseCurrency(opts.Currency); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+        }
+    }
+
+    reader := csv.NewReader(file)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+    switch opts.Delimiter {
+    case "", ",":
+    case ";":
+        reader.Comma = ';'
+    case "\t", "tab":
+        reader.Comma = '\t'
+    default:
+        return nil, fmt.Errorf("%w: delimiter must be \",\", \";\" or a tab", ErrInvalidImport)
+    }
+
+    hasHeader := opts.HasHeader == nil || *opts.HasHeader
+    var header []string
+    if hasHeader {
+        if header, err = reader.Read(); err != nil {
+            return nil, fmt.Errorf("%w: could not read CSV header: %v", ErrInvalidImport, err)
+        }
+    }
+
+    columns, err := resolveColumns(opts.Mapping, header)
+    if err != nil {
+        return nil, err
+    }
+
+    existing, err := s.queries.GetCategoriesByLedger(ctx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    categoryIDs := make(map[string]uuid.UUID, len(existing))
+    for _, c := range existing {
+        categoryIDs[strings.ToLower(c.Name)] = c.ID
+    }
+    newCategories := make(map[string]bool)
+
+    result := &ImportResult{Rows: []ImportRow{}, NewCategories: []string{}}
+    line := 1
+    if hasHeader {
+        line++
+    }
+    for ; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+        }
+        if isBlank(record) {
+            continue
+        }
+        if len(result.Rows) >= maxImportRows {
+            return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidImport, maxImportRows)
+        }
+
+        row := ImportRow{Line: line, Raw: make(map[string]string, len(columns)), Errors: []string{}}
+        for name, i := range columns {
+            row.Raw[name] = cell(record, i)
+        }
+
+        req := CreateExpenseRequest{
+            Description: row.Raw["description"],
+            Currency:    row.Raw["currency"],
+        }
+        if req.Currency == "" {
+            req.Currency = defaultCurrency
+        }
+
+        if amount, err := parseImportAmount(row.Raw["amount"], decimal); err != nil {
+            row.Errors = append(row.Errors, err.Error())
+        } else {
+            req.Amount = amount
+        }
+
+        if date, err := parseImportDate(row.Raw["date"], layouts); err != nil {
+            row.Errors = append(row.Errors, err.Error())
+        } else {
+            req.Date = date.Format("2006-01-02")
+        }
+
+        if name := row.Raw["category"]; name != "" {
+            row.CategoryName = name
+            key := strings.ToLower(name)
+            if id, ok := categoryIDs[key]; ok {
+                idStr := id.String()
+                req.CategoryID = &idStr
+            } else if opts.CreateCategories {
+                row.NewCategory = true
+                if !newCategories[key] {
+                    newCategories[key] = true
+                    result.NewCategories = append(result.NewCategories, name)
+                }
+            } else {
+                row.Errors = append(row.Errors, fmt.Sprintf("Unknown category %q", name))
+            }
+        }
+
+        if len(row.Errors) == 0 {
+            input, err := parseExpenseRequest(req, baseCurrency)
+            if err != nil {
+                row.Errors = append(row.Errors, err.Error())
+            } else {
+                row.Input = input
+            }
+        }
+
+        if row.Input != nil {
+            result.ValidRows++
+        } else {
+            result.InvalidRows++
+        }
+        result.Rows = append(result.Rows, row)
+    }
+
+    return result, nil
+}
+
+func (s *Service) commitImport(ctx context.Context, ledgerID, userID uuid.UUID, result *ImportResult) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    qtx := s.queries.WithTx(tx)
+
+    created := make(map[string]uuid.UUID, len(result.NewCategories))
+    for _, name := range result.NewCategories {
+        category, err := qtx.CreateCategory(ctx, database.CreateCategoryParams{
+            LedgerID: ledgerID,
+            UserID:   userID,
+            Name:     name,
+            Color:    importColor,
+        })
+        if err != nil {
+            return err
+        }
+        created[strings.ToLower(name)] = category.ID
+    }
+
+    for i := range result.Rows {
+        row := &result.Rows[i]
+        categoryID := nullUUID(row.Input.CategoryID)
+        if row.NewCategory {
+            categoryID = uuid.NullUUID{UUID: created[strings.ToLower(row.CategoryName)], Valid: true}
+        }
+
+        _, err := qtx.CreateExpense(ctx, database.CreateExpenseParams{
+            LedgerID:    ledgerID,
+            UserID:      userID,
+            CategoryID:  categoryID,
+            Amount:      row.Input.Amount,
+            Description: row.Input.Description,
+            Date:        row.Input.Date,
+            Currency:    row.Input.Currency,
+        })
+        if err != nil {
+            return err
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    result.Imported = len(result.Rows)
+    result.Committed = true
+    return nil
+}
+
+// resolveColumns maps each field to a column index.
+func resolveColumns(mapping ImportMapping, header []string) (map[string]int, error) {
+    fields := map[string]string{
+        "date":        mapping.Date,
+        "amount":      mapping.Amount,
+        "description": mapping.Description,
+        "category":    mapping.Category,
+        "currency":    mapping.Currency,
+    }
+    for _, required := range []string{"date", "amount", "description"} {
+        if fields[required] == "" {
+            return nil, fmt.Errorf("%w: mapping.%s is required", ErrInvalidImport, required)
+        }
+    }
+
+    byName := make(map[string]int, len(header))
+    for i, name := range header {
+        byName[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+
+    columns := make(map[string]int, len(fields))
+    for field, column := range fields {
+        if column == "" {
+            continue
+        }
+        if i, ok := byName[strings.ToLower(strings.TrimSpace(column))]; ok {
+            columns[field] = i
+            continue
+        }
+        if i, err := strconv.Atoi(column); err == nil && i >= 0 {
+            columns[field] = i
+            continue
+        }
+        return nil, fmt.Errorf("%w: column %q for %s not found", ErrInvalidImport, column, field)
+    }
+    return columns, nil
+}
+
+// dateLayouts turns patterns like "DD/MM/YYYY" into Go layouts. Patterns
+// that already look like Go layouts are used as they are.
+func dateLayouts(patterns []string) ([]string, error) {
+    if len(patterns) == 0 {
+        return []string{"2006-01-02"}, nil
+    }
+
+    tokens := []struct{ pattern, layout string }{
+        {"YYYY", "2006"}, {"YY", "06"},
+        {"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+        {"DD", "02"}, {"D", "2"},
+    }
+
+    layouts := make([]string, 0, len(patterns))
+    for _, pattern := range patterns {
+        if strings.Contains(pattern, "2006") || strings.Contains(pattern, "06") {
+            layouts = append(layouts, pattern)
+            continue
+        }
+
+        var layout strings.Builder
+        for rest := pattern; rest != ""; {
+            matched := false
+            for _, t := range tokens {
+                if strings.HasPrefix(rest, t.pattern) {
+                    layout.WriteString(t.layout)
+                    rest = rest[len(t.pattern):]
+                    matched = true
+                    break
+                }
+            }
+            if !matched {
+                layout.WriteByte(rest[0])
+                rest = rest[1:]
+            }
+        }
+        if !strings.Contains(layout.String(), "06") {
+            return nil, fmt.Errorf("%w: date format %q has no year", ErrInvalidImport, pattern)
+        }
+        layouts = append(layouts, layout.String())
+    }
+    return layouts, nil
+}
+
+func parseImportDate(value string, layouts []string) (time.Time, error) {
+    value = strings.TrimSpace(value)
+    if value == "" {
+        return time.Time{}, errors.New("Date is required")
+    }
+    for _, layout := range layouts {
+        if date, err := time.Parse(layout, value); err == nil {
+            return date, nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("Date %q does not match any of the configured formats", value)
+}
+
+// parseImportAmount normalizes spreadsheet-style numbers such as
+// "1.234,56" or "$1,234.56" before handing them to money.Parse.
+func parseImportAmount(value, decimal string) (money.Amount, error) {
+    thousands := ","
+    if decimal == "," {
+        thousands = "."
+    }
+
+    cleaned := strings.TrimSpace(value)
+    if cleaned == "" {
+        return 0, errors.New("Amount is required")
+    }
+    cleaned = strings.Trim(cleaned, "$€£¥  ")
+    cleaned = strings.NewReplacer(thousands, "", " ", "", " ", "", "'", "").Replace(cleaned)
+    if decimal == "," {
+        cleaned = strings.Replace(cleaned, ",", ".", 1)
+    }
+
+    amount, err := money.Parse(cleaned)
+    if err != nil {
+        return 0, fmt.Errorf("Invalid amount %q: %v", value, err)
+    }
+    return amount, nil
+}
+
+func cell(record []string, i int) string {
+    if i < len(record) {
+        return strings.TrimSpace(record[i])
+    }
+    return ""
+}
+
+func isBlank(record []string) bool {
+    for _, v := range record {
+        if strings.TrimSpace(v) != "" {
+            return false
+        }
+    }
+    return true
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+    if id == nil {
+        return uuid.NullUUID{}
+    }
+    return uuid.NullUUID{UUID: *id, Valid: true}
+}
@@ -0,0 +1,108 @@
+package expenses
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestDateLayouts(t *testing.T) {
+    tests := []struct {
+        name     string
+        patterns []string
+        want     []string
+        wantErr  error
+    }{
+        {"default", nil, []string{"2006-01-02"}, nil},
+        {"iso", []string{"YYYY-MM-DD"}, []string{"2006-01-02"}, nil},
+        {"day first", []string{"DD/MM/YYYY"}, []string{"02/01/2006"}, nil},
+        {"short", []string{"M/D/YY"}, []string{"1/2/06"}, nil},
+        {"month names", []string{"MMM D, YYYY", "D MMMM YYYY"}, []string{"Jan 2, 2006", "2 January 2006"}, nil},
+        {"go layout kept", []string{"02.01.2006", "Jan 06"}, []string{"02.01.2006", "Jan 06"}, nil},
+        {"mixed", []string{"DD.MM.YYYY", "2006-01-02"}, []string{"02.01.2006", "2006-01-02"}, nil},
+        {"no year", []string{"DD/MM"}, nil, ErrInvalidImport},
+        {"no year after a valid one", []string{"YYYY-MM-DD", "MM-DD"}, nil, ErrInvalidImport},
+    }
+
+    for _, tt := range tests {
+        got, err := dateLayouts(tt.patterns)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s: dateLayouts(%q) = %q, want %q", tt.name, tt.patterns, got, tt.want)
+        }
+    }
+}
+
+func TestParseImportDate(t *testing.T) {
+    dayFirst, err := dateLayouts([]string{"DD/MM/YYYY", "YYYY-MM-DD"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    monthFirst, err := dateLayouts([]string{"M/D/YY"})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name    string
+        value   string
+        layouts []string
+        want    time.Time
+        wantErr bool
+    }{
+        {"day first", "03/04/2024", dayFirst, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), false},
+        {"falls through to the next layout", "2024-04-03", dayFirst, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), false},
+        {"trimmed", " 03/04/2024 ", dayFirst, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), false},
+        {"month first", "4/3/24", monthFirst, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), false},
+        {"two-digit month first", "12/31/99", monthFirst, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), false},
+        {"impossible day", "31/02/2024", dayFirst, time.Time{}, true},
+        {"wrong order", "2024/04/03", dayFirst, time.Time{}, true},
+        {"empty", "", dayFirst, time.Time{}, true},
+    }
+
+    for _, tt := range tests {
+        got, err := parseImportDate(tt.value, tt.layouts)
+        if tt.wantErr != (err != nil) {
+            t.Errorf("%s: parseImportDate(%q) error = %v, want error %v", tt.name, tt.value, err, tt.wantErr)
+            continue
+        }
+        if !got.Equal(tt.want) {
+            t.Errorf("%s: parseImportDate(%q) = %s, want %s", tt.name, tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestParseImportAmount(t *testing.T) {
+    tests := []struct {
+        value   string
+        decimal string
+        want    int64
+        wantErr bool
+    }{
+        {"12.34", ".", 1234, false},
+        {"$1,234.56", ".", 123456, false},
+        {"-1,234.56", ".", -123456, false},
+        {"1.234,56", ",", 123456, false},
+        {"1 234,56 €", ",", 123456, false},
+        {"1'234.50", ".", 123450, false},
+        {"12,34", ".", 123400, false},
+        {"1.234", ".", 0, true},
+        {"", ".", 0, true},
+        {"abc", ".", 0, true},
+    }
+
+    for _, tt := range tests {
+        got, err := parseImportAmount(tt.value, tt.decimal)
+        if tt.wantErr != (err != nil) {
+            t.Errorf("parseImportAmount(%q, %q) error = %v, want error %v", tt.value, tt.decimal, err, tt.wantErr)
+            continue
+        }
+        if got.MinorUnits() != tt.want {
+            t.Errorf("parseImportAmount(%q, %q) = %d, want %d", tt.value, tt.decimal, got.MinorUnits(), tt.want)
+        }
+    }
+}
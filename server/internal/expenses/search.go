@@ -0,0 +1,236 @@
+package expenses
+
+import (
+    "context"
+    "errors"
+    "html"
+    "net/http"
+    "strconv"
+    "strings"
+    "unicode"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+)
+
+var ErrEmptySearch = errors.New("search query has no searchable words")
+
+// Markers SearchExpenses puts around highlighted words in snippets
+const (
+    highlightStart = "\uE000"
+    highlightStop  = "\uE001"
+)
+
+type SearchResult struct {
+    Expense database.GetExpensesByLedgerRow
+    Rank    float32
+    Snippet string // HTML-escaped, matches wrapped in <mark>
+}
+
+type SearchResultResponse struct {
+    ExpenseResponse
+    Rank    float32 `json:"rank"`
+    Snippet string  `json:"snippet"`
+}
+
+type SearchResponse struct {
+    Query      string `json:"query"`
+    Count      int    `json:"count"`
+    TotalCount int64  `json:"total_count"`
+    Limit      int32  `json:"limit"`
+    Offset     int32  `json:"offset"`
+    Results    []SearchResultResponse `json:"results"`
+}
+
+// SearchExpenses runs a full-text search over descriptions and category
+// names, best matches first. See buildTSQuery for the query syntax.
+func (s *Service) SearchExpenses(ctx context.Context, ledgerID uuid.UUID, query string, limit, offset int32) ([]SearchResult, int64, error) {
+    tsquery, err := buildTSQuery(query)
+    if err != nil {
+        return nil, 0, err
+    }
+    
+    rows, err := s.queries.SearchExpenses(ctx, database.SearchExpensesParams{
+        Query:      tsquery,
+        LedgerID:   ledgerID,
+        PageLimit:  limit,
+        PageOffset: offset,
+    })
+    if err != nil {
+        return nil, 0, err
+    }
+    
+    var total int64
+    results := make([]SearchResult, len(rows))
+    for i, row := range rows {
+        total = row.TotalCount
+        results[i] = SearchResult{
+            Expense: database.GetExpensesByLedgerRow{
+                ID:            row.ID,
+                UserID:        row.UserID,
+                CategoryID:    row.CategoryID,
+                Amount:        row.Amount,
+                Currency:      row.Currency,
+                Description:   row.Description,
+                Date:          row.Date,
+                CreatedAt:     row.CreatedAt,
+                UpdatedAt:     row.UpdatedAt,
+                CategoryName:  row.CategoryName,
+                CategoryColor: row.CategoryColor,
+                Tags:          row.Tags,
+            },
+            Rank:    row.Rank,
+            Snippet: highlightSnippet(row.Snippet),
+        }
+    }
+    return results, total, nil
+}
+
+// buildTSQuery turns a search box string into to_tsquery syntax. Words are
+// ANDed, "quoted text" is a phrase, a trailing * makes a prefix match, a
+// leading - excludes a word and OR between two terms makes either match.
+// Punctuation is dropped so user input can never break the tsquery parser.
+func buildTSQuery(input string) (string, error) {
+    var terms []string
+    var ops []string
+    pendingOr := false
+    
+    add := func(term string, negate bool) {
+        if term == "" {
+            return
+        }
+        if negate {
+            term = "!" + term
+        }
+        if len(terms) > 0 {
+            if pendingOr {
+                ops = append(ops, " | ")
+            } else {
+                ops = append(ops, " & ")
+            }
+        }
+        pendingOr = false
+        terms = append(terms, term)
+    }
+    
+    rest := strings.TrimSpace(input)
+    for rest != "" {
+        negate := false
+        if rest[0] == '-' {
+            negate = true
+            rest = rest[1:]
+        }
+        
+        if strings.HasPrefix(rest, `"`) {
+            phrase, after, _ := strings.Cut(rest[1:], `"`)
+            add(tsPhrase(strings.Fields(phrase)), negate)
+            rest = strings.TrimSpace(after)
+            continue
+        }
+        
+        word, after, _ := strings.Cut(rest, " ")
+        rest = strings.TrimSpace(after)
+        if word == "OR" && !negate {
+            pendingOr = len(terms) > 0
+            continue
+        }
+        add(tsPhrase([]string{word}), negate)
+    }
+    
+    if len(terms) == 0 {
+        return "", ErrEmptySearch
+    }
+    var b strings.Builder
+    for i, term := range terms {
+        if i > 0 {
+            b.WriteString(ops[i-1])
+        }
+        b.WriteString(term)
+    }
+    return b.String(), nil
+}
+
+// tsPhrase joins words as adjacent lexemes. Words containing punctuation
+// ("e-mail") split into their parts, and a trailing * on the last word
+// becomes a prefix match.
+func tsPhrase(words []string) string {
+    var lexemes []string
+    for i, word := range words {
+        prefix := i == len(words)-1 && strings.HasSuffix(word, "*")
+        parts := strings.FieldsFunc(word, func(r rune) bool {
+            return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+        })
+        for j, part := range parts {
+            lexeme := "'" + strings.ToLower(part) + "'"
+            if prefix && j == len(parts)-1 {
+                lexeme += ":*"
+            }
+            lexemes = append(lexemes, lexeme)
+        }
+    }
+    if len(lexemes) > 1 {
+        return "(" + strings.Join(lexemes, " <-> ") + ")"
+    }
+    return strings.Join(lexemes, "")
+}
+
+// highlightSnippet escapes the snippet and only then turns the highlight
+// markers into tags, so descriptions cannot inject markup.
+func highlightSnippet(snippet string) string {
+    snippet = html.EscapeString(snippet)
+    snippet = strings.ReplaceAll(snippet, highlightStart, "<mark>")
+    return strings.ReplaceAll(snippet, highlightStop, "</mark>")
+}
+
+func (s *Service) HandleSearchExpenses(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    query := strings.TrimSpace(r.URL.Query().Get("q"))
+    if query == "" {
+        utils.RespondWithError(w, http.StatusBadRequest, "q is required")
+        return
+    }
+    
+    limit := int32(20)
+    offset := int32(0)
+    if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+        limit = int32(min(l, maxPageLimit))
+    }
+    if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+        offset = int32(o)
+    }
+    
+    results, total, err := s.SearchExpenses(r.Context(), ledger.ID, query, limit, offset)
+    if err != nil {
+        if errors.Is(err, ErrEmptySearch) {
+            utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to search expenses")
+        return
+    }
+    
+    response := make([]SearchResultResponse, len(results))
+    for i, result := range results {
+        response[i] = SearchResultResponse{
+            ExpenseResponse: expenseRowResponse(result.Expense),
+            Rank:            result.Rank,
+            Snippet:         result.Snippet,
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, SearchResponse{
+        Query:      query,
+        Count:      len(response),
+        TotalCount: total,
+        Limit:      limit,
+        Offset:     offset,
+        Results:    response,
+    })
+}
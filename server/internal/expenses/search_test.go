@@ -0,0 +1,69 @@
+package expenses
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+        wantErr error
+    }{
+        {"coffee", "'coffee'", nil},
+        {"Coffee  Beans", "'coffee' & 'beans'", nil},
+        {`"iced coffee"`, "('iced' <-> 'coffee')", nil},
+        {`"iced coffee`, "('iced' <-> 'coffee')", nil},
+        {`-"iced coffee" tea`, "!('iced' <-> 'coffee') & 'tea'", nil},
+        {"caf*", "'caf':*", nil},
+        {"foo*bar*", "('foo' <-> 'bar':*)", nil},
+        {"-tea coffee", "!'tea' & 'coffee'", nil},
+        {"tea OR coffee", "'tea' | 'coffee'", nil},
+        {"tea OR -coffee milk", "'tea' | !'coffee' & 'milk'", nil},
+        {"tea OR OR coffee", "'tea' | 'coffee'", nil},
+        {"OR tea", "'tea'", nil},
+        {"tea OR", "'tea'", nil},
+        {"tea or coffee", "'tea' & 'or' & 'coffee'", nil},
+        {"-OR", "!'or'", nil},
+        {"e-mail", "('e' <-> 'mail')", nil},
+        {"it's", "('it' <-> 's')", nil},
+        {"café", "'café'", nil},
+        {"a & b | c:*", "'a' & 'b' & 'c':*", nil},
+        {"'); drop", "'drop'", nil},
+        {"", "", ErrEmptySearch},
+        {"   ", "", ErrEmptySearch},
+        {"-", "", ErrEmptySearch},
+        {"!!! ???", "", ErrEmptySearch},
+        {`""`, "", ErrEmptySearch},
+        {"OR", "", ErrEmptySearch},
+    }
+
+    for _, tt := range tests {
+        got, err := buildTSQuery(tt.in)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("buildTSQuery(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("buildTSQuery(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestHighlightSnippet(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        {"plain", "plain"},
+        {highlightStart + "coffee" + highlightStop + " beans", "<mark>coffee</mark> beans"},
+        {"<b>" + highlightStart + "x" + highlightStop + "</b>", "&lt;b&gt;<mark>x</mark>&lt;/b&gt;"},
+    }
+
+    for _, tt := range tests {
+        if got := highlightSnippet(tt.in); got != tt.want {
+            t.Errorf("highlightSnippet(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
@@ -2,121 +2,599 @@ package expenses
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/LuisBAndrade/etracker/internal/attachments"
 	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/rates"
+	"github.com/LuisBAndrade/etracker/internal/splits"
+	"github.com/LuisBAndrade/etracker/internal/tags"
 )
 
+// ErrCategoryNotFound means the category is missing or belongs to another
+// ledger.
+var ErrCategoryNotFound = errors.New("category not found")
+
 type Service struct {
-    queries *database.Queries
+    db          *sql.DB
+    queries     *database.Queries
+    rates       *rates.Service
+    attachments *attachments.Service
+}
+
+func NewService(db *sql.DB, queries *database.Queries, rates *rates.Service, attachments *attachments.Service) *Service {
+    return &Service{db: db, queries: queries, rates: rates, attachments: attachments}
 }
 
-func NewService(queries *database.Queries) *Service {
-    return &Service{queries: queries}
+// TaggedExpense is an expense together with its sorted tag names.
+type TaggedExpense struct {
+    database.Expense
+    Tags []string
 }
 
-func (s *Service) CreateExpense(ctx context.Context, userID uuid.UUID, categoryID *uuid.UUID, amount string, description string, date time.Time) (*database.Expense, error) {
-    var nullCategoryID uuid.NullUUID 
-    if categoryID != nil {
-        nullCategoryID = uuid.NullUUID{UUID: *categoryID, Valid: true}
+func (s *Service) CreateExpense(ctx context.Context, ledgerID, userID uuid.UUID, categoryID *uuid.UUID, amount money.Amount, currency string, description string, date time.Time, tagNames []string) (*TaggedExpense, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
     }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
     
-    expense, err := s.queries.CreateExpense(ctx, database.CreateExpenseParams{
+    if err := checkCategory(ctx, qtx, ledgerID, categoryID); err != nil {
+        return nil, err
+    }
+    
+    expense, err := qtx.CreateExpense(ctx, database.CreateExpenseParams{
+        LedgerID:    ledgerID,
         UserID:      userID,
-        CategoryID:  nullCategoryID, 
-        Amount:      amount,       
+        CategoryID:  nullUUID(categoryID),
+        Amount:      amount,
         Description: description,
         Date:        date,
+        Currency:    currency,
     })
-    return &expense, err
+    if err != nil {
+        return nil, err
+    }
+    
+    if tagNames == nil {
+        tagNames = []string{}
+    }
+    if err := tags.SetExpenseTags(ctx, qtx, ledgerID, userID, expense.ID, tagNames); err != nil {
+        return nil, err
+    }
+    
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &TaggedExpense{Expense: expense, Tags: tagNames}, nil
 }
 
-func (s *Service) GetUserExpenses(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]database.GetExpensesByUserRow, error) {
-    return s.queries.GetExpensesByUser(ctx, database.GetExpensesByUserParams{
-        UserID: userID,
-        Limit:  limit,
-        Offset: offset,
-    })
+const maxPageLimit = 100
+
+// PageRequest selects a page either by Cursor or, for older clients, by
+// Offset. Offset is ignored when a cursor is given.
+type PageRequest struct {
+    Limit  int32
+    Offset int32
+    Cursor *Cursor
 }
 
-func (s *Service) GetExpensesByDateRange(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]database.GetExpensesByUserAndDateRangeRow, error) {
-    return s.queries.GetExpensesByUserAndDateRange(ctx, database.GetExpensesByUserAndDateRangeParams{
-        UserID: userID,
-        Date:   startDate,
-        Date_2: endDate,
-    })
+// ExpensePage is one page of the expense list. The cursors are nil when
+// there is nothing further in that direction.
+type ExpensePage struct {
+    Expenses   []database.GetExpensesByLedgerRow
+    NextCursor *Cursor
+    PrevCursor *Cursor
 }
 
-func (s *Service) GetExpenseByID(ctx context.Context, expenseID, userID uuid.UUID) (*database.GetExpenseByIDRow, error) {
+// ListExpenses returns one page of the ledger's expenses matching filter,
+// newest first.
+func (s *Service) ListExpenses(ctx context.Context, ledgerID uuid.UUID, filter ExpenseFilter, page PageRequest) (*ExpensePage, error) {
+    if page.Limit <= 0 || page.Limit > maxPageLimit {
+        page.Limit = maxPageLimit
+    }
+    
+    p := filter.params(ledgerID)
+    params := database.GetExpensesByLedgerParams{
+        LedgerID:      p.LedgerID,
+        StartDate:     p.StartDate,
+        EndDate:       p.EndDate,
+        CategoryIds:   p.CategoryIds,
+        Uncategorized: p.Uncategorized,
+        HasMinAmount:  p.HasMinAmount,
+        MinAmount:     p.MinAmount,
+        HasMaxAmount:  p.HasMaxAmount,
+        MaxAmount:     p.MaxAmount,
+        Query:         p.Query,
+        Tags:          p.Tags,
+        PageLimit:     page.Limit + 1, // one extra row tells us whether there is more
+        PageOffset:    page.Offset,
+    }
+    
+    // Each direction has its own query with a fixed ORDER BY so both can
+    // walk the keyset index instead of sorting the ledger.
+    backward := page.Cursor != nil && page.Cursor.Backward
+    var rows []database.GetExpensesByLedgerRow
+    if backward {
+        backwardRows, err := s.queries.GetExpensesByLedgerBackward(ctx, database.GetExpensesByLedgerBackwardParams{
+            LedgerID:        p.LedgerID,
+            StartDate:       p.StartDate,
+            EndDate:         p.EndDate,
+            CategoryIds:     p.CategoryIds,
+            Uncategorized:   p.Uncategorized,
+            HasMinAmount:    p.HasMinAmount,
+            MinAmount:       p.MinAmount,
+            HasMaxAmount:    p.HasMaxAmount,
+            MaxAmount:       p.MaxAmount,
+            Query:           p.Query,
+            Tags:            p.Tags,
+            CursorDate:      page.Cursor.Date,
+            CursorCreatedAt: page.Cursor.CreatedAt,
+            CursorID:        page.Cursor.ID,
+            PageLimit:       page.Limit + 1,
+        })
+        if err != nil {
+            return nil, err
+        }
+        rows = make([]database.GetExpensesByLedgerRow, len(backwardRows))
+        for i, row := range backwardRows {
+            rows[i] = database.GetExpensesByLedgerRow(row)
+        }
+    } else {
+        if page.Cursor != nil {
+            params.CursorID = uuid.NullUUID{UUID: page.Cursor.ID, Valid: true}
+            params.CursorDate = sql.NullTime{Time: page.Cursor.Date, Valid: true}
+            params.CursorCreatedAt = sql.NullTime{Time: page.Cursor.CreatedAt, Valid: true}
+            params.PageOffset = 0
+        }
+        var err error
+        rows, err = s.queries.GetExpensesByLedger(ctx, params)
+        if err != nil {
+            return nil, err
+        }
+    }
+    
+    hasMore := len(rows) > int(page.Limit)
+    if hasMore {
+        rows = rows[:page.Limit]
+    }
+    result := &ExpensePage{Expenses: rows}
+    if len(rows) == 0 {
+        return result, nil
+    }
+    
+    if backward {
+        // Backward pages are fetched oldest first
+        for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+            rows[i], rows[j] = rows[j], rows[i]
+        }
+        next := cursorFor(rows[len(rows)-1], false)
+        result.NextCursor = &next
+        if hasMore {
+            prev := cursorFor(rows[0], true)
+            result.PrevCursor = &prev
+        }
+        return result, nil
+    }
+    
+    if hasMore {
+        next := cursorFor(rows[len(rows)-1], false)
+        result.NextCursor = &next
+    }
+    if page.Cursor != nil || page.Offset > 0 {
+        prev := cursorFor(rows[0], true)
+        result.PrevCursor = &prev
+    }
+    return result, nil
+}
+
+func (s *Service) GetExpenseByID(ctx context.Context, expenseID, ledgerID uuid.UUID) (*database.GetExpenseByIDRow, error) {
     expense, err := s.queries.GetExpenseByID(ctx, database.GetExpenseByIDParams{
-        ID:     expenseID,
-        UserID: userID,
+        ID:       expenseID,
+        LedgerID: ledgerID,
     })
     return &expense, err
 }
 
-func (s *Service) UpdateExpense(ctx context.Context, expenseID, userID uuid.UUID, categoryID *uuid.UUID, amount string, description string, date time.Time) (*database.Expense, error) {
-    var nullCategoryID uuid.NullUUID
-    if categoryID != nil {
-        nullCategoryID = uuid.NullUUID{UUID: *categoryID, Valid: true}
+// UpdateExpense replaces the expense's fields. Its tags are replaced too
+// unless tagNames is nil; new tags are recorded as created by userID.
+func (s *Service) UpdateExpense(ctx context.Context, expenseID, ledgerID, userID uuid.UUID, categoryID *uuid.UUID, amount money.Amount, currency string, description string, date time.Time, tagNames []string) (*TaggedExpense, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
     }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
     
-    expense, err := s.queries.UpdateExpense(ctx, database.UpdateExpenseParams{
+    if err := checkCategory(ctx, qtx, ledgerID, categoryID); err != nil {
+        return nil, err
+    }
+    
+    expense, err := qtx.UpdateExpense(ctx, database.UpdateExpenseParams{
         ID:          expenseID,
-        UserID:      userID,
+        LedgerID:    ledgerID,
         Amount:      amount,
         Description: description,
-        CategoryID:  nullCategoryID,
+        CategoryID:  nullUUID(categoryID),
         Date:        date,
+        Currency:    currency,
     })
-    return &expense, err
+    if err != nil {
+        return nil, err
+    }
+    
+    if err := splits.Rebalance(ctx, qtx, ledgerID, expense.ID, expense.Amount); err != nil {
+        return nil, err
+    }
+    
+    if tagNames != nil {
+        if err := tags.SetExpenseTags(ctx, qtx, ledgerID, userID, expense.ID, tagNames); err != nil {
+            return nil, err
+        }
+    } else {
+        current, err := qtx.GetExpenseByID(ctx, database.GetExpenseByIDParams{ID: expense.ID, LedgerID: ledgerID})
+        if err != nil {
+            return nil, err
+        }
+        tagNames = current.Tags
+    }
+    
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &TaggedExpense{Expense: expense, Tags: tagNames}, nil
 }
 
-func (s *Service) DeleteExpense(ctx context.Context, expenseID, userID uuid.UUID) error {
-    return s.queries.DeleteExpense(ctx, database.DeleteExpenseParams{
-        ID:     expenseID,
-        UserID: userID,
-    })
+// checkCategory makes sure an optional category belongs to the ledger.
+func checkCategory(ctx context.Context, queries *database.Queries, ledgerID uuid.UUID, categoryID *uuid.UUID) error {
+    if categoryID == nil {
+        return nil
+    }
+    _, err := queries.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: *categoryID, LedgerID: ledgerID})
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrCategoryNotFound
+    }
+    return err
 }
 
-func (s *Service) GetExpenseTotal(ctx context.Context, userID uuid.UUID) (string, error) {
-    result, err := s.queries.GetExpenseTotalByUser(ctx, userID)
+// DeleteExpense removes the expense and any attachment blobs that no other
+// expense still uses.
+func (s *Service) DeleteExpense(ctx context.Context, expenseID, ledgerID uuid.UUID) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    hashes, err := qtx.GetAttachmentHashesByExpense(ctx, expenseID)
     if err != nil {
-        return "", err
+        return err
     }
     
-    // Type assert to string
-    total, ok := result.(string)
-    if !ok {
-        return "0.00", nil // Return default value if assertion fails
+    if err := qtx.DeleteExpense(ctx, database.DeleteExpenseParams{
+        ID:       expenseID,
+        LedgerID: ledgerID,
+    }); err != nil {
+        return err
     }
     
-    return total, nil
+    unused, err := s.attachments.ReleaseBlobs(ctx, qtx, hashes)
+    if err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    s.attachments.DeleteBlobs(ctx, unused)
+    return nil
+}
+
+// CurrencyTotal is the unconverted spend in one currency.
+type CurrencyTotal struct {
+    Currency     string
+    Total        money.Amount
+    ExpenseCount int64
+}
+
+// Totals reports spend converted into a base currency alongside the
+// original per-currency amounts. Currencies without a usable exchange rate
+// are left out of Total and listed in Unconverted.
+type Totals struct {
+    Currency     string
+    Total        money.Amount
+    ExpenseCount int64
+    ByCurrency   []CurrencyTotal
+    Unconverted  []string
 }
 
-func (s *Service) GetExpenseTotalByDateRange(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) (string, error) {
-    result, err := s.queries.GetExpenseTotalByUserAndDateRange(ctx, database.GetExpenseTotalByUserAndDateRangeParams{
-        UserID: userID,
-        Date:   startDate,
-        Date_2: endDate,
+// CategoryTotals is one category's share of spend for a period.
+type CategoryTotals struct {
+    CategoryID    uuid.UUID
+    ParentID      *uuid.UUID
+    CategoryName  string
+    CategoryColor string
+    Totals
+}
+
+// RolledUpCategoryTotals holds a category's totals including all of its
+// subcategories, with the category's own spend kept separately in Own.
+type RolledUpCategoryTotals struct {
+    CategoryTotals
+    Own Totals
+}
+
+// TagTotals is one tag's share of spend for a period.
+type TagTotals struct {
+    TagID   uuid.UUID
+    TagName string
+    Totals
+}
+
+// totalGroup is the sum of one currency on one day, the finest grain at
+// which a single exchange rate applies.
+type totalGroup struct {
+    currency string
+    date     time.Time
+    total    money.Amount
+    count    int64
+}
+
+// GetExpenseTotals sums every expense matching filter, converted into
+// baseCurrency.
+func (s *Service) GetExpenseTotals(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, filter ExpenseFilter) (*Totals, error) {
+    rows, err := s.queries.GetExpenseTotalsByLedger(ctx, filter.params(ledgerID))
+    if err != nil {
+        return nil, err
+    }
+
+    groups := make([]totalGroup, len(rows))
+    for i, row := range rows {
+        groups[i] = totalGroup{currency: row.Currency, date: row.Date, total: row.Total, count: row.ExpenseCount}
+    }
+    return s.summarize(ctx, s.rates.NewConverter(), baseCurrency, groups)
+}
+
+// GetExpensesByCategory returns per-category totals converted into
+// baseCurrency, largest first. Categories without spend are included when
+// includeEmpty is set.
+func (s *Service) GetExpensesByCategory(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, startDate, endDate time.Time, includeEmpty bool) ([]CategoryTotals, error) {
+    rows, err := s.queries.GetExpensesByCategory(ctx, database.GetExpensesByCategoryParams{
+        LedgerID:     ledgerID,
+        StartDate:    startDate,
+        EndDate:      endDate,
+        IncludeEmpty: includeEmpty,
     })
     if err != nil {
-        return "", err
+        return nil, err
     }
-    
-    // Type assert to string
-    total, ok := result.(string)
-    if !ok {
-        return "0.00", nil // Return default value if assertion fails
+
+    var order []uuid.UUID
+    byCategory := make(map[uuid.UUID]*CategoryTotals)
+    groups := make(map[uuid.UUID][]totalGroup)
+    for _, row := range rows {
+        if _, ok := byCategory[row.CategoryID]; !ok {
+            order = append(order, row.CategoryID)
+            byCategory[row.CategoryID] = &CategoryTotals{
+                CategoryID:    row.CategoryID,
+                ParentID:      parentID(row.ParentID),
+                CategoryName:  row.CategoryName,
+                CategoryColor: row.CategoryColor,
+            }
+        }
+        // Empty categories come back as a single row with no currency
+        if row.Currency.Valid {
+            groups[row.CategoryID] = append(groups[row.CategoryID], totalGroup{
+                currency: row.Currency.String,
+                date:     row.Date.Time,
+                total:    row.TotalAmount,
+                count:    row.ExpenseCount,
+            })
+        }
     }
-    
-    return total, nil
+
+    converter := s.rates.NewConverter()
+    result := make([]CategoryTotals, 0, len(order))
+    for _, id := range order {
+        totals, err := s.summarize(ctx, converter, baseCurrency, groups[id])
+        if err != nil {
+            return nil, err
+        }
+        category := byCategory[id]
+        category.Totals = *totals
+        result = append(result, *category)
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i].Total > result[j].Total
+    })
+    return result, nil
+}
+
+// GetRolledUpExpensesByCategory is GetExpensesByCategory with every
+// category's totals including its subcategories. Parents appear even with
+// no direct spend when something below them has some.
+func (s *Service) GetRolledUpExpensesByCategory(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, startDate, endDate time.Time, includeEmpty bool) ([]RolledUpCategoryTotals, error) {
+    rows, err := s.queries.GetExpensesByCategory(ctx, database.GetExpensesByCategoryParams{
+        LedgerID:     ledgerID,
+        StartDate:    startDate,
+        EndDate:      endDate,
+        IncludeEmpty: true, // every ancestor is needed to roll up into
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    var order []uuid.UUID
+    byCategory := make(map[uuid.UUID]*CategoryTotals)
+    own := make(map[uuid.UUID][]totalGroup)
+    children := make(map[uuid.UUID][]uuid.UUID)
+    for _, row := range rows {
+        if _, ok := byCategory[row.CategoryID]; !ok {
+            order = append(order, row.CategoryID)
+            byCategory[row.CategoryID] = &CategoryTotals{
+                CategoryID:    row.CategoryID,
+                ParentID:      parentID(row.ParentID),
+                CategoryName:  row.CategoryName,
+                CategoryColor: row.CategoryColor,
+            }
+            if row.ParentID.Valid {
+                children[row.ParentID.UUID] = append(children[row.ParentID.UUID], row.CategoryID)
+            }
+        }
+        if row.Currency.Valid {
+            own[row.CategoryID] = append(own[row.CategoryID], totalGroup{
+                currency: row.Currency.String,
+                date:     row.Date.Time,
+                total:    row.TotalAmount,
+                count:    row.ExpenseCount,
+            })
+        }
+    }
+
+    // Collect each subtree's groups; visited guards against a corrupt tree
+    var subtree func(id uuid.UUID, visited map[uuid.UUID]bool) []totalGroup
+    subtree = func(id uuid.UUID, visited map[uuid.UUID]bool) []totalGroup {
+        if visited[id] {
+            return nil
+        }
+        visited[id] = true
+        groups := append([]totalGroup(nil), own[id]...)
+        for _, child := range children[id] {
+            groups = append(groups, subtree(child, visited)...)
+        }
+        return groups
+    }
+
+    converter := s.rates.NewConverter()
+    result := make([]RolledUpCategoryTotals, 0, len(order))
+    for _, id := range order {
+        rolled, err := s.summarize(ctx, converter, baseCurrency, subtree(id, make(map[uuid.UUID]bool)))
+        if err != nil {
+            return nil, err
+        }
+        if rolled.ExpenseCount == 0 && !includeEmpty {
+            continue
+        }
+        ownTotals, err := s.summarize(ctx, converter, baseCurrency, own[id])
+        if err != nil {
+            return nil, err
+        }
+        category := byCategory[id]
+        category.Totals = *rolled
+        result = append(result, RolledUpCategoryTotals{CategoryTotals: *category, Own: *ownTotals})
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i].Total > result[j].Total
+    })
+    return result, nil
+}
+
+func parentID(id uuid.NullUUID) *uuid.UUID {
+    if !id.Valid {
+        return nil
+    }
+    return &id.UUID
 }
-func (s *Service) GetExpensesByCategory(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]database.GetExpensesByCategoryRow, error) {
-    return s.queries.GetExpensesByCategory(ctx, database.GetExpensesByCategoryParams{
-        UserID: userID,
-        Date:   startDate,
-        Date_2: endDate,
+
+// GetExpensesByTag returns per-tag totals converted into baseCurrency,
+// largest first. Expenses with several tags count towards each one, so
+// the tag totals can add up to more than the overall total.
+func (s *Service) GetExpensesByTag(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, startDate, endDate time.Time) ([]TagTotals, error) {
+    rows, err := s.queries.GetExpensesByTag(ctx, database.GetExpensesByTagParams{
+        LedgerID:  ledgerID,
+        StartDate: startDate,
+        EndDate:   endDate,
     })
-}
\ No newline at end of file
+    if err != nil {
+        return nil, err
+    }
+
+    var order []uuid.UUID
+    byTag := make(map[uuid.UUID]*TagTotals)
+    groups := make(map[uuid.UUID][]totalGroup)
+    for _, row := range rows {
+        if _, ok := byTag[row.TagID]; !ok {
+            order = append(order, row.TagID)
+            byTag[row.TagID] = &TagTotals{TagID: row.TagID, TagName: row.TagName}
+        }
+        groups[row.TagID] = append(groups[row.TagID], totalGroup{
+            currency: row.Currency,
+            date:     row.Date,
+            total:    row.TotalAmount,
+            count:    row.ExpenseCount,
+        })
+    }
+
+    converter := s.rates.NewConverter()
+    result := make([]TagTotals, 0, len(order))
+    for _, id := range order {
+        totals, err := s.summarize(ctx, converter, baseCurrency, groups[id])
+        if err != nil {
+            return nil, err
+        }
+        tag := byTag[id]
+        tag.Totals = *totals
+        result = append(result, *tag)
+    }
+
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i].Total > result[j].Total
+    })
+    return result, nil
+}
+
+// summarize converts each currency/day group into baseCurrency and folds
+// the results into Totals.
+func (s *Service) summarize(ctx context.Context, converter *rates.Converter, baseCurrency string, groups []totalGroup) (*Totals, error) {
+    totals := &Totals{Currency: baseCurrency, ByCurrency: []CurrencyTotal{}, Unconverted: []string{}}
+    byCurrency := make(map[string]*CurrencyTotal)
+    unconverted := make(map[string]bool)
+
+    for _, g := range groups {
+        ct, ok := byCurrency[g.currency]
+        if !ok {
+            ct = &CurrencyTotal{Currency: g.currency}
+            byCurrency[g.currency] = ct
+        }
+
+        var err error
+        if ct.Total, err = ct.Total.Add(g.total); err != nil {
+            return nil, err
+        }
+        ct.ExpenseCount += g.count
+        totals.ExpenseCount += g.count
+
+        converted, err := converter.Convert(ctx, g.total, g.currency, baseCurrency, g.date)
+        if errors.Is(err, rates.ErrRateNotFound) {
+            unconverted[g.currency] = true
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        if totals.Total, err = totals.Total.Add(converted); err != nil {
+            return nil, err
+        }
+    }
+
+    for _, ct := range byCurrency {
+        totals.ByCurrency = append(totals.ByCurrency, *ct)
+    }
+    sort.Slice(totals.ByCurrency, func(i, j int) bool {
+        return totals.ByCurrency[i].Currency < totals.ByCurrency[j].Currency
+    })
+    for currency := range unconverted {
+        totals.Unconverted = append(totals.Unconverted, currency)
+    }
+    sort.Strings(totals.Unconverted)
+
+    return totals, nil
+}
@@ -0,0 +1,171 @@
+package income
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/rates"
+	"github.com/google/uuid"
+)
+
+const MaxCashFlowMonths = 60
+
+var ErrInvalidRange = errors.New("invalid month range")
+
+// MonthCashFlow is one month's income against spending in the base
+// currency. SavingsRate is the share of income not spent, as a percentage
+// to two decimals, and nil in months without income.
+type MonthCashFlow struct {
+    Month        time.Time
+    Income       money.Amount
+    Expenses     money.Amount
+    Net          money.Amount
+    SavingsRate  *float64
+    IncomeCount  int64
+    ExpenseCount int64
+}
+
+// CashFlow covers a run of months plus their totals. Currencies without a
+// usable exchange rate are left out of every amount and listed in
+// Unconverted.
+type CashFlow struct {
+    Currency    string
+    Months      []MonthCashFlow
+    Total       MonthCashFlow
+    Unconverted []string
+}
+
+// GetCashFlow reports income, spending, net and savings rate for every
+// month from first to last, inclusive, including months with no activity.
+// Settlements between ledger members are neither income nor spending.
+func (s *Service) GetCashFlow(ctx context.Context, ledgerID uuid.UUID, baseCurrency string, first, last time.Time) (*CashFlow, error) {
+    first = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+    last = time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)
+    if last.Before(first) {
+        return nil, ErrInvalidRange
+    }
+    months := (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+    if months > MaxCashFlowMonths {
+        return nil, ErrInvalidRange
+    }
+    endDate := last.AddDate(0, 1, -1)
+    
+    incomeRows, err := s.queries.GetIncomeTotalsByDay(ctx, database.GetIncomeTotalsByDayParams{
+        LedgerID:  ledgerID,
+        StartDate: first,
+        EndDate:   endDate,
+    })
+    if err != nil {
+        return nil, err
+    }
+    expenseRows, err := s.queries.GetExpenseTotalsByDay(ctx, database.GetExpenseTotalsByDayParams{
+        LedgerID:  ledgerID,
+        StartDate: first,
+        EndDate:   endDate,
+    })
+    if err != nil {
+        return nil, err
+    }
+    
+    flow := &CashFlow{Currency: baseCurrency, Months: make([]MonthCashFlow, months), Unconverted: []string{}}
+    for i := range flow.Months {
+        flow.Months[i].Month = first.AddDate(0, i, 0)
+    }
+    monthIndex := func(date time.Time) int {
+        return (date.Year()-first.Year())*12 + int(date.Month()-first.Month())
+    }
+    
+    converter := s.rates.NewConverter()
+    unconverted := make(map[string]bool)
+    // convert puts a day's total into the base currency at that day's
+    // rate. ok is false when there is no rate for the currency.
+    convert := func(total money.Amount, currency string, date time.Time) (money.Amount, bool, error) {
+        converted, err := converter.Convert(ctx, total, currency, baseCurrency, date)
+        if errors.Is(err, rates.ErrRateNotFound) {
+            unconverted[currency] = true
+            return 0, false, nil
+        }
+        return converted, err == nil, err
+    }
+    
+    for _, row := range incomeRows {
+        month := &flow.Months[monthIndex(row.Date)]
+        month.IncomeCount += row.IncomeCount
+        converted, ok, err := convert(row.Total, row.Currency, row.Date)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            if month.Income, err = month.Income.Add(converted); err != nil {
+                return nil, err
+            }
+        }
+    }
+    for _, row := range expenseRows {
+        month := &flow.Months[monthIndex(row.Date)]
+        month.ExpenseCount += row.ExpenseCount
+        converted, ok, err := convert(row.Total, row.Currency, row.Date)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            if month.Expenses, err = month.Expenses.Add(converted); err != nil {
+                return nil, err
+            }
+        }
+    }
+    
+    for i := range flow.Months {
+        month := &flow.Months[i]
+        if err := month.settle(); err != nil {
+            return nil, err
+        }
+        if flow.Total.Income, err = flow.Total.Income.Add(month.Income); err != nil {
+            return nil, err
+        }
+        if flow.Total.Expenses, err = flow.Total.Expenses.Add(month.Expenses); err != nil {
+            return nil, err
+        }
+        flow.Total.IncomeCount += month.IncomeCount
+        flow.Total.ExpenseCount += month.ExpenseCount
+    }
+    if err := flow.Total.settle(); err != nil {
+        return nil, err
+    }
+    
+    for currency := range unconverted {
+        flow.Unconverted = append(flow.Unconverted, currency)
+    }
+    sort.Strings(flow.Unconverted)
+    return flow, nil
+}
+
+// settle fills in Net and SavingsRate from Income and Expenses.
+func (m *MonthCashFlow) settle() error {
+    net, err := m.Income.Sub(m.Expenses)
+    if err != nil {
+        return err
+    }
+    m.Net = net
+    m.SavingsRate = savingsRate(net, m.Income)
+    return nil
+}
+
+// savingsRate returns net as a percentage of income, rounded half away
+// from zero to two decimals.
+func savingsRate(net, income money.Amount) *float64 {
+    if !income.IsPositive() {
+        return nil
+    }
+    n, d := net.MinorUnits()*10000, income.MinorUnits()
+    basisPoints := (n + d/2) / d
+    if n < 0 {
+        basisPoints = (n - d/2) / d
+    }
+    rate := float64(basisPoints) / 100
+    return &rate
+}
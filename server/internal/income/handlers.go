@@ -0,0 +1,481 @@
+package income
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LuisBAndrade/etracker/internal/auth"
+	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type IncomeCategoryRequest struct {
+    Name  string `json:"name" validate:"required"`
+    Color string `json:"color"`
+}
+
+type IncomeCategoryResponse struct {
+    ID        string `json:"id"`
+    Name      string `json:"name"`
+    Color     string `json:"color"`
+    CreatedAt string `json:"created_at"`
+}
+
+type IncomeRequest struct {
+    CategoryID  *string      `json:"category_id"` // an income category
+    Amount      money.Amount `json:"amount" validate:"required"` // "12.34" or integer cents
+    Currency    string       `json:"currency"` // ISO 4217, defaults to the user's base currency
+    Description string       `json:"description" validate:"required"`
+    Date        string       `json:"date"` // YYYY-MM-DD, defaults to today
+}
+
+type IncomeResponse struct {
+    ID            string       `json:"id"`
+    CategoryID    *string      `json:"category_id"`
+    CategoryName  *string      `json:"category_name,omitempty"`
+    CategoryColor *string      `json:"category_color,omitempty"`
+    Amount        money.Amount `json:"amount"`
+    Currency      string       `json:"currency"`
+    Description   string       `json:"description"`
+    Date          string       `json:"date"`
+    CreatedAt     string       `json:"created_at"`
+    UpdatedAt     string       `json:"updated_at"`
+}
+
+type MonthCashFlowResponse struct {
+    Month        string       `json:"month,omitempty"`
+    Income       money.Amount `json:"income"`
+    Expenses     money.Amount `json:"expenses"`
+    Net          money.Amount `json:"net"`
+    SavingsRate  *float64     `json:"savings_rate"` // percent of income not spent, null without income
+    IncomeCount  int64        `json:"income_count"`
+    ExpenseCount int64        `json:"expense_count"`
+}
+
+type CashFlowResponse struct {
+    Currency    string                  `json:"currency"`
+    Months      []MonthCashFlowResponse `json:"months"`
+    Total       MonthCashFlowResponse   `json:"total"`
+    Unconverted []string                `json:"unconverted_currencies"`
+}
+
+type incomeInput struct {
+    CategoryID  *uuid.UUID
+    Amount      money.Amount
+    Currency    string
+    Description string
+    Date        time.Time
+}
+
+// parseIncomeRequest applies the same rules as expenses. Error messages
+// are safe to show clients.
+func parseIncomeRequest(req IncomeRequest, baseCurrency string) (*incomeInput, error) {
+    if err := utils.ValidateStruct(req); err != nil {
+        return nil, err
+    }
+    
+    if !req.Amount.IsPositive() {
+        return nil, errors.New("Amount must be greater than 0")
+    }
+    if !req.Amount.Storable() {
+        return nil, errors.New("Amount must be at most " + money.MaxStored.String())
+    }
+    
+    input := &incomeInput{
+        Amount:      req.Amount,
+        Currency:    baseCurrency,
+        Description: req.Description,
+        Date:        time.Now(),
+    }
+    
+    if req.Currency != "" {
+        currency, err := money.ParseCurrency(req.Currency)
+        if err != nil {
+            return nil, errors.New("Invalid currency, use a three-letter ISO 4217 code")
+        }
+        input.Currency = currency
+    }
+    
+    if req.Date != "" {
+        date, err := time.Parse("2006-01-02", req.Date)
+        if err != nil {
+            return nil, errors.New("Invalid date format, use YYYY-MM-DD")
+        }
+        input.Date = date
+    }
+    
+    if req.CategoryID != nil && *req.CategoryID != "" {
+        categoryID, err := uuid.Parse(*req.CategoryID)
+        if err != nil {
+            return nil, errors.New("Invalid category ID")
+        }
+        input.CategoryID = &categoryID
+    }
+    
+    return input, nil
+}
+
+func respondWithIncomeError(w http.ResponseWriter, err error, message string) {
+    switch {
+    case errors.Is(err, ErrIncomeNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Income not found")
+    case errors.Is(err, ErrCategoryNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Income category not found")
+    case errors.Is(err, ErrCategoryExists):
+        utils.RespondWithError(w, http.StatusConflict, "An income category with that name already exists")
+    case errors.Is(err, ErrInvalidRange):
+        utils.RespondWithError(w, http.StatusBadRequest, "from must not be after to, and the range can cover at most 60 months")
+    default:
+        utils.RespondWithError(w, http.StatusInternalServerError, message)
+    }
+}
+
+func categoryResponse(category database.IncomeCategory) IncomeCategoryResponse {
+    return IncomeCategoryResponse{
+        ID:        category.ID.String(),
+        Name:      category.Name,
+        Color:     category.Color,
+        CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+}
+
+func incomeResponse(income database.Income) IncomeResponse {
+    response := IncomeResponse{
+        ID:          income.ID.String(),
+        Amount:      income.Amount,
+        Currency:    income.Currency,
+        Description: income.Description,
+        Date:        income.Date.Format("2006-01-02"),
+        CreatedAt:   income.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        UpdatedAt:   income.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+    if income.CategoryID.Valid {
+        categoryID := income.CategoryID.UUID.String()
+        response.CategoryID = &categoryID
+    }
+    return response
+}
+
+func monthResponse(m MonthCashFlow, month string) MonthCashFlowResponse {
+    return MonthCashFlowResponse{
+        Month:        month,
+        Income:       m.Income,
+        Expenses:     m.Expenses,
+        Net:          m.Net,
+        SavingsRate:  m.SavingsRate,
+        IncomeCount:  m.IncomeCount,
+        ExpenseCount: m.ExpenseCount,
+    }
+}
+
+func (s *Service) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    var req IncomeCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    category, err := s.CreateCategory(r.Context(), ledger.ID, user.ID, req.Name, req.Color)
+    if err != nil {
+        respondWithIncomeError(w, err, "Failed to create income category")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusCreated, categoryResponse(*category))
+}
+
+func (s *Service) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    categories, err := s.GetCategories(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get income categories")
+        return
+    }
+    
+    response := make([]IncomeCategoryResponse, len(categories))
+    for i, category := range categories {
+        response[i] = categoryResponse(category)
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    categoryID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
+        return
+    }
+    
+    var req IncomeCategoryRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    category, err := s.UpdateCategory(r.Context(), categoryID, ledger.ID, req.Name, req.Color)
+    if err != nil {
+        respondWithIncomeError(w, err, "Failed to update income category")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, categoryResponse(*category))
+}
+
+func (s *Service) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    categoryID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid category ID")
+        return
+    }
+    
+    if err := s.DeleteCategory(r.Context(), categoryID, ledger.ID); err != nil {
+        respondWithIncomeError(w, err, "Failed to delete income category")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Income category deleted"})
+}
+
+func (s *Service) HandleCreateIncome(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    var req IncomeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    input, err := parseIncomeRequest(req, user.BaseCurrency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    income, err := s.CreateIncome(r.Context(), ledger.ID, user.ID, input.CategoryID, input.Amount, input.Currency, input.Description, input.Date)
+    if err != nil {
+        respondWithIncomeError(w, err, "Failed to record income")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusCreated, incomeResponse(*income))
+}
+
+func (s *Service) HandleGetIncomes(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    var startDate, endDate *time.Time
+    if v := r.URL.Query().Get("start_date"); v != "" {
+        date, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid start_date format")
+            return
+        }
+        startDate = &date
+    }
+    if v := r.URL.Query().Get("end_date"); v != "" {
+        date, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid end_date format")
+            return
+        }
+        endDate = &date
+    }
+    
+    incomes, err := s.GetIncomes(r.Context(), ledger.ID, startDate, endDate)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get income")
+        return
+    }
+    
+    response := make([]IncomeResponse, len(incomes))
+    for i, row := range incomes {
+        response[i] = incomeResponse(database.Income{
+            ID:          row.ID,
+            UserID:      row.UserID,
+            CategoryID:  row.CategoryID,
+            Amount:      row.Amount,
+            Currency:    row.Currency,
+            Description: row.Description,
+            Date:        row.Date,
+            CreatedAt:   row.CreatedAt,
+            UpdatedAt:   row.UpdatedAt,
+        })
+        if row.CategoryName.Valid {
+            response[i].CategoryName = &row.CategoryName.String
+            response[i].CategoryColor = &row.CategoryColor.String
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleUpdateIncome(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    incomeID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid income ID")
+        return
+    }
+    
+    var req IncomeRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    input, err := parseIncomeRequest(req, user.BaseCurrency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    income, err := s.UpdateIncome(r.Context(), incomeID, ledger.ID, input.CategoryID, input.Amount, input.Currency, input.Description, input.Date)
+    if err != nil {
+        respondWithIncomeError(w, err, "Failed to update income")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, incomeResponse(*income))
+}
+
+func (s *Service) HandleDeleteIncome(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    incomeID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid income ID")
+        return
+    }
+    
+    if err := s.DeleteIncome(r.Context(), incomeID, ledger.ID); err != nil {
+        respondWithIncomeError(w, err, "Failed to delete income")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Income deleted"})
+}
+
+// HandleGetCashFlow reports month by month from the from month to the to
+// month (YYYY-MM), defaulting to the last twelve months.
+func (s *Service) HandleGetCashFlow(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    now := time.Now()
+    last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+    if m := r.URL.Query().Get("to"); m != "" {
+        parsed, err := time.Parse("2006-01", m)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid to month, use YYYY-MM")
+            return
+        }
+        last = parsed
+    }
+    first := last.AddDate(0, -11, 0)
+    if m := r.URL.Query().Get("from"); m != "" {
+        parsed, err := time.Parse("2006-01", m)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid from month, use YYYY-MM")
+            return
+        }
+        first = parsed
+    }
+    
+    flow, err := s.GetCashFlow(r.Context(), ledger.ID, user.BaseCurrency, first, last)
+    if err != nil {
+        respondWithIncomeError(w, err, "Failed to get cash flow")
+        return
+    }
+    
+    response := CashFlowResponse{
+        Currency:    flow.Currency,
+        Months:      make([]MonthCashFlowResponse, len(flow.Months)),
+        Total:       monthResponse(flow.Total, ""),
+        Unconverted: flow.Unconverted,
+    }
+    for i, m := range flow.Months {
+        response.Months[i] = monthResponse(m, m.Month.Format("2006-01"))
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,192 @@
+package income
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/rates"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const defaultCategoryColor = "#10B981"
+
+var (
+    ErrIncomeNotFound   = errors.New("income not found")
+    ErrCategoryNotFound = errors.New("income category not found")
+    ErrCategoryExists   = errors.New("an income category with that name already exists")
+)
+
+type Service struct {
+    queries *database.Queries
+    rates   *rates.Service
+}
+
+func NewService(queries *database.Queries, rates *rates.Service) *Service {
+    return &Service{queries: queries, rates: rates}
+}
+
+func (s *Service) CreateCategory(ctx context.Context, ledgerID, userID uuid.UUID, name, color string) (*database.IncomeCategory, error) {
+    if color == "" {
+        color = defaultCategoryColor
+    }
+    
+    category, err := s.queries.CreateIncomeCategory(ctx, database.CreateIncomeCategoryParams{
+        LedgerID: ledgerID,
+        UserID:   userID,
+        Name:     name,
+        Color:    color,
+    })
+    if err != nil {
+        return nil, categoryError(err)
+    }
+    return &category, nil
+}
+
+func (s *Service) GetCategories(ctx context.Context, ledgerID uuid.UUID) ([]database.IncomeCategory, error) {
+    return s.queries.GetIncomeCategoriesByLedger(ctx, ledgerID)
+}
+
+func (s *Service) UpdateCategory(ctx context.Context, categoryID, ledgerID uuid.UUID, name, color string) (*database.IncomeCategory, error) {
+    if color == "" {
+        color = defaultCategoryColor
+    }
+    
+    category, err := s.queries.UpdateIncomeCategory(ctx, database.UpdateIncomeCategoryParams{
+        ID:       categoryID,
+        LedgerID: ledgerID,
+        Name:     name,
+        Color:    color,
+    })
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrCategoryNotFound
+        }
+        return nil, categoryError(err)
+    }
+    return &category, nil
+}
+
+// DeleteCategory removes the category. Income filed under it is kept and
+// becomes uncategorized.
+func (s *Service) DeleteCategory(ctx context.Context, categoryID, ledgerID uuid.UUID) error {
+    deleted, err := s.queries.DeleteIncomeCategory(ctx, database.DeleteIncomeCategoryParams{
+        ID:       categoryID,
+        LedgerID: ledgerID,
+    })
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrCategoryNotFound
+    }
+    return nil
+}
+
+func categoryError(err error) error {
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+        return ErrCategoryExists
+    }
+    return err
+}
+
+// checkCategory makes sure an income category belongs to the ledger.
+func (s *Service) checkCategory(ctx context.Context, ledgerID uuid.UUID, categoryID *uuid.UUID) error {
+    if categoryID == nil {
+        return nil
+    }
+    _, err := s.queries.GetIncomeCategoryByID(ctx, database.GetIncomeCategoryByIDParams{
+        ID:       *categoryID,
+        LedgerID: ledgerID,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrCategoryNotFound
+    }
+    return err
+}
+
+func (s *Service) CreateIncome(ctx context.Context, ledgerID, userID uuid.UUID, categoryID *uuid.UUID, amount money.Amount, currency, description string, date time.Time) (*database.Income, error) {
+    if err := s.checkCategory(ctx, ledgerID, categoryID); err != nil {
+        return nil, err
+    }
+    
+    income, err := s.queries.CreateIncome(ctx, database.CreateIncomeParams{
+        LedgerID:    ledgerID,
+        UserID:      userID,
+        CategoryID:  nullUUID(categoryID),
+        Amount:      amount,
+        Currency:    currency,
+        Description: description,
+        Date:        date,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &income, nil
+}
+
+// GetIncomes lists income newest first. Nil dates leave that end of the
+// range open.
+func (s *Service) GetIncomes(ctx context.Context, ledgerID uuid.UUID, startDate, endDate *time.Time) ([]database.GetIncomesByLedgerRow, error) {
+    return s.queries.GetIncomesByLedger(ctx, database.GetIncomesByLedgerParams{
+        LedgerID:  ledgerID,
+        StartDate: nullTime(startDate),
+        EndDate:   nullTime(endDate),
+    })
+}
+
+func (s *Service) UpdateIncome(ctx context.Context, incomeID, ledgerID uuid.UUID, categoryID *uuid.UUID, amount money.Amount, currency, description string, date time.Time) (*database.Income, error) {
+    if err := s.checkCategory(ctx, ledgerID, categoryID); err != nil {
+        return nil, err
+    }
+    
+    income, err := s.queries.UpdateIncome(ctx, database.UpdateIncomeParams{
+        ID:          incomeID,
+        LedgerID:    ledgerID,
+        CategoryID:  nullUUID(categoryID),
+        Amount:      amount,
+        Currency:    currency,
+        Description: description,
+        Date:        date,
+    })
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrIncomeNotFound
+        }
+        return nil, err
+    }
+    return &income, nil
+}
+
+func (s *Service) DeleteIncome(ctx context.Context, incomeID, ledgerID uuid.UUID) error {
+    deleted, err := s.queries.DeleteIncome(ctx, database.DeleteIncomeParams{
+        ID:       incomeID,
+        LedgerID: ledgerID,
+    })
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrIncomeNotFound
+    }
+    return nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+    if id == nil {
+        return uuid.NullUUID{}
+    }
+    return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+    if t == nil {
+        return sql.NullTime{}
+    }
+    return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,522 @@
+package ledgers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+type LedgerRequest struct {
+    Name string `json:"name" validate:"required"`
+}
+
+type MemberRoleRequest struct {
+    Role string `json:"role" validate:"required"` // owner, editor or viewer
+}
+
+type InvitationRequest struct {
+    Email string `json:"email" validate:"required,email"`
+    Role  string `json:"role"` // editor or viewer, defaults to editor
+}
+
+type LedgerResponse struct {
+    ID          string    `json:"id"`
+    Name        string    `json:"name"`
+    Role        string    `json:"role"`
+    MemberCount int64     `json:"member_count"`
+    Active      bool      `json:"active"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type MemberResponse struct {
+    UserID   string    `json:"user_id"`
+    Email    string    `json:"email"`
+    Role     string    `json:"role"`
+    JoinedAt time.Time `json:"joined_at"`
+}
+
+type InvitationResponse struct {
+    ID        string    `json:"id"`
+    LedgerID  string    `json:"ledger_id"`
+    Email     string    `json:"email"`
+    Role      string    `json:"role"`
+    ExpiresAt time.Time `json:"expires_at"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// UserInvitationResponse is an invitation as its recipient sees it.
+type UserInvitationResponse struct {
+    ID         string    `json:"id"`
+    LedgerID   string    `json:"ledger_id"`
+    LedgerName string    `json:"ledger_name"`
+    Role       string    `json:"role"`
+    InvitedBy  string    `json:"invited_by"`
+    ExpiresAt  time.Time `json:"expires_at"`
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+func invitationResponse(i database.LedgerInvitation) InvitationResponse {
+    return InvitationResponse{
+        ID:        i.ID.String(),
+        LedgerID:  i.LedgerID.String(),
+        Email:     i.Email,
+        Role:      i.Role,
+        ExpiresAt: i.ExpiresAt,
+        CreatedAt: i.CreatedAt,
+    }
+}
+
+func respondWithLedgerError(w http.ResponseWriter, err error, message string) {
+    switch {
+    case errors.Is(err, ErrLedgerNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Ledger not found")
+    case errors.Is(err, ErrNotOwner):
+        utils.RespondWithError(w, http.StatusForbidden, "Only owners can manage this ledger")
+    case errors.Is(err, ErrLastLedger):
+        utils.RespondWithError(w, http.StatusConflict, "You can't leave or delete your only ledger")
+    case errors.Is(err, ErrLastOwner):
+        utils.RespondWithError(w, http.StatusConflict, "A ledger needs at least one owner, promote someone else first")
+    case errors.Is(err, ErrMemberNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Member not found")
+    case errors.Is(err, ErrAlreadyMember):
+        utils.RespondWithError(w, http.StatusConflict, "Already a member of this ledger")
+    case errors.Is(err, ErrInvalidRole):
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid role")
+    case errors.Is(err, ErrInvitationNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Invitation not found")
+    case errors.Is(err, ErrEmailNotVerified):
+        utils.RespondWithError(w, http.StatusForbidden, "Verify your email address to see and accept invitations")
+    default:
+        utils.RespondWithError(w, http.StatusInternalServerError, message)
+    }
+}
+
+// pathID parses a UUID route variable.
+func pathID(r *http.Request, name string) (uuid.UUID, bool) {
+    id, err := uuid.Parse(mux.Vars(r)[name])
+    return id, err == nil
+}
+
+func (s *Service) HandleGetLedgers(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgers, err := s.GetUserLedgers(r.Context(), user.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get ledgers")
+        return
+    }
+
+    // The ledger this request resolved to, which is the active one unless
+    // X-Ledger-ID says otherwise
+    current, _ := auth.GetLedgerFromContext(r.Context())
+    response := make([]LedgerResponse, len(ledgers))
+    for i, l := range ledgers {
+        response[i] = LedgerResponse{
+            ID:          l.ID.String(),
+            Name:        l.Name,
+            Role:        l.Role,
+            MemberCount: l.MemberCount,
+            Active:      current != nil && current.ID == l.ID,
+            CreatedAt:   l.CreatedAt,
+            UpdatedAt:   l.UpdatedAt,
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleCreateLedger(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    var req LedgerRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    ledger, err := s.CreateLedger(r.Context(), user.ID, req.Name)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create ledger")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusCreated, LedgerResponse{
+        ID:          ledger.ID.String(),
+        Name:        ledger.Name,
+        Role:        auth.RoleOwner,
+        MemberCount: 1,
+        CreatedAt:   ledger.CreatedAt,
+        UpdatedAt:   ledger.UpdatedAt,
+    })
+}
+
+func (s *Service) HandleUpdateLedger(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    var req LedgerRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    req.Name = strings.TrimSpace(req.Name)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    ledger, err := s.RenameLedger(r.Context(), user.ID, ledgerID, req.Name)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to update ledger")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "id":   ledger.ID.String(),
+        "name": ledger.Name,
+    })
+}
+
+func (s *Service) HandleDeleteLedger(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    if err := s.DeleteLedger(r.Context(), user.ID, ledgerID); err != nil {
+        respondWithLedgerError(w, err, "Failed to delete ledger")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ledger deleted successfully"})
+}
+
+func (s *Service) HandleActivateLedger(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    ledger, err := s.ActivateLedger(r.Context(), user.ID, ledgerID)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to switch ledger")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "id":   ledger.ID.String(),
+        "name": ledger.Name,
+        "role": ledger.Role,
+    })
+}
+
+func (s *Service) HandleGetMembers(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    members, err := s.GetMembers(r.Context(), user.ID, ledgerID)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to get members")
+        return
+    }
+
+    response := make([]MemberResponse, len(members))
+    for i, m := range members {
+        response[i] = MemberResponse{
+            UserID:   m.UserID.String(),
+            Email:    m.Email,
+            Role:     m.Role,
+            JoinedAt: m.CreatedAt,
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleUpdateMember(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+    memberID, ok := pathID(r, "userId")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+        return
+    }
+
+    var req MemberRoleRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if err := s.SetMemberRole(r.Context(), user.ID, ledgerID, memberID, req.Role); err != nil {
+        respondWithLedgerError(w, err, "Failed to update member")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Member updated successfully"})
+}
+
+// HandleRemoveMember removes a member, or with the caller's own user ID
+// leaves the ledger.
+func (s *Service) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+    memberID, ok := pathID(r, "userId")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+        return
+    }
+
+    if err := s.RemoveMember(r.Context(), user.ID, ledgerID, memberID); err != nil {
+        respondWithLedgerError(w, err, "Failed to remove member")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Member removed successfully"})
+}
+
+func (s *Service) HandleCreateInvitation(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    var req InvitationRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    req.Email = strings.TrimSpace(req.Email)
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if req.Role == "" {
+        req.Role = auth.RoleEditor
+    }
+
+    invitation, err := s.Invite(r.Context(), user, ledgerID, req.Email, req.Role)
+    if err != nil {
+        if errors.Is(err, ErrInvalidRole) {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid role, use editor or viewer")
+            return
+        }
+        respondWithLedgerError(w, err, "Failed to create invitation")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusCreated, invitationResponse(*invitation))
+}
+
+func (s *Service) HandleGetInvitations(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+
+    invitations, err := s.GetInvitations(r.Context(), user.ID, ledgerID)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to get invitations")
+        return
+    }
+
+    response := make([]InvitationResponse, len(invitations))
+    for i, invitation := range invitations {
+        response[i] = invitationResponse(invitation)
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleCancelInvitation(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    ledgerID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid ledger ID")
+        return
+    }
+    invitationID, ok := pathID(r, "invitationId")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid invitation ID")
+        return
+    }
+
+    if err := s.CancelInvitation(r.Context(), user.ID, ledgerID, invitationID); err != nil {
+        respondWithLedgerError(w, err, "Failed to cancel invitation")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Invitation cancelled successfully"})
+}
+
+// HandleGetUserInvitations lists invitations addressed to the current user.
+func (s *Service) HandleGetUserInvitations(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    invitations, err := s.GetUserInvitations(r.Context(), user)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to get invitations")
+        return
+    }
+
+    response := make([]UserInvitationResponse, len(invitations))
+    for i, invitation := range invitations {
+        response[i] = UserInvitationResponse{
+            ID:         invitation.ID.String(),
+            LedgerID:   invitation.LedgerID.String(),
+            LedgerName: invitation.LedgerName,
+            Role:       invitation.Role,
+            InvitedBy:  invitation.InvitedByEmail,
+            ExpiresAt:  invitation.ExpiresAt,
+            CreatedAt:  invitation.CreatedAt,
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    invitationID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid invitation ID")
+        return
+    }
+
+    ledger, err := s.AcceptInvitation(r.Context(), user, invitationID)
+    if err != nil {
+        respondWithLedgerError(w, err, "Failed to accept invitation")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "id":   ledger.ID.String(),
+        "name": ledger.Name,
+        "role": ledger.Role,
+    })
+}
+
+func (s *Service) HandleDeclineInvitation(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    invitationID, ok := pathID(r, "id")
+    if !ok {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid invitation ID")
+        return
+    }
+
+    if err := s.DeclineInvitation(r.Context(), user, invitationID); err != nil {
+        respondWithLedgerError(w, err, "Failed to decline invitation")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Invitation declined"})
+}
@@ -0,0 +1,447 @@
+package ledgers
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/attachments"
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/mailer"
+    "github.com/google/uuid"
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 14 * 24 * time.Hour
+
+var (
+    ErrLedgerNotFound     = errors.New("ledger not found")
+    ErrNotOwner           = errors.New("only owners can manage this ledger")
+    ErrLastLedger         = errors.New("you can't leave or delete your only ledger")
+    ErrLastOwner          = errors.New("a ledger needs at least one owner")
+    ErrMemberNotFound     = errors.New("member not found")
+    ErrAlreadyMember      = errors.New("already a member of this ledger")
+    ErrInvalidRole        = errors.New("invalid role")
+    ErrInvitationNotFound = errors.New("invitation not found")
+    ErrEmailNotVerified   = errors.New("verify your email address to see and accept invitations")
+)
+
+type Service struct {
+    db          *sql.DB
+    queries     *database.Queries
+    attachments *attachments.Service
+    mailer      mailer.Mailer
+    appURL      string
+}
+
+func NewService(db *sql.DB, queries *database.Queries, attachments *attachments.Service, mailer mailer.Mailer, appURL string) *Service {
+    return &Service{db: db, queries: queries, attachments: attachments, mailer: mailer, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// access returns the user's membership in a ledger. Ledgers the user isn't
+// in are reported as not found rather than forbidden.
+func (s *Service) access(ctx context.Context, queries *database.Queries, userID, ledgerID uuid.UUID) (*database.GetLedgerAccessRow, error) {
+    row, err := queries.GetLedgerAccess(ctx, database.GetLedgerAccessParams{
+        UserID:   userID,
+        LedgerID: uuid.NullUUID{UUID: ledgerID, Valid: true},
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrLedgerNotFound
+    }
+    return &row, err
+}
+
+// manage is access for actions only owners may take.
+func (s *Service) manage(ctx context.Context, queries *database.Queries, userID, ledgerID uuid.UUID) (*database.GetLedgerAccessRow, error) {
+    row, err := s.access(ctx, queries, userID, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    if row.Role != auth.RoleOwner {
+        return nil, ErrNotOwner
+    }
+    return row, nil
+}
+
+func (s *Service) GetUserLedgers(ctx context.Context, userID uuid.UUID) ([]database.GetLedgersByUserRow, error) {
+    return s.queries.GetLedgersByUser(ctx, userID)
+}
+
+// CreateLedger creates an empty ledger owned by userID.
+func (s *Service) CreateLedger(ctx context.Context, userID uuid.UUID, name string) (*database.Ledger, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    ledger, err := qtx.CreateLedger(ctx, name)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := qtx.AddLedgerMember(ctx, database.AddLedgerMemberParams{
+        LedgerID: ledger.ID,
+        UserID:   userID,
+        Role:     auth.RoleOwner,
+    }); err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &ledger, nil
+}
+
+func (s *Service) RenameLedger(ctx context.Context, userID, ledgerID uuid.UUID, name string) (*database.Ledger, error) {
+    if _, err := s.manage(ctx, s.queries, userID, ledgerID); err != nil {
+        return nil, err
+    }
+    ledger, err := s.queries.RenameLedger(ctx, database.RenameLedgerParams{ID: ledgerID, Name: name})
+    return &ledger, err
+}
+
+// DeleteLedger removes a ledger with all of its data for every member,
+// along with attachment blobs no other ledger still uses.
+func (s *Service) DeleteLedger(ctx context.Context, userID, ledgerID uuid.UUID) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    if err := qtx.LockLedger(ctx, ledgerID); err != nil {
+        return err
+    }
+    if _, err := s.manage(ctx, qtx, userID, ledgerID); err != nil {
+        return err
+    }
+    count, err := qtx.CountLedgersByUser(ctx, userID)
+    if err != nil {
+        return err
+    }
+    if count <= 1 {
+        return ErrLastLedger
+    }
+
+    hashes, err := qtx.GetAttachmentHashesByLedger(ctx, ledgerID)
+    if err != nil {
+        return err
+    }
+    if err := qtx.DeleteLedger(ctx, ledgerID); err != nil {
+        return err
+    }
+    unused, err := s.attachments.ReleaseBlobs(ctx, qtx, hashes)
+    if err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return err
+    }
+    s.attachments.DeleteBlobs(ctx, unused)
+    return nil
+}
+
+// ActivateLedger makes a ledger the one requests use when they don't name
+// one in X-Ledger-ID.
+func (s *Service) ActivateLedger(ctx context.Context, userID, ledgerID uuid.UUID) (*database.GetLedgerAccessRow, error) {
+    row, err := s.access(ctx, s.queries, userID, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.queries.SetActiveLedger(ctx, database.SetActiveLedgerParams{
+        ID:             userID,
+        ActiveLedgerID: uuid.NullUUID{UUID: ledgerID, Valid: true},
+    }); err != nil {
+        return nil, err
+    }
+    return row, nil
+}
+
+// GetMembers lists a ledger's members to anyone in it.
+func (s *Service) GetMembers(ctx context.Context, userID, ledgerID uuid.UUID) ([]database.GetLedgerMembersRow, error) {
+    if _, err := s.access(ctx, s.queries, userID, ledgerID); err != nil {
+        return nil, err
+    }
+    return s.queries.GetLedgerMembers(ctx, ledgerID)
+}
+
+func validRole(role string) bool {
+    return role == auth.RoleOwner || role == auth.RoleEditor || role == auth.RoleViewer
+}
+
+// SetMemberRole changes a member's role. Owners can demote themselves as
+// long as another owner remains.
+func (s *Service) SetMemberRole(ctx context.Context, userID, ledgerID, memberID uuid.UUID, role string) error {
+    if !validRole(role) {
+        return ErrInvalidRole
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    if err := qtx.LockLedger(ctx, ledgerID); err != nil {
+        return err
+    }
+    if _, err := s.manage(ctx, qtx, userID, ledgerID); err != nil {
+        return err
+    }
+
+    rows, err := qtx.UpdateLedgerMemberRole(ctx, database.UpdateLedgerMemberRoleParams{
+        LedgerID: ledgerID,
+        UserID:   memberID,
+        Role:     role,
+    })
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return ErrMemberNotFound
+    }
+    if err := s.checkOwners(ctx, qtx, ledgerID); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// RemoveMember takes someone out of a ledger. Owners can remove anyone;
+// everyone else can only remove themselves, i.e. leave.
+func (s *Service) RemoveMember(ctx context.Context, userID, ledgerID, memberID uuid.UUID) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    if err := qtx.LockLedger(ctx, ledgerID); err != nil {
+        return err
+    }
+    if memberID == userID {
+        if _, err := s.access(ctx, qtx, userID, ledgerID); err != nil {
+            return err
+        }
+        count, err := qtx.CountLedgersByUser(ctx, userID)
+        if err != nil {
+            return err
+        }
+        if count <= 1 {
+            return ErrLastLedger
+        }
+    } else if _, err := s.manage(ctx, qtx, userID, ledgerID); err != nil {
+        return err
+    }
+
+    rows, err := qtx.RemoveLedgerMember(ctx, database.RemoveLedgerMemberParams{
+        LedgerID: ledgerID,
+        UserID:   memberID,
+    })
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return ErrMemberNotFound
+    }
+    if err := s.checkOwners(ctx, qtx, ledgerID); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// checkOwners fails when a change inside the transaction left the ledger
+// without an owner. Callers hold the ledger lock.
+func (s *Service) checkOwners(ctx context.Context, queries *database.Queries, ledgerID uuid.UUID) error {
+    owners, err := queries.CountLedgerOwners(ctx, ledgerID)
+    if err != nil {
+        return err
+    }
+    if owners == 0 {
+        return ErrLastOwner
+    }
+    return nil
+}
+
+// Invite asks someone to join a ledger by email. They accept in the app
+// after signing in with that address, so no token is mailed.
+func (s *Service) Invite(ctx context.Context, inviter *database.User, ledgerID uuid.UUID, email, role string) (*database.LedgerInvitation, error) {
+    // Ownership is handed out by promoting an existing member
+    if role != auth.RoleEditor && role != auth.RoleViewer {
+        return nil, ErrInvalidRole
+    }
+
+    ledger, err := s.manage(ctx, s.queries, inviter.ID, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    members, err := s.queries.GetLedgerMembers(ctx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    for _, m := range members {
+        if strings.EqualFold(m.Email, email) {
+            return nil, ErrAlreadyMember
+        }
+    }
+
+    invitation, err := s.queries.CreateLedgerInvitation(ctx, database.CreateLedgerInvitationParams{
+        LedgerID:  ledgerID,
+        Email:     email,
+        Role:      role,
+        InvitedBy: inviter.ID,
+        ExpiresAt: time.Now().Add(invitationTTL),
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    s.sendInBackground(mailer.Message{
+        To:      email,
+        Subject: fmt.Sprintf("%s invited you to %q", inviter.Email, ledger.Name),
+        Body: fmt.Sprintf("%s invited you to share the ledger %q as %s.\n\n"+
+            "Sign in or create an account with this email address to accept:\n\n%s\n\n"+
+            "The invitation expires in %d days.\n",
+            inviter.Email, ledger.Name, role, s.appURL+"/invitations", int(invitationTTL.Hours()/24)),
+    })
+    return &invitation, nil
+}
+
+func (s *Service) sendInBackground(msg mailer.Message) {
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+        defer cancel()
+        if err := s.mailer.Send(ctx, msg); err != nil {
+            log.Printf("Failed to send %q email: %v", msg.Subject, err)
+        }
+    }()
+}
+
+func (s *Service) GetInvitations(ctx context.Context, userID, ledgerID uuid.UUID) ([]database.LedgerInvitation, error) {
+    if _, err := s.manage(ctx, s.queries, userID, ledgerID); err != nil {
+        return nil, err
+    }
+    return s.queries.GetLedgerInvitations(ctx, ledgerID)
+}
+
+func (s *Service) CancelInvitation(ctx context.Context, userID, ledgerID, invitationID uuid.UUID) error {
+    if _, err := s.manage(ctx, s.queries, userID, ledgerID); err != nil {
+        return err
+    }
+    rows, err := s.queries.DeleteLedgerInvitation(ctx, database.DeleteLedgerInvitationParams{
+        ID:       invitationID,
+        LedgerID: ledgerID,
+    })
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return ErrInvitationNotFound
+    }
+    return nil
+}
+
+// GetUserInvitations lists the pending invitations for the user's email.
+// The address has to be verified, or anyone could sign up with it and see
+// what was meant for its owner.
+func (s *Service) GetUserInvitations(ctx context.Context, user *database.User) ([]database.GetInvitationsForEmailRow, error) {
+    if !user.EmailVerifiedAt.Valid {
+        return nil, ErrEmailNotVerified
+    }
+    return s.queries.GetInvitationsForEmail(ctx, user.Email)
+}
+
+// AcceptInvitation adds the user to the invitation's ledger. Accepting an
+// invitation to a ledger the user is already in just uses it up.
+func (s *Service) AcceptInvitation(ctx context.Context, user *database.User, invitationID uuid.UUID) (*database.GetLedgerAccessRow, error) {
+    if !user.EmailVerifiedAt.Valid {
+        return nil, ErrEmailNotVerified
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    invitation, err := qtx.GetInvitationForEmail(ctx, database.GetInvitationForEmailParams{
+        ID:    invitationID,
+        Email: user.Email,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrInvitationNotFound
+    } else if err != nil {
+        return nil, err
+    }
+
+    if err := qtx.LockLedger(ctx, invitation.LedgerID); err != nil {
+        return nil, err
+    }
+    if _, err := s.access(ctx, qtx, user.ID, invitation.LedgerID); errors.Is(err, ErrLedgerNotFound) {
+        if _, err := qtx.AddLedgerMember(ctx, database.AddLedgerMemberParams{
+            LedgerID: invitation.LedgerID,
+            UserID:   user.ID,
+            Role:     invitation.Role,
+        }); err != nil {
+            return nil, err
+        }
+    } else if err != nil {
+        return nil, err
+    }
+
+    if _, err := qtx.DeleteLedgerInvitation(ctx, database.DeleteLedgerInvitationParams{
+        ID:       invitation.ID,
+        LedgerID: invitation.LedgerID,
+    }); err != nil {
+        return nil, err
+    }
+    row, err := s.access(ctx, qtx, user.ID, invitation.LedgerID)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return row, nil
+}
+
+func (s *Service) DeclineInvitation(ctx context.Context, user *database.User, invitationID uuid.UUID) error {
+    if !user.EmailVerifiedAt.Valid {
+        return ErrEmailNotVerified
+    }
+
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+
+    invitation, err := qtx.GetInvitationForEmail(ctx, database.GetInvitationForEmailParams{
+        ID:    invitationID,
+        Email: user.Email,
+    })
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrInvitationNotFound
+    } else if err != nil {
+        return err
+    }
+    if _, err := qtx.DeleteLedgerInvitation(ctx, database.DeleteLedgerInvitationParams{
+        ID:       invitation.ID,
+        LedgerID: invitation.LedgerID,
+    }); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+// LogMailer writes every message to w instead of delivering it. Useful in
+// development, where reset links can be copied from the output.
+type LogMailer struct {
+    mu   sync.Mutex
+    w    io.Writer
+    from string
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+    return &LogMailer{w: w, from: from}
+}
+
+// NewFileMailer appends messages to the file at path, creating it if needed.
+func NewFileMailer(path, from string) (*LogMailer, error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return nil, err
+    }
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+    if err != nil {
+        return nil, err
+    }
+    return NewLogMailer(f, from), nil
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+    if _, err := parseAddress(msg.To); err != nil {
+        return err
+    }
+    
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    if _, err := fmt.Fprintf(m.w, "----- mail %s -----\r\n", time.Now().UTC().Format(time.RFC3339)); err != nil {
+        return err
+    }
+    _, err := m.w.Write(format(m.from, msg, time.Now()))
+    return err
+}
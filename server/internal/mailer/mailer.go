@@ -0,0 +1,59 @@
+package mailer
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "mime"
+    "net/mail"
+    "strings"
+    "time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+    To      string
+    Subject string
+    Body    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+    Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as an RFC 5322 message with CRLF line endings.
+func format(from string, msg Message, now time.Time) []byte {
+    var buf bytes.Buffer
+    header := func(name, value string) {
+        fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+    }
+    header("From", from)
+    header("To", msg.To)
+    header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+    header("Date", now.Format(time.RFC1123Z))
+    header("MIME-Version", "1.0")
+    header("Content-Type", "text/plain; charset=utf-8")
+    header("Content-Transfer-Encoding", "8bit")
+    buf.WriteString("\r\n")
+    
+    body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+    for _, line := range strings.Split(body, "\n") {
+        buf.WriteString(line)
+        buf.WriteString("\r\n")
+    }
+    return buf.Bytes()
+}
+
+// parseAddress returns the bare address of addr, which may carry a display
+// name. It also keeps user-supplied addresses from injecting headers.
+func parseAddress(addr string) (string, error) {
+    if strings.ContainsAny(addr, "\r\n") {
+        return "", fmt.Errorf("invalid address %q", addr)
+    }
+    parsed, err := mail.ParseAddress(addr)
+    if err != nil {
+        return "", fmt.Errorf("invalid address %q: %w", addr, err)
+    }
+    return parsed.Address, nil
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+    "context"
+    "crypto/tls"
+    "net"
+    "net/smtp"
+    "strconv"
+    "time"
+)
+
+type SMTPConfig struct {
+    Host     string
+    Port     int
+    Username string
+    Password string
+    From     string
+}
+
+// SMTPMailer sends through an SMTP relay, upgrading with STARTTLS when the
+// server offers it. With no username it sends unauthenticated, which is what
+// MailHog and similar local catchers expect.
+type SMTPMailer struct {
+    cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+    if cfg.Port == 0 {
+        cfg.Port = 587
+    }
+    return &SMTPMailer{cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+    to, err := parseAddress(msg.To)
+    if err != nil {
+        return err
+    }
+    from, err := parseAddress(m.cfg.From)
+    if err != nil {
+        return err
+    }
+    
+    addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+    dialer := net.Dialer{Timeout: 10 * time.Second}
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return err
+    }
+    deadline := time.Now().Add(30 * time.Second)
+    if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+        deadline = d
+    }
+    conn.SetDeadline(deadline)
+    
+    client, err := smtp.NewClient(conn, m.cfg.Host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer client.Close()
+    
+    if ok, _ := client.Extension("STARTTLS"); ok {
+        if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+            return err
+        }
+    }
+    if m.cfg.Username != "" {
+        // PlainAuth refuses to send credentials over an unencrypted
+        // connection to anything but localhost.
+        if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+            return err
+        }
+    }
+    
+    if err := client.Mail(from); err != nil {
+        return err
+    }
+    if err := client.Rcpt(to); err != nil {
+        return err
+    }
+    w, err := client.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := w.Write(format(m.cfg.From, msg, time.Now())); err != nil {
+        return err
+    }
+    if err := w.Close(); err != nil {
+        return err
+    }
+    return client.Quit()
+}
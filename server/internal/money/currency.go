@@ -0,0 +1,64 @@
+package money
+
+import (
+    "errors"
+    "math"
+    "math/big"
+    "strings"
+)
+
+var (
+    ErrInvalidCurrency = errors.New("currency must be a three-letter ISO 4217 code")
+    ErrInvalidRate     = errors.New("invalid exchange rate")
+)
+
+// DefaultCurrency is used when neither the request nor the user says otherwise.
+const DefaultCurrency = "USD"
+
+// ParseCurrency normalizes a currency code to upper case and checks its shape.
+func ParseCurrency(code string) (string, error) {
+    code = strings.ToUpper(strings.TrimSpace(code))
+    if len(code) != 3 {
+        return "", ErrInvalidCurrency
+    }
+    for i := 0; i < len(code); i++ {
+        if code[i] < 'A' || code[i] > 'Z' {
+            return "", ErrInvalidCurrency
+        }
+    }
+    return code, nil
+}
+
+// ParseRate reads a positive decimal exchange rate exactly.
+func ParseRate(s string) (*big.Rat, error) {
+    rate, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+    if !ok || rate.Sign() <= 0 {
+        return nil, ErrInvalidRate
+    }
+    return rate, nil
+}
+
+// Convert multiplies the amount by rate and rounds half away from zero to
+// the nearest minor unit.
+func (a Amount) Convert(rate *big.Rat) (Amount, error) {
+    product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(a)), rate)
+
+    num := new(big.Int).Set(product.Num())
+    den := product.Denom()
+    negative := num.Sign() < 0
+    num.Abs(num)
+
+    quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+    if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+        quo.Add(quo, big.NewInt(1))
+    }
+    if !quo.IsInt64() || quo.Int64() == math.MaxInt64 {
+        return 0, ErrOverflow
+    }
+
+    cents := quo.Int64()
+    if negative {
+        cents = -cents
+    }
+    return Amount(cents), nil
+}
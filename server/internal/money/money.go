@@ -0,0 +1,232 @@
+// Package money provides an exact decimal amount type for expense values.
+package money
+
+import (
+    "bytes"
+    "database/sql/driver"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Amount is a monetary value stored as an integer number of minor units
+// (cents). It never passes through float64, so sums are exact.
+type Amount int64
+
+const Zero Amount = 0
+
+// MaxStored is the largest amount the DECIMAL(10, 2) amount columns hold.
+// Parse accepts far more, so check Storable before writing an amount.
+const MaxStored Amount = 99_999_999_99
+
+var (
+    ErrInvalidAmount = errors.New("invalid amount")
+    ErrTooPrecise    = errors.New("amount has more than two decimal places")
+    ErrOverflow      = errors.New("amount is out of range")
+)
+
+// Parse reads a decimal string such as "12", "12.3" or "-12.34".
+// More than two decimal places is an error.
+func Parse(s string) (Amount, error) {
+    return parse(s, true)
+}
+
+// FromMinorUnits builds an Amount from a count of cents.
+func FromMinorUnits(cents int64) Amount {
+    return Amount(cents)
+}
+
+// MinorUnits returns the amount as a count of cents.
+func (a Amount) MinorUnits() int64 {
+    return int64(a)
+}
+
+// Storable reports whether the amount fits the amount columns.
+func (a Amount) Storable() bool {
+    return a >= -MaxStored && a <= MaxStored
+}
+
+func (a Amount) IsZero() bool     { return a == 0 }
+func (a Amount) IsPositive() bool { return a > 0 }
+func (a Amount) IsNegative() bool { return a < 0 }
+
+func (a Amount) Add(b Amount) (Amount, error) {
+    if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+        return 0, ErrOverflow
+    }
+    return a + b, nil
+}
+
+func (a Amount) Sub(b Amount) (Amount, error) {
+    if b == math.MinInt64 {
+        return 0, ErrOverflow
+    }
+    return a.Add(-b)
+}
+
+func (a Amount) Neg() Amount {
+    return -a
+}
+
+// Sum adds amounts exactly, failing only on int64 overflow.
+func Sum(amounts ...Amount) (Amount, error) {
+    var total Amount
+    var err error
+    for _, a := range amounts {
+        if total, err = total.Add(a); err != nil {
+            return 0, err
+        }
+    }
+    return total, nil
+}
+
+// String formats the amount with exactly two decimal places.
+func (a Amount) String() string {
+    cents := int64(a)
+    sign := ""
+    var abs uint64
+    if cents < 0 {
+        sign = "-"
+        abs = uint64(-(cents + 1)) + 1
+    } else {
+        abs = uint64(cents)
+    }
+    return fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)
+}
+
+// MarshalJSON always emits a string so clients never see a float.
+func (a Amount) MarshalJSON() ([]byte, error) {
+    return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts either a decimal string ("12.34") or an integer
+// number of minor units (1234). Fractional JSON numbers are rejected.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+    data = bytes.TrimSpace(data)
+    if bytes.Equal(data, []byte("null")) {
+        return nil
+    }
+
+    if len(data) > 0 && data[0] == '"' {
+        var s string
+        if err := json.Unmarshal(data, &s); err != nil {
+            return ErrInvalidAmount
+        }
+        parsed, err := Parse(s)
+        if err != nil {
+            return err
+        }
+        *a = parsed
+        return nil
+    }
+
+    cents, err := strconv.ParseInt(string(data), 10, 64)
+    if err != nil {
+        if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+            return ErrOverflow
+        }
+        return fmt.Errorf("%w: numbers must be integer minor units, use a string for decimals", ErrInvalidAmount)
+    }
+    *a = Amount(cents)
+    return nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns.
+func (a *Amount) Scan(src interface{}) error {
+    switch v := src.(type) {
+    case []byte:
+        parsed, err := parse(string(v), false)
+        if err != nil {
+            return err
+        }
+        *a = parsed
+    case string:
+        parsed, err := parse(v, false)
+        if err != nil {
+            return err
+        }
+        *a = parsed
+    case int64:
+        if v > math.MaxInt64/100 || v < math.MinInt64/100 {
+            return ErrOverflow
+        }
+        *a = Amount(v * 100)
+    case nil:
+        *a = 0
+    default:
+        return fmt.Errorf("money: cannot scan %T into Amount", src)
+    }
+    return nil
+}
+
+// Value implements driver.Valuer, sending the amount as a decimal string.
+func (a Amount) Value() (driver.Value, error) {
+    return a.String(), nil
+}
+
+// parse converts a decimal string to minor units. When strict is false,
+// extra fractional digits are allowed as long as they are zeros, which is
+// how Postgres renders NUMERIC values with a wider scale.
+func parse(s string, strict bool) (Amount, error) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return 0, ErrInvalidAmount
+    }
+
+    negative := false
+    switch s[0] {
+    case '-':
+        negative = true
+        s = s[1:]
+    case '+':
+        s = s[1:]
+    }
+
+    whole, frac, hasPoint := strings.Cut(s, ".")
+    if whole == "" && frac == "" {
+        return 0, ErrInvalidAmount
+    }
+    if hasPoint && frac == "" {
+        return 0, ErrInvalidAmount
+    }
+    if !isDigits(whole) || !isDigits(frac) {
+        return 0, ErrInvalidAmount
+    }
+
+    if len(frac) > 2 {
+        if strict || strings.TrimRight(frac[2:], "0") != "" {
+            return 0, ErrTooPrecise
+        }
+        frac = frac[:2]
+    }
+    for len(frac) < 2 {
+        frac += "0"
+    }
+    if whole == "" {
+        whole = "0"
+    }
+
+    units, err := strconv.ParseInt(whole, 10, 64)
+    if err != nil || units > (math.MaxInt64-99)/100 {
+        return 0, ErrOverflow
+    }
+    cents, _ := strconv.ParseInt(frac, 10, 64)
+
+    total := units*100 + cents
+    if negative {
+        total = -total
+    }
+    return Amount(total), nil
+}
+
+func isDigits(s string) bool {
+    for i := 0; i < len(s); i++ {
+        if s[i] < '0' || s[i] > '9' {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,200 @@
+package money
+
+import (
+    "errors"
+    "math"
+    "testing"
+)
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Amount
+        wantErr error
+    }{
+        {"12", 1200, nil},
+        {"12.3", 1230, nil},
+        {"-12.34", -1234, nil},
+        {"+0.05", 5, nil},
+        {".5", 50, nil},
+        {" 7.00 ", 700, nil},
+        {"92233720368547757.99", 9223372036854775799, nil},
+        {"-92233720368547757.99", -9223372036854775799, nil},
+        {"92233720368547758", 0, ErrOverflow},
+        {"92233720368547759", 0, ErrOverflow},
+        {"99999999999999999999", 0, ErrOverflow},
+        {"1.234", 0, ErrTooPrecise},
+        {"1.230", 0, ErrTooPrecise},
+        {"", 0, ErrInvalidAmount},
+        {"-", 0, ErrInvalidAmount},
+        {".", 0, ErrInvalidAmount},
+        {"12.", 0, ErrInvalidAmount},
+        {"1e3", 0, ErrInvalidAmount},
+        {"1,000", 0, ErrInvalidAmount},
+        {"--1", 0, ErrInvalidAmount},
+    }
+
+    for _, tt := range tests {
+        got, err := Parse(tt.in)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestScanAllowsTrailingZeros(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Amount
+        wantErr error
+    }{
+        {"12.3400", 1234, nil},
+        {"12.3401", 0, ErrTooPrecise},
+    }
+
+    for _, tt := range tests {
+        var got Amount
+        err := got.Scan([]byte(tt.in))
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("Scan(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("Scan(%q) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestString(t *testing.T) {
+    tests := []struct {
+        in   Amount
+        want string
+    }{
+        {0, "0.00"},
+        {5, "0.05"},
+        {-5, "-0.05"},
+        {123456, "1234.56"},
+        {math.MaxInt64, "92233720368547758.07"},
+        {math.MinInt64, "-92233720368547758.08"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.in.String(); got != tt.want {
+            t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+        }
+    }
+}
+
+func TestStorable(t *testing.T) {
+    tests := []struct {
+        in   string
+        want bool
+    }{
+        {"0.01", true},
+        {"99999999.99", true},
+        {"-99999999.99", true},
+        {"100000000.00", false},
+        {"-100000000.00", false},
+        {"92233720368547757.99", false},
+    }
+
+    for _, tt := range tests {
+        a, err := Parse(tt.in)
+        if err != nil {
+            t.Fatalf("Parse(%q): %v", tt.in, err)
+        }
+        if got := a.Storable(); got != tt.want {
+            t.Errorf("Parse(%q).Storable() = %v, want %v", tt.in, got, tt.want)
+        }
+    }
+    if MaxStored.String() != "99999999.99" {
+        t.Errorf("MaxStored = %s, want the DECIMAL(10, 2) maximum 99999999.99", MaxStored)
+    }
+}
+
+func TestAddSub(t *testing.T) {
+    tests := []struct {
+        name    string
+        a, b    Amount
+        op      func(Amount, Amount) (Amount, error)
+        want    Amount
+        wantErr error
+    }{
+        {"add", 150, 275, Amount.Add, 425, nil},
+        {"add negative", 150, -275, Amount.Add, -125, nil},
+        {"add to max", math.MaxInt64 - 1, 1, Amount.Add, math.MaxInt64, nil},
+        {"add past max", math.MaxInt64, 1, Amount.Add, 0, ErrOverflow},
+        {"add past min", math.MinInt64, -1, Amount.Add, 0, ErrOverflow},
+        {"sub", 150, 275, Amount.Sub, -125, nil},
+        {"sub to min", math.MinInt64 + 1, 1, Amount.Sub, math.MinInt64, nil},
+        {"sub past min", math.MinInt64, 1, Amount.Sub, 0, ErrOverflow},
+        {"sub past max", math.MaxInt64, -1, Amount.Sub, 0, ErrOverflow},
+        {"sub min from zero", 0, math.MinInt64, Amount.Sub, 0, ErrOverflow},
+        {"sub min from negative", -1, math.MinInt64, Amount.Sub, 0, ErrOverflow},
+    }
+
+    for _, tt := range tests {
+        got, err := tt.op(tt.a, tt.b)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestSum(t *testing.T) {
+    tests := []struct {
+        name    string
+        in      []Amount
+        want    Amount
+        wantErr error
+    }{
+        {"empty", nil, 0, nil},
+        {"mixed", []Amount{1, 2, -4}, -1, nil},
+        {"overflow", []Amount{math.MaxInt64, 1, -1}, 0, ErrOverflow},
+    }
+
+    for _, tt := range tests {
+        got, err := Sum(tt.in...)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Amount
+        wantErr error
+    }{
+        {`"12.34"`, 1234, nil},
+        {`1234`, 1234, nil},
+        {`12.34`, 0, ErrInvalidAmount},
+        {`99999999999999999999`, 0, ErrOverflow},
+        {`"1.234"`, 0, ErrTooPrecise},
+    }
+
+    for _, tt := range tests {
+        var got Amount
+        err := got.UnmarshalJSON([]byte(tt.in))
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("UnmarshalJSON(%s) error = %v, want %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("UnmarshalJSON(%s) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
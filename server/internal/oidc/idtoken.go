@@ -0,0 +1,207 @@
+package oidc
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/rsa"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "strings"
+    "time"
+)
+
+// Allowed clock difference between us and the provider
+const clockSkew = 2 * time.Minute
+
+var ErrInvalidIDToken = errors.New("oidc: invalid ID token")
+
+// Claims are the ID token claims the app uses.
+type Claims struct {
+    Issuer        string
+    Subject       string
+    Email         string
+    EmailVerified bool
+    Name          string
+}
+
+type rawClaims struct {
+    Issuer        string          `json:"iss"`
+    Subject       string          `json:"sub"`
+    Audience      audience        `json:"aud"`
+    AuthorizedParty string        `json:"azp"`
+    Expiry        int64           `json:"exp"`
+    IssuedAt      int64           `json:"iat"`
+    Nonce         string          `json:"nonce"`
+    Email         string          `json:"email"`
+    EmailVerified json.RawMessage `json:"email_verified"`
+    Name          string          `json:"name"`
+}
+
+// audience accepts both forms "aud" may take: a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        *a = audience{single}
+        return nil
+    }
+    var list []string
+    if err := json.Unmarshal(data, &list); err != nil {
+        return err
+    }
+    *a = list
+    return nil
+}
+
+// verified reads email_verified, which some providers send as a string.
+func verified(raw json.RawMessage) bool {
+    var b bool
+    if err := json.Unmarshal(raw, &b); err == nil {
+        return b
+    }
+    var s string
+    if err := json.Unmarshal(raw, &s); err == nil {
+        return strings.EqualFold(s, "true")
+    }
+    return false
+}
+
+func invalid(format string, args ...interface{}) error {
+    return fmt.Errorf("%w: %s", ErrInvalidIDToken, fmt.Sprintf(format, args...))
+}
+
+// verifyIDToken checks the signature and the claims required by OpenID
+// Connect Core 3.1.3.7.
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+    parts := strings.Split(raw, ".")
+    if len(parts) != 3 {
+        return nil, invalid("malformed token")
+    }
+    
+    headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+    if err != nil {
+        return nil, invalid("malformed header")
+    }
+    var header struct {
+        Alg string `json:"alg"`
+        Kid string `json:"kid"`
+    }
+    if err := json.Unmarshal(headerJSON, &header); err != nil {
+        return nil, invalid("malformed header")
+    }
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, invalid("malformed signature")
+    }
+    
+    key, err := p.keys.key(ctx, header.Kid)
+    if err != nil {
+        return nil, err
+    }
+    if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+        return nil, invalid("%v", err)
+    }
+    
+    payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+    if err != nil {
+        return nil, invalid("malformed payload")
+    }
+    var claims rawClaims
+    if err := json.Unmarshal(payload, &claims); err != nil {
+        return nil, invalid("malformed payload")
+    }
+    
+    now := time.Now()
+    if strings.TrimRight(claims.Issuer, "/") != p.cfg.Issuer {
+        return nil, invalid("issuer %q", claims.Issuer)
+    }
+    if !containsString(claims.Audience, p.cfg.ClientID) {
+        return nil, invalid("audience does not include client")
+    }
+    if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+        return nil, invalid("authorized party %q", claims.AuthorizedParty)
+    }
+    if claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)) {
+        return nil, invalid("expired")
+    }
+    if claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)) {
+        return nil, invalid("issued in the future")
+    }
+    if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+        return nil, invalid("nonce mismatch")
+    }
+    if claims.Subject == "" {
+        return nil, invalid("missing subject")
+    }
+    
+    return &Claims{
+        Issuer:        p.cfg.Issuer,
+        Subject:       claims.Subject,
+        Email:         claims.Email,
+        EmailVerified: verified(claims.EmailVerified),
+        Name:          claims.Name,
+    }, nil
+}
+
+func containsString(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
+
+// verifySignature supports the asymmetric JWS algorithms providers use.
+// "none" and the HMAC algorithms are refused: the client secret must never
+// be able to mint ID tokens.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+    var hash crypto.Hash
+    switch alg {
+    case "RS256", "PS256", "ES256":
+        hash = crypto.SHA256
+    case "RS384", "PS384", "ES384":
+        hash = crypto.SHA384
+    case "RS512", "PS512", "ES512":
+        hash = crypto.SHA512
+    default:
+        return fmt.Errorf("unsupported algorithm %q", alg)
+    }
+    h := hash.New()
+    h.Write(signed)
+    digest := h.Sum(nil)
+    
+    switch alg[:2] {
+    case "RS", "PS":
+        pub, ok := key.(*rsa.PublicKey)
+        if !ok {
+            return errors.New("key type does not match algorithm")
+        }
+        if alg[0] == 'R' {
+            return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+        }
+        return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+    default:
+        pub, ok := key.(*ecdsa.PublicKey)
+        if !ok {
+            return errors.New("key type does not match algorithm")
+        }
+        // JWS encodes ECDSA signatures as fixed-size r || s
+        size := (pub.Curve.Params().BitSize + 7) / 8
+        if len(signature) != 2*size {
+            return errors.New("bad signature length")
+        }
+        r := new(big.Int).SetBytes(signature[:size])
+        s := new(big.Int).SetBytes(signature[size:])
+        if !ecdsa.Verify(pub, digest, r, s) {
+            return errors.New("signature mismatch")
+        }
+        return nil
+    }
+}
@@ -0,0 +1,163 @@
+package oidc
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "math/big"
+    "sync"
+    "time"
+)
+
+// minRefreshInterval stops tokens with made-up key IDs from making us hammer
+// the provider's JWKS endpoint.
+const minRefreshInterval = time.Minute
+
+type jwk struct {
+    Kty string `json:"kty"`
+    Kid string `json:"kid"`
+    Use string `json:"use"`
+    Alg string `json:"alg"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+}
+
+type keySet struct {
+    uri     string
+    getJSON func(ctx context.Context, url string, v interface{}) error
+    
+    mu          sync.Mutex
+    keys        map[string]crypto.PublicKey
+    lastRefresh time.Time
+}
+
+func newKeySet(uri string, getJSON func(context.Context, string, interface{}) error) *keySet {
+    return &keySet{uri: uri, getJSON: getJSON}
+}
+
+// key returns the signing key with the given ID, refetching the set when
+// the ID is unknown since providers rotate keys.
+func (ks *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+    ks.mu.Lock()
+    defer ks.mu.Unlock()
+    
+    if key, ok := ks.lookup(kid); ok {
+        return key, nil
+    }
+    if time.Since(ks.lastRefresh) < minRefreshInterval {
+        return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+    }
+    if err := ks.refresh(ctx); err != nil {
+        return nil, err
+    }
+    if key, ok := ks.lookup(kid); ok {
+        return key, nil
+    }
+    return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+// lookup finds kid, or the only key when the token names none.
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+    if kid == "" && len(ks.keys) == 1 {
+        for _, key := range ks.keys {
+            return key, true
+        }
+    }
+    key, ok := ks.keys[kid]
+    return key, ok
+}
+
+func (ks *keySet) refresh(ctx context.Context) error {
+    ks.lastRefresh = time.Now()
+    
+    var set struct {
+        Keys []jwk `json:"keys"`
+    }
+    if err := ks.getJSON(ctx, ks.uri, &set); err != nil {
+        return fmt.Errorf("oidc: fetch JWKS: %w", err)
+    }
+    
+    keys := make(map[string]crypto.PublicKey)
+    for _, k := range set.Keys {
+        if k.Use != "" && k.Use != "sig" {
+            continue
+        }
+        key, err := k.publicKey()
+        if err != nil {
+            // Skip key types we don't support rather than failing the set
+            continue
+        }
+        keys[k.Kid] = key
+    }
+    if len(keys) == 0 {
+        return errors.New("oidc: JWKS has no usable signing keys")
+    }
+    ks.keys = keys
+    return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    if len(b) == 0 {
+        return nil, errors.New("empty value")
+    }
+    return new(big.Int).SetBytes(b), nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+    switch k.Kty {
+    case "RSA":
+        n, err := decodeBigInt(k.N)
+        if err != nil {
+            return nil, err
+        }
+        e, err := decodeBigInt(k.E)
+        if err != nil {
+            return nil, err
+        }
+        if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+            return nil, errors.New("invalid RSA exponent")
+        }
+        if n.BitLen() < 2048 {
+            return nil, errors.New("RSA key too small")
+        }
+        return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+    case "EC":
+        var curve elliptic.Curve
+        switch k.Crv {
+        case "P-256":
+            curve = elliptic.P256()
+        case "P-384":
+            curve = elliptic.P384()
+        case "P-521":
+            curve = elliptic.P521()
+        default:
+            return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+        }
+        x, err := decodeBigInt(k.X)
+        if err != nil {
+            return nil, err
+        }
+        y, err := decodeBigInt(k.Y)
+        if err != nil {
+            return nil, err
+        }
+        if !curve.IsOnCurve(x, y) {
+            return nil, errors.New("EC point is not on the curve")
+        }
+        return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+    default:
+        return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+    }
+}
@@ -0,0 +1,209 @@
+// Package oidc is a small OpenID Connect relying party: discovery, the
+// authorization code flow with PKCE, and ID token validation against the
+// provider's JWKS. It only depends on the standard library.
+package oidc
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+type Config struct {
+    // Issuer URL; discovery is read from Issuer + "/.well-known/openid-configuration"
+    Issuer       string
+    ClientID     string
+    // Empty for public clients, which rely on PKCE alone
+    ClientSecret string
+    RedirectURL  string
+    // Defaults to openid, email and profile
+    Scopes       []string
+}
+
+type discovery struct {
+    Issuer                string `json:"issuer"`
+    AuthorizationEndpoint string `json:"authorization_endpoint"`
+    TokenEndpoint         string `json:"token_endpoint"`
+    JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one identity provider. Discovery happens on first use
+// so the API can start while the provider is unreachable.
+type Provider struct {
+    cfg    Config
+    client *http.Client
+    
+    mu        sync.Mutex
+    meta      *discovery
+    keys      *keySet
+}
+
+func NewProvider(cfg Config) (*Provider, error) {
+    if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+        return nil, errors.New("oidc: issuer, client ID and redirect URL are required")
+    }
+    if len(cfg.Scopes) == 0 {
+        cfg.Scopes = []string{"openid", "email", "profile"}
+    }
+    cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+    return &Provider{
+        cfg:    cfg,
+        client: &http.Client{Timeout: 10 * time.Second},
+    }, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if p.meta != nil {
+        return p.meta, nil
+    }
+    
+    var meta discovery
+    if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+        return nil, fmt.Errorf("oidc: discovery: %w", err)
+    }
+    // The document must describe the issuer we were configured with,
+    // otherwise tokens could be accepted from someone else
+    if strings.TrimRight(meta.Issuer, "/") != p.cfg.Issuer {
+        return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", meta.Issuer, p.cfg.Issuer)
+    }
+    if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+        return nil, errors.New("oidc: discovery document is missing endpoints")
+    }
+    p.meta = &meta
+    p.keys = newKeySet(meta.JWKSURI, p.getJSON)
+    return p.meta, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Accept", "application/json")
+    resp, err := p.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("GET %s: %s", url, resp.Status)
+    }
+    return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// AuthRequest holds the per-login secrets that must survive the round trip
+// through the provider. The caller keeps it (e.g. in a signed cookie) and
+// hands it back to Exchange.
+type AuthRequest struct {
+    State        string `json:"state"`
+    Nonce        string `json:"nonce"`
+    CodeVerifier string `json:"code_verifier"`
+}
+
+func randomString() (string, error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func NewAuthRequest() (*AuthRequest, error) {
+    var req AuthRequest
+    for _, field := range []*string{&req.State, &req.Nonce, &req.CodeVerifier} {
+        value, err := randomString()
+        if err != nil {
+            return nil, err
+        }
+        *field = value
+    }
+    return &req, nil
+}
+
+// AuthCodeURL is where to send the browser to sign in.
+func (p *Provider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+    meta, err := p.discover(ctx)
+    if err != nil {
+        return "", err
+    }
+    challenge := sha256.Sum256([]byte(req.CodeVerifier))
+    query := url.Values{
+        "response_type":         {"code"},
+        "client_id":             {p.cfg.ClientID},
+        "redirect_uri":          {p.cfg.RedirectURL},
+        "scope":                 {strings.Join(p.cfg.Scopes, " ")},
+        "state":                 {req.State},
+        "nonce":                 {req.Nonce},
+        "code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+        "code_challenge_method": {"S256"},
+    }
+    sep := "?"
+    if strings.Contains(meta.AuthorizationEndpoint, "?") {
+        sep = "&"
+    }
+    return meta.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the
+// validated ID token claims.
+func (p *Provider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Claims, error) {
+    meta, err := p.discover(ctx)
+    if err != nil {
+        return nil, err
+    }
+    
+    form := url.Values{
+        "grant_type":    {"authorization_code"},
+        "code":          {code},
+        "redirect_uri":  {p.cfg.RedirectURL},
+        "code_verifier": {req.CodeVerifier},
+    }
+    if p.cfg.ClientSecret == "" {
+        form.Set("client_id", p.cfg.ClientID)
+    }
+    httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    httpReq.Header.Set("Accept", "application/json")
+    if p.cfg.ClientSecret != "" {
+        // client_secret_basic, the default authentication method (RFC 6749 2.3.1)
+        httpReq.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+    }
+    
+    resp, err := p.client.Do(httpReq)
+    if err != nil {
+        return nil, fmt.Errorf("oidc: token request: %w", err)
+    }
+    defer resp.Body.Close()
+    
+    var token struct {
+        IDToken          string `json:"id_token"`
+        Error            string `json:"error"`
+        ErrorDescription string `json:"error_description"`
+    }
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+        return nil, fmt.Errorf("oidc: token response: %s: %w", resp.Status, err)
+    }
+    if resp.StatusCode != http.StatusOK || token.Error != "" {
+        return nil, fmt.Errorf("oidc: token request: %s %s %s", resp.Status, token.Error, token.ErrorDescription)
+    }
+    if token.IDToken == "" {
+        return nil, errors.New("oidc: token response has no id_token")
+    }
+    
+    return p.verifyIDToken(ctx, token.IDToken, req.Nonce)
+}
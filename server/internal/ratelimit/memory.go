@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+type entry struct {
+    failures     int
+    lastFailure  time.Time
+    blockedUntil time.Time
+}
+
+// MemoryLimiter keeps attempts in process memory. It is enough for a single
+// instance; use PostgresLimiter when several instances share the load.
+type MemoryLimiter struct {
+    mu      sync.Mutex
+    policy  Policy
+    entries map[string]*entry
+    now     func() time.Time
+    lastGC  time.Time
+}
+
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+    return &MemoryLimiter{
+        policy:  policy,
+        entries: make(map[string]*entry),
+        now:     time.Now,
+    }
+}
+
+func (l *MemoryLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    
+    e, ok := l.entries[key]
+    if !ok {
+        return 0, nil
+    }
+    return remaining(e.blockedUntil, l.now()), nil
+}
+
+func (l *MemoryLimiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    
+    now := l.now()
+    l.gc(now)
+    
+    e, ok := l.entries[key]
+    if !ok || now.Sub(e.lastFailure) > l.policy.Window {
+        e = &entry{}
+        l.entries[key] = e
+    }
+    e.failures++
+    e.lastFailure = now
+    if delay := l.policy.Delay(e.failures); delay > 0 {
+        e.blockedUntil = now.Add(delay)
+    }
+    return remaining(e.blockedUntil, now), nil
+}
+
+func (l *MemoryLimiter) Reset(ctx context.Context, key string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    
+    delete(l.entries, key)
+    return nil
+}
+
+// gc drops forgotten entries at most once per window so the map can't grow
+// without bound under a spray of keys.
+func (l *MemoryLimiter) gc(now time.Time) {
+    if now.Sub(l.lastGC) < l.policy.Window {
+        return
+    }
+    l.lastGC = now
+    for key, e := range l.entries {
+        if now.Sub(e.lastFailure) > l.policy.Window && !e.blockedUntil.After(now) {
+            delete(l.entries, key)
+        }
+    }
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+)
+
+// PostgresLimiter keeps attempts in the login_attempts table so every
+// instance behind a load balancer sees the same counts. Limiters with
+// different policies share the table through distinct prefixes.
+type PostgresLimiter struct {
+    queries *database.Queries
+    prefix  string
+    policy  Policy
+}
+
+func NewPostgresLimiter(queries *database.Queries, prefix string, policy Policy) *PostgresLimiter {
+    return &PostgresLimiter{queries: queries, prefix: prefix + ":", policy: policy}
+}
+
+func (l *PostgresLimiter) Check(ctx context.Context, key string) (time.Duration, error) {
+    seconds, err := l.queries.GetLoginAttemptBlock(ctx, l.prefix+key)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return 0, nil
+        }
+        return 0, err
+    }
+    return toDuration(seconds), nil
+}
+
+func (l *PostgresLimiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+    failures, err := l.queries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+        Key:           l.prefix + key,
+        WindowSeconds: l.policy.Window.Seconds(),
+    })
+    if err != nil {
+        return 0, err
+    }
+    
+    delay := l.policy.Delay(int(failures))
+    if delay == 0 {
+        return 0, nil
+    }
+    err = l.queries.SetLoginAttemptBlock(ctx, database.SetLoginAttemptBlockParams{
+        Seconds: delay.Seconds(),
+        Key:     l.prefix + key,
+    })
+    if err != nil {
+        return 0, err
+    }
+    return delay, nil
+}
+
+func (l *PostgresLimiter) Reset(ctx context.Context, key string) error {
+    return l.queries.ResetLoginAttempts(ctx, l.prefix+key)
+}
+
+// Cleanup deletes keys whose failures have been forgotten.
+func (l *PostgresLimiter) Cleanup(ctx context.Context) error {
+    return l.queries.CleanupLoginAttempts(ctx, database.CleanupLoginAttemptsParams{
+        Prefix:        l.prefix,
+        WindowSeconds: l.policy.Window.Seconds(),
+    })
+}
+
+func toDuration(seconds float64) time.Duration {
+    if seconds <= 0 {
+        return 0
+    }
+    return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+    "context"
+    "time"
+)
+
+// Limiter tracks failed attempts per key (an IP, an account, ...) and
+// decides how long the key has to wait before trying again.
+type Limiter interface {
+    // Check returns how long key is still blocked, or zero if it may try now.
+    Check(ctx context.Context, key string) (time.Duration, error)
+    // Fail records a failed attempt and returns the block it caused, if any.
+    Fail(ctx context.Context, key string) (time.Duration, error)
+    // Reset forgets the failures of key after a successful attempt.
+    Reset(ctx context.Context, key string) error
+}
+
+// Policy describes the backoff for one kind of key. The first FreeAttempts
+// failures cost nothing; after that each failure blocks the key for
+// BaseDelay, doubling every time up to MaxDelay. Reaching LockoutAfter
+// failures locks the key for LockoutDuration. Failures older than Window
+// are forgotten.
+type Policy struct {
+    FreeAttempts    int
+    BaseDelay       time.Duration
+    MaxDelay        time.Duration
+    LockoutAfter    int
+    LockoutDuration time.Duration
+    Window          time.Duration
+}
+
+// Delay is the block that follows the given number of consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+    if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+        return p.LockoutDuration
+    }
+    over := failures - p.FreeAttempts
+    if over <= 0 {
+        return 0
+    }
+    delay := p.BaseDelay
+    for i := 1; i < over; i++ {
+        delay *= 2
+        if delay >= p.MaxDelay {
+            return p.MaxDelay
+        }
+    }
+    return min(delay, p.MaxDelay)
+}
+
+// remaining is how much of a block ending at until is left at now.
+func remaining(until, now time.Time) time.Duration {
+    if until.After(now) {
+        return until.Sub(now)
+    }
+    return 0
+}
@@ -0,0 +1,65 @@
+package rates
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+)
+
+type RateResponse struct {
+    Base  string `json:"base"`
+    Quote string `json:"quote"`
+    Rate  string `json:"rate"`
+    Date  string `json:"date"`
+}
+
+// HandleGetRates lists the latest known rates for a base currency, which
+// defaults to the caller's own base currency.
+func (s *Service) HandleGetRates(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+
+    base := user.BaseCurrency
+    if b := r.URL.Query().Get("base"); b != "" {
+        parsed, err := money.ParseCurrency(b)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid base currency")
+            return
+        }
+        base = parsed
+    }
+
+    asOf := time.Now()
+    if d := r.URL.Query().Get("date"); d != "" {
+        parsed, err := time.Parse("2006-01-02", d)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
+            return
+        }
+        asOf = parsed
+    }
+
+    rates, err := s.GetRatesByBase(r.Context(), base, asOf)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get exchange rates")
+        return
+    }
+
+    response := make([]RateResponse, len(rates))
+    for i, rate := range rates {
+        response[i] = RateResponse{
+            Base:  rate.BaseCurrency,
+            Quote: rate.QuoteCurrency,
+            Rate:  rate.Rate,
+            Date:  rate.RateDate.Format("2006-01-02"),
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,160 @@
+package rates
+
+import (
+    "encoding/csv"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/money"
+)
+
+// ECB reference rates are quoted against the euro.
+const ecbBase = "EUR"
+
+// ParseCSV accepts either a long file with date, base, quote and rate
+// columns, or the ECB wide layout (Date, USD, JPY, ...) quoted in EUR.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("reading CSV header: %w", err)
+    }
+
+    columns := make(map[string]int, len(header))
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+
+    _, hasBase := columns["base"]
+    _, hasQuote := columns["quote"]
+    _, hasRate := columns["rate"]
+    if hasBase && hasQuote && hasRate {
+        return parseLongCSV(reader, columns)
+    }
+    return parseWideCSV(reader, header)
+}
+
+func parseLongCSV(reader *csv.Reader, columns map[string]int) ([]Rate, error) {
+    dateCol, ok := columns["date"]
+    if !ok {
+        return nil, fmt.Errorf("CSV is missing a date column")
+    }
+
+    var rates []Rate
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %w", line, err)
+        }
+
+        rate, err := newRate(
+            field(record, columns["base"]),
+            field(record, columns["quote"]),
+            field(record, dateCol),
+            field(record, columns["rate"]),
+        )
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %w", line, err)
+        }
+        rates = append(rates, rate)
+    }
+    return rates, nil
+}
+
+func parseWideCSV(reader *csv.Reader, header []string) ([]Rate, error) {
+    if len(header) < 2 || !strings.EqualFold(strings.TrimSpace(header[0]), "date") {
+        return nil, fmt.Errorf("CSV must have base/quote/rate/date columns or start with a Date column")
+    }
+
+    var rates []Rate
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %w", line, err)
+        }
+
+        for i := 1; i < len(header) && i < len(record); i++ {
+            quote := strings.TrimSpace(header[i])
+            value := strings.TrimSpace(record[i])
+            // ECB leaves a trailing empty column and marks gaps with N/A
+            if quote == "" || value == "" || strings.EqualFold(value, "N/A") {
+                continue
+            }
+            rate, err := newRate(ecbBase, quote, record[0], value)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %w", line, err)
+            }
+            rates = append(rates, rate)
+        }
+    }
+    return rates, nil
+}
+
+type ecbEnvelope struct {
+    Days []struct {
+        Time  string `xml:"time,attr"`
+        Rates []struct {
+            Currency string `xml:"currency,attr"`
+            Rate     string `xml:"rate,attr"`
+        } `xml:"Cube"`
+    } `xml:"Cube>Cube"`
+}
+
+// ParseECBXML reads the eurofxref daily or historical XML feeds.
+func ParseECBXML(r io.Reader) ([]Rate, error) {
+    var envelope ecbEnvelope
+    if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+        return nil, fmt.Errorf("decoding ECB XML: %w", err)
+    }
+
+    var rates []Rate
+    for _, day := range envelope.Days {
+        for _, quote := range day.Rates {
+            rate, err := newRate(ecbBase, quote.Currency, day.Time, quote.Rate)
+            if err != nil {
+                return nil, fmt.Errorf("%s %s: %w", day.Time, quote.Currency, err)
+            }
+            rates = append(rates, rate)
+        }
+    }
+    return rates, nil
+}
+
+func newRate(base, quote, date, value string) (Rate, error) {
+    base, err := money.ParseCurrency(base)
+    if err != nil {
+        return Rate{}, err
+    }
+    quote, err = money.ParseCurrency(quote)
+    if err != nil {
+        return Rate{}, err
+    }
+    day, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+    if err != nil {
+        return Rate{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+    }
+    value = strings.TrimSpace(value)
+    if _, err := money.ParseRate(value); err != nil {
+        return Rate{}, err
+    }
+    return Rate{Base: base, Quote: quote, Date: day, Rate: value}, nil
+}
+
+func field(record []string, i int) string {
+    if i < len(record) {
+        return record[i]
+    }
+    return ""
+}
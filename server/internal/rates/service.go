@@ -0,0 +1,177 @@
+package rates
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "math/big"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// Rate is one quotation: 1 unit of Base buys Rate units of Quote on Date.
+type Rate struct {
+    Base  string
+    Quote string
+    Date  time.Time
+    Rate  string
+}
+
+type Service struct {
+    db      *sql.DB
+    queries *database.Queries
+}
+
+func NewService(db *sql.DB, queries *database.Queries) *Service {
+    return &Service{db: db, queries: queries}
+}
+
+// Import upserts rates in a single transaction and returns how many were written.
+func (s *Service) Import(ctx context.Context, rates []Rate) (int, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    qtx := s.queries.WithTx(tx)
+    for _, r := range rates {
+        err := qtx.UpsertExchangeRate(ctx, database.UpsertExchangeRateParams{
+            BaseCurrency:  r.Base,
+            QuoteCurrency: r.Quote,
+            RateDate:      r.Date,
+            Rate:          r.Rate,
+        })
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return len(rates), nil
+}
+
+// ImportFile loads an ECB-style XML file (.xml) or a CSV file.
+func (s *Service) ImportFile(ctx context.Context, path string) (int, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer f.Close()
+
+    var rates []Rate
+    if strings.EqualFold(filepath.Ext(path), ".xml") {
+        rates, err = ParseECBXML(f)
+    } else {
+        rates, err = ParseCSV(f)
+    }
+    if err != nil {
+        return 0, err
+    }
+    return s.Import(ctx, rates)
+}
+
+func (s *Service) GetRatesByBase(ctx context.Context, base string, asOf time.Time) ([]database.ExchangeRate, error) {
+    return s.queries.GetExchangeRatesByBase(ctx, database.GetExchangeRatesByBaseParams{
+        BaseCurrency: base,
+        RateDate:     asOf,
+    })
+}
+
+// Lookup finds how many units of to one unit of from buys on the given day,
+// using the latest quotation on or before it. Direct, inverse and
+// common-base cross rates (e.g. USD->GBP via EUR) are all supported.
+func (s *Service) Lookup(ctx context.Context, from, to string, on time.Time) (*big.Rat, error) {
+    if from == to {
+        return big.NewRat(1, 1), nil
+    }
+
+    rows, err := s.queries.GetLatestExchangeRates(ctx, database.GetLatestExchangeRatesParams{
+        AsOf:         on,
+        FromCurrency: from,
+        ToCurrency:   to,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    quotes := make(map[[2]string]*big.Rat, len(rows))
+    for _, row := range rows {
+        rate, err := money.ParseRate(row.Rate)
+        if err != nil {
+            return nil, err
+        }
+        quotes[[2]string{row.BaseCurrency, row.QuoteCurrency}] = rate
+    }
+
+    if rate, ok := quotes[[2]string{from, to}]; ok {
+        return rate, nil
+    }
+    if rate, ok := quotes[[2]string{to, from}]; ok {
+        return new(big.Rat).Inv(rate), nil
+    }
+
+    // Cross through a shared currency, whichever side of the pair it sits on.
+    for pair, fromRate := range quotes {
+        var pivot string
+        switch {
+        case pair[1] == from:
+            pivot = pair[0] // pivot -> from
+        case pair[0] == from:
+            pivot = pair[1] // from -> pivot
+            fromRate = new(big.Rat).Inv(fromRate)
+        default:
+            continue
+        }
+        if toRate, ok := quotes[[2]string{pivot, to}]; ok {
+            return new(big.Rat).Quo(toRate, fromRate), nil
+        }
+        if toRate, ok := quotes[[2]string{to, pivot}]; ok {
+            return new(big.Rat).Quo(new(big.Rat).Inv(toRate), fromRate), nil
+        }
+    }
+
+    return nil, ErrRateNotFound
+}
+
+// Converter caches lookups for the lifetime of one request or report.
+type Converter struct {
+    service *Service
+    cache   map[converterKey]*big.Rat
+}
+
+type converterKey struct {
+    from, to string
+    day      string
+}
+
+func (s *Service) NewConverter() *Converter {
+    return &Converter{service: s, cache: make(map[converterKey]*big.Rat)}
+}
+
+func (c *Converter) Convert(ctx context.Context, amount money.Amount, from, to string, on time.Time) (money.Amount, error) {
+    if from == to {
+        return amount, nil
+    }
+
+    key := converterKey{from: from, to: to, day: on.Format("2006-01-02")}
+    rate, ok := c.cache[key]
+    if !ok {
+        var err error
+        rate, err = c.service.Lookup(ctx, from, to, on)
+        if err != nil {
+            return 0, err
+        }
+        c.cache[key] = rate
+    }
+    return amount.Convert(rate)
+}
@@ -0,0 +1,428 @@
+package recurring
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+// RecurringExpenseRequest is used for both create and update. On update,
+// StartDate is when the edited schedule takes effect; leaving it empty
+// keeps the current rhythm from the next pending occurrence.
+type RecurringExpenseRequest struct {
+    CategoryID  *string      `json:"category_id"`
+    Amount      money.Amount `json:"amount" validate:"required"` // "12.34" or integer cents
+    Currency    string       `json:"currency"`
+    Description string       `json:"description" validate:"required"`
+    Frequency   string       `json:"frequency" validate:"required"` // daily, weekly, monthly or yearly
+    Interval    int          `json:"interval"` // every N periods, defaults to 1
+    StartDate   string       `json:"start_date"` // YYYY-MM-DD
+    EndDate     *string      `json:"end_date"` // YYYY-MM-DD, inclusive
+    Count       *int         `json:"count"` // total number of occurrences
+}
+
+type SkipOccurrenceRequest struct {
+    Date string `json:"date" validate:"required"` // YYYY-MM-DD
+}
+
+type RecurringExpenseResponse struct {
+    ID          string       `json:"id"`
+    CategoryID  *string      `json:"category_id"`
+    Amount      money.Amount `json:"amount"`
+    Currency    string       `json:"currency"`
+    Description string       `json:"description"`
+    Frequency   string       `json:"frequency"`
+    Interval    int32        `json:"interval"`
+    StartDate   string       `json:"start_date"`
+    EndDate     *string      `json:"end_date"`
+    Count       *int32       `json:"count"`
+    Occurrences int32        `json:"occurrences"` // occurrences already passed, including skipped
+    NextDate    *string      `json:"next_date"`
+    Paused      bool         `json:"paused"`
+    Upcoming    []string     `json:"upcoming,omitempty"`
+    Skipped     []string     `json:"skipped,omitempty"`
+    CreatedAt   string       `json:"created_at"`
+    UpdatedAt   string       `json:"updated_at"`
+}
+
+const upcomingPreview = 5
+
+func (s *Service) HandleCreateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    var req RecurringExpenseRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+
+    template, _, err := parseTemplate(req, user.BaseCurrency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    rec, err := s.CreateRecurringExpense(r.Context(), ledger.ID, user.ID, template)
+    if err != nil {
+        if errors.Is(err, ErrInvalidSchedule) {
+            utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        if errors.Is(err, ErrCategoryNotFound) {
+            utils.RespondWithError(w, http.StatusBadRequest, "Category not found")
+            return
+        }
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create recurring expense")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusCreated, toResponse(rec))
+}
+
+func (s *Service) HandleGetRecurringExpenses(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    recs, err := s.GetLedgerRecurringExpenses(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get recurring expenses")
+        return
+    }
+
+    response := make([]RecurringExpenseResponse, len(recs))
+    for i := range recs {
+        response[i] = toResponse(&recs[i])
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleGetRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    id, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid recurring expense ID")
+        return
+    }
+
+    rec, err := s.GetRecurringExpense(r.Context(), id, ledger.ID)
+    if err != nil {
+        respondWithServiceError(w, err, "Failed to get recurring expense")
+        return
+    }
+
+    skips, err := s.GetSkips(r.Context(), rec)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get recurring expense")
+        return
+    }
+
+    response := toResponse(rec)
+    skipped := make(map[string]bool, len(skips))
+    response.Skipped = make([]string, len(skips))
+    for i, d := range skips {
+        response.Skipped[i] = d.Format("2006-01-02")
+        skipped[response.Skipped[i]] = true
+    }
+
+    // Preview the next few dates that will actually produce an expense
+    schedule := scheduleOf(*rec)
+    response.Upcoming = []string{}
+    for n := int(rec.NextIndex); len(response.Upcoming) < upcomingPreview; n++ {
+        next := schedule.Next(n)
+        if next == nil {
+            break
+        }
+        if date := next.Format("2006-01-02"); !skipped[date] {
+            response.Upcoming = append(response.Upcoming, date)
+        }
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleUpdateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    id, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid recurring expense ID")
+        return
+    }
+
+    var req RecurringExpenseRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+
+    template, keepStart, err := parseTemplate(req, user.BaseCurrency)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    rec, err := s.UpdateRecurringExpense(r.Context(), id, ledger.ID, template, keepStart)
+    if err != nil {
+        if errors.Is(err, ErrInvalidSchedule) {
+            utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        if errors.Is(err, ErrCategoryNotFound) {
+            utils.RespondWithError(w, http.StatusBadRequest, "Category not found")
+            return
+        }
+        respondWithServiceError(w, err, "Failed to update recurring expense")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, toResponse(rec))
+}
+
+func (s *Service) HandlePauseRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    s.handleSetPaused(w, r, true)
+}
+
+func (s *Service) HandleResumeRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    s.handleSetPaused(w, r, false)
+}
+
+func (s *Service) handleSetPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    id, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid recurring expense ID")
+        return
+    }
+
+    var rec *database.RecurringExpense
+    if paused {
+        rec, err = s.PauseRecurringExpense(r.Context(), id, ledger.ID)
+    } else {
+        rec, err = s.ResumeRecurringExpense(r.Context(), id, ledger.ID)
+    }
+    if err != nil {
+        respondWithServiceError(w, err, "Failed to update recurring expense")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, toResponse(rec))
+}
+
+func (s *Service) HandleSkipOccurrence(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    id, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid recurring expense ID")
+        return
+    }
+
+    var req SkipOccurrenceRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    date, err := time.Parse("2006-01-02", req.Date)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
+        return
+    }
+
+    if err := s.SkipOccurrence(r.Context(), id, ledger.ID, date); err != nil {
+        if errors.Is(err, ErrNotAnOccurrence) {
+            utils.RespondWithError(w, http.StatusBadRequest, "Date is not an upcoming occurrence of this schedule")
+            return
+        }
+        respondWithServiceError(w, err, "Failed to skip occurrence")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Occurrence skipped",
+    })
+}
+
+func (s *Service) HandleDeleteRecurringExpense(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+
+    id, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid recurring expense ID")
+        return
+    }
+
+    if err := s.DeleteRecurringExpense(r.Context(), id, ledger.ID); err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete recurring expense")
+        return
+    }
+
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Recurring expense deleted successfully",
+    })
+}
+
+// parseTemplate validates a request. The returned flag is true when no
+// start date was given.
+func parseTemplate(req RecurringExpenseRequest, baseCurrency string) (Template, bool, error) {
+    if err := utils.ValidateStruct(req); err != nil {
+        return Template{}, false, err
+    }
+
+    if !req.Amount.IsPositive() {
+        return Template{}, false, errors.New("Amount must be greater than 0")
+    }
+    if !req.Amount.Storable() {
+        return Template{}, false, errors.New("Amount must be at most " + money.MaxStored.String())
+    }
+
+    t := Template{
+        Amount:      req.Amount,
+        Currency:    baseCurrency,
+        Description: req.Description,
+        Interval:    req.Interval,
+        Count:       req.Count,
+    }
+
+    if req.Currency != "" {
+        currency, err := money.ParseCurrency(req.Currency)
+        if err != nil {
+            return Template{}, false, errors.New("Invalid currency, use a three-letter ISO 4217 code")
+        }
+        t.Currency = currency
+    }
+
+    frequency, err := ParseFrequency(req.Frequency)
+    if err != nil {
+        return Template{}, false, errors.New("Frequency must be one of daily, weekly, monthly or yearly")
+    }
+    t.Frequency = frequency
+
+    if t.Interval == 0 {
+        t.Interval = 1
+    }
+
+    keepStart := req.StartDate == ""
+    if keepStart {
+        t.StartDate = today()
+    } else {
+        t.StartDate, err = time.Parse("2006-01-02", req.StartDate)
+        if err != nil {
+            return Template{}, false, errors.New("Invalid start_date format, use YYYY-MM-DD")
+        }
+    }
+
+    if req.EndDate != nil && *req.EndDate != "" {
+        endDate, err := time.Parse("2006-01-02", *req.EndDate)
+        if err != nil {
+            return Template{}, false, errors.New("Invalid end_date format, use YYYY-MM-DD")
+        }
+        t.EndDate = &endDate
+    }
+
+    if req.CategoryID != nil && *req.CategoryID != "" {
+        categoryID, err := uuid.Parse(*req.CategoryID)
+        if err != nil {
+            return Template{}, false, errors.New("Invalid category ID")
+        }
+        t.CategoryID = &categoryID
+    }
+
+    return t, keepStart, nil
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error, message string) {
+    if errors.Is(err, ErrNotFound) {
+        utils.RespondWithError(w, http.StatusNotFound, "Recurring expense not found")
+        return
+    }
+    utils.RespondWithError(w, http.StatusInternalServerError, message)
+}
+
+func toResponse(rec *database.RecurringExpense) RecurringExpenseResponse {
+    response := RecurringExpenseResponse{
+        ID:          rec.ID.String(),
+        Amount:      rec.Amount,
+        Currency:    rec.Currency,
+        Description: rec.Description,
+        Frequency:   rec.Frequency,
+        Interval:    rec.IntervalCount,
+        StartDate:   rec.StartDate.Format("2006-01-02"),
+        Occurrences: rec.NextIndex,
+        Paused:      rec.Paused,
+        CreatedAt:   rec.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        UpdatedAt:   rec.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+
+    if rec.CategoryID.Valid {
+        categoryID := rec.CategoryID.UUID.String()
+        response.CategoryID = &categoryID
+    }
+    if rec.EndDate.Valid {
+        endDate := rec.EndDate.Time.Format("2006-01-02")
+        response.EndDate = &endDate
+    }
+    if rec.OccurrenceLimit.Valid {
+        count := rec.OccurrenceLimit.Int32
+        response.Count = &count
+    }
+    if rec.NextDate.Valid {
+        nextDate := rec.NextDate.Time.Format("2006-01-02")
+        response.NextDate = &nextDate
+    }
+
+    return response
+}
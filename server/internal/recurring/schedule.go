@@ -0,0 +1,153 @@
+package recurring
+
+import (
+    "errors"
+    "fmt"
+    "time"
+)
+
+type Frequency string
+
+const (
+    Daily   Frequency = "daily"
+    Weekly  Frequency = "weekly"
+    Monthly Frequency = "monthly"
+    Yearly  Frequency = "yearly"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// maxInterval keeps occurrences at most about a year apart for daily and
+// weekly schedules and ten years apart for monthly and yearly ones, so
+// every occurrence date stays computable.
+var maxInterval = map[Frequency]int{
+    Daily:   366,
+    Weekly:  52,
+    Monthly: 120,
+    Yearly:  10,
+}
+
+// maxCount bounds COUNT, which is stored as a 32-bit integer.
+const maxCount = 10000
+
+func ParseFrequency(s string) (Frequency, error) {
+    switch f := Frequency(s); f {
+    case Daily, Weekly, Monthly, Yearly:
+        return f, nil
+    }
+    return "", ErrInvalidSchedule
+}
+
+// Schedule is a small RRULE subset: FREQ, INTERVAL, UNTIL and COUNT.
+// Occurrences are numbered from zero; occurrence n falls on
+// Anchor + (n - AnchorIndex) * Interval periods.
+type Schedule struct {
+    Frequency   Frequency
+    Interval    int
+    Anchor      time.Time
+    AnchorIndex int
+    Until       *time.Time // inclusive
+    Count       *int       // total occurrences, including skipped ones
+}
+
+// Occurrence returns the date of occurrence n. Monthly and yearly
+// schedules anchored on a day the target month lacks (e.g. the 31st) fall
+// on that month's last day instead of spilling into the next month.
+func (s Schedule) Occurrence(n int) time.Time {
+    steps := (n - s.AnchorIndex) * s.Interval
+    switch s.Frequency {
+    case Daily:
+        return s.Anchor.AddDate(0, 0, steps)
+    case Weekly:
+        return s.Anchor.AddDate(0, 0, 7*steps)
+    case Monthly:
+        return addMonthsClamped(s.Anchor, steps)
+    case Yearly:
+        return addMonthsClamped(s.Anchor, 12*steps)
+    }
+    return s.Anchor
+}
+
+// Finished reports whether occurrence n lies beyond COUNT or UNTIL.
+func (s Schedule) Finished(n int) bool {
+    if s.Count != nil && n >= *s.Count {
+        return true
+    }
+    if s.Until != nil && s.Occurrence(n).After(*s.Until) {
+        return true
+    }
+    return false
+}
+
+// Next returns the date of occurrence n, or nil once the schedule is over.
+func (s Schedule) Next(n int) *time.Time {
+    if s.Finished(n) {
+        return nil
+    }
+    date := s.Occurrence(n)
+    return &date
+}
+
+// IndexOf finds which occurrence falls on date, searching from occurrence
+// `from`. It returns false if date is not an occurrence.
+func (s Schedule) IndexOf(date time.Time, from int) (int, bool) {
+    for n := from; !s.Finished(n); n++ {
+        occurrence := s.Occurrence(n)
+        if occurrence.Equal(date) {
+            return n, true
+        }
+        if occurrence.After(date) {
+            return 0, false
+        }
+    }
+    return 0, false
+}
+
+// Upcoming lists up to limit occurrence dates starting at occurrence n.
+func (s Schedule) Upcoming(n, limit int) []time.Time {
+    dates := []time.Time{}
+    for ; len(dates) < limit && !s.Finished(n); n++ {
+        dates = append(dates, s.Occurrence(n))
+    }
+    return dates
+}
+
+func (s Schedule) Validate() error {
+    if _, err := ParseFrequency(string(s.Frequency)); err != nil {
+        return fmt.Errorf("%w: frequency must be one of daily, weekly, monthly or yearly", ErrInvalidSchedule)
+    }
+    if s.Interval < 1 {
+        return fmt.Errorf("%w: interval must be at least 1", ErrInvalidSchedule)
+    }
+    if limit := maxInterval[s.Frequency]; s.Interval > limit {
+        return fmt.Errorf("%w: interval can be at most %d for a %s schedule", ErrInvalidSchedule, limit, s.Frequency)
+    }
+    if s.Count != nil && *s.Count < 1 {
+        return fmt.Errorf("%w: count must be at least 1", ErrInvalidSchedule)
+    }
+    if s.Count != nil && *s.Count > maxCount {
+        return fmt.Errorf("%w: count can be at most %d", ErrInvalidSchedule, maxCount)
+    }
+    if s.Until != nil && s.Until.Before(s.Anchor) {
+        return fmt.Errorf("%w: end_date must not be before start_date", ErrInvalidSchedule)
+    }
+    return nil
+}
+
+func addMonthsClamped(t time.Time, months int) time.Time {
+    year, month, day := t.Date()
+    total := int(month) - 1 + months
+    year += total / 12
+    total %= 12
+    if total < 0 {
+        total += 12
+        year--
+    }
+    target := time.Month(total + 1)
+
+    lastDay := time.Date(year, target+1, 0, 0, 0, 0, 0, t.Location()).Day()
+    if day > lastDay {
+        day = lastDay
+    }
+    return time.Date(year, target, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,163 @@
+package recurring
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+    return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func intPtr(n int) *int { return &n }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestOccurrence(t *testing.T) {
+    tests := []struct {
+        name     string
+        schedule Schedule
+        n        int
+        want     time.Time
+    }{
+        {"daily", Schedule{Frequency: Daily, Interval: 3, Anchor: date(2024, 12, 30)}, 1, date(2025, 1, 2)},
+        {"weekly", Schedule{Frequency: Weekly, Interval: 2, Anchor: date(2024, 1, 1)}, 2, date(2024, 1, 29)},
+        {"monthly clamps to february", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2023, 1, 31)}, 1, date(2023, 2, 28)},
+        {"monthly clamps in leap year", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 1, 31)}, 1, date(2024, 2, 29)},
+        {"monthly returns to the 31st", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2023, 1, 31)}, 2, date(2023, 3, 31)},
+        {"monthly clamps to the 30th", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2023, 1, 31)}, 3, date(2023, 4, 30)},
+        {"monthly crosses the year", Schedule{Frequency: Monthly, Interval: 5, Anchor: date(2023, 10, 31)}, 1, date(2024, 3, 31)},
+        {"monthly before the anchor", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 3, 31), AnchorIndex: 2}, 1, date(2024, 2, 29)},
+        {"monthly before the anchor crosses the year", Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 1, 31), AnchorIndex: 1}, 0, date(2023, 12, 31)},
+        {"yearly leap day", Schedule{Frequency: Yearly, Interval: 1, Anchor: date(2024, 2, 29)}, 1, date(2025, 2, 28)},
+        {"yearly leap day returns", Schedule{Frequency: Yearly, Interval: 1, Anchor: date(2024, 2, 29)}, 4, date(2028, 2, 29)},
+        {"anchor index offset", Schedule{Frequency: Daily, Interval: 1, Anchor: date(2024, 5, 10), AnchorIndex: 4}, 6, date(2024, 5, 12)},
+    }
+
+    for _, tt := range tests {
+        if got := tt.schedule.Occurrence(tt.n); !got.Equal(tt.want) {
+            t.Errorf("%s: Occurrence(%d) = %s, want %s", tt.name, tt.n, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+        }
+    }
+}
+
+func TestNext(t *testing.T) {
+    monthly := Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 1, 31)}
+
+    tests := []struct {
+        name     string
+        schedule Schedule
+        n        int
+        want     *time.Time
+    }{
+        {"open ended", monthly, 1, timePtr(date(2024, 2, 29))},
+        {"within count", withCount(monthly, 3), 2, timePtr(date(2024, 3, 31))},
+        {"count reached", withCount(monthly, 3), 3, nil},
+        {"until is inclusive", withUntil(monthly, date(2024, 2, 29)), 1, timePtr(date(2024, 2, 29))},
+        {"after until", withUntil(monthly, date(2024, 3, 30)), 2, nil},
+    }
+
+    for _, tt := range tests {
+        got := tt.schedule.Next(tt.n)
+        switch {
+        case got == nil && tt.want == nil:
+        case got == nil || tt.want == nil:
+            t.Errorf("%s: Next(%d) = %v, want %v", tt.name, tt.n, got, tt.want)
+        case !got.Equal(*tt.want):
+            t.Errorf("%s: Next(%d) = %s, want %s", tt.name, tt.n, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+        }
+    }
+}
+
+func TestIndexOf(t *testing.T) {
+    monthly := Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2023, 1, 31)}
+
+    tests := []struct {
+        name     string
+        schedule Schedule
+        date     time.Time
+        from     int
+        want     int
+        wantOK   bool
+    }{
+        {"anchor", monthly, date(2023, 1, 31), 0, 0, true},
+        {"clamped month end", monthly, date(2023, 2, 28), 0, 1, true},
+        {"unclamped day in a short month", monthly, date(2023, 3, 28), 0, 0, false},
+        {"month end after a clamp", monthly, date(2023, 3, 31), 0, 2, true},
+        {"thirtieth", monthly, date(2023, 4, 30), 0, 3, true},
+        {"search starts later", monthly, date(2023, 2, 28), 2, 0, false},
+        {"before the anchor", monthly, date(2022, 12, 31), 0, 0, false},
+        {"past the count", withCount(monthly, 2), date(2023, 3, 31), 0, 0, false},
+        {"weekly", Schedule{Frequency: Weekly, Interval: 1, Anchor: date(2024, 1, 1)}, date(2024, 1, 15), 0, 2, true},
+        {"weekly off day", Schedule{Frequency: Weekly, Interval: 1, Anchor: date(2024, 1, 1)}, date(2024, 1, 16), 0, 0, false},
+    }
+
+    for _, tt := range tests {
+        got, ok := tt.schedule.IndexOf(tt.date, tt.from)
+        if ok != tt.wantOK || got != tt.want {
+            t.Errorf("%s: IndexOf(%s, %d) = %d, %v, want %d, %v", tt.name, tt.date.Format("2006-01-02"), tt.from, got, ok, tt.want, tt.wantOK)
+        }
+    }
+}
+
+func TestUpcoming(t *testing.T) {
+    schedule := withCount(Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 1, 31)}, 4)
+    want := []time.Time{date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30)}
+
+    got := schedule.Upcoming(1, 10)
+    if len(got) != len(want) {
+        t.Fatalf("Upcoming(1, 10) returned %d dates, want %d", len(got), len(want))
+    }
+    for i := range want {
+        if !got[i].Equal(want[i]) {
+            t.Errorf("Upcoming(1, 10)[%d] = %s, want %s", i, got[i].Format("2006-01-02"), want[i].Format("2006-01-02"))
+        }
+    }
+}
+
+func TestValidate(t *testing.T) {
+    valid := Schedule{Frequency: Monthly, Interval: 1, Anchor: date(2024, 1, 31)}
+
+    tests := []struct {
+        name     string
+        schedule Schedule
+        wantErr  bool
+    }{
+        {"valid", valid, false},
+        {"unknown frequency", Schedule{Frequency: "hourly", Interval: 1, Anchor: date(2024, 1, 1)}, true},
+        {"zero interval", Schedule{Frequency: Daily, Interval: 0, Anchor: date(2024, 1, 1)}, true},
+        {"largest daily interval", Schedule{Frequency: Daily, Interval: 366, Anchor: date(2024, 1, 1)}, false},
+        {"daily interval too large", Schedule{Frequency: Daily, Interval: 367, Anchor: date(2024, 1, 1)}, true},
+        {"weekly interval too large", Schedule{Frequency: Weekly, Interval: 53, Anchor: date(2024, 1, 1)}, true},
+        {"largest monthly interval", Schedule{Frequency: Monthly, Interval: 120, Anchor: date(2024, 1, 1)}, false},
+        {"monthly interval too large", Schedule{Frequency: Monthly, Interval: 121, Anchor: date(2024, 1, 1)}, true},
+        {"yearly interval too large", Schedule{Frequency: Yearly, Interval: 11, Anchor: date(2024, 1, 1)}, true},
+        {"huge interval", Schedule{Frequency: Daily, Interval: 1 << 40, Anchor: date(2024, 1, 1)}, true},
+        {"zero count", withCount(valid, 0), true},
+        {"largest count", withCount(valid, maxCount), false},
+        {"count too large", withCount(valid, maxCount+1), true},
+        {"until before anchor", withUntil(valid, date(2024, 1, 30)), true},
+        {"until on anchor", withUntil(valid, date(2024, 1, 31)), false},
+    }
+
+    for _, tt := range tests {
+        err := tt.schedule.Validate()
+        if tt.wantErr != (err != nil) {
+            t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+        }
+        if err != nil && !errors.Is(err, ErrInvalidSchedule) {
+            t.Errorf("%s: Validate() = %v, want ErrInvalidSchedule", tt.name, err)
+        }
+    }
+}
+
+func withCount(s Schedule, count int) Schedule {
+    s.Count = intPtr(count)
+    return s
+}
+
+func withUntil(s Schedule, until time.Time) Schedule {
+    s.Until = timePtr(until)
+    return s
+}
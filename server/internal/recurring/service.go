@@ -0,0 +1,474 @@
+package recurring
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/google/uuid"
+)
+
+var (
+    ErrNotFound         = errors.New("recurring expense not found")
+    ErrNotAnOccurrence  = errors.New("date is not an upcoming occurrence")
+    // ErrCategoryNotFound means the category is missing or belongs to
+    // another ledger.
+    ErrCategoryNotFound = errors.New("category not found")
+)
+
+const (
+    // maxBackfillDays bounds how far in the past a schedule may start, so
+    // creating or re-anchoring a template back-fills about a month at most.
+    maxBackfillDays = 31
+    // maxOccurrencesPerRun caps how many occurrences of one template a
+    // single materialize transaction creates. The rest follow on later runs.
+    maxOccurrencesPerRun = 100
+)
+
+// Template describes what each occurrence looks like and when it happens.
+type Template struct {
+    CategoryID  *uuid.UUID
+    Amount      money.Amount
+    Currency    string
+    Description string
+    Frequency   Frequency
+    Interval    int
+    StartDate   time.Time
+    EndDate     *time.Time
+    Count       *int
+}
+
+type Service struct {
+    db      *sql.DB
+    queries *database.Queries
+}
+
+func NewService(db *sql.DB, queries *database.Queries) *Service {
+    return &Service{db: db, queries: queries}
+}
+
+func (s *Service) CreateRecurringExpense(ctx context.Context, ledgerID, userID uuid.UUID, t Template) (*database.RecurringExpense, error) {
+    schedule := Schedule{
+        Frequency: t.Frequency,
+        Interval:  t.Interval,
+        Anchor:    t.StartDate,
+        Until:     t.EndDate,
+        Count:     t.Count,
+    }
+    if err := schedule.Validate(); err != nil {
+        return nil, err
+    }
+    if err := checkStart(t.StartDate); err != nil {
+        return nil, err
+    }
+
+    if err := checkCategory(ctx, s.queries, ledgerID, t.CategoryID); err != nil {
+        return nil, err
+    }
+
+    rec, err := s.queries.CreateRecurringExpense(ctx, database.CreateRecurringExpenseParams{
+        LedgerID:        ledgerID,
+        UserID:          userID,
+        CategoryID:      nullUUID(t.CategoryID),
+        Amount:          t.Amount,
+        Currency:        t.Currency,
+        Description:     t.Description,
+        Frequency:       string(t.Frequency),
+        IntervalCount:   int32(t.Interval),
+        StartDate:       t.StartDate,
+        EndDate:         nullTime(t.EndDate),
+        OccurrenceLimit: nullInt32(t.Count),
+        AnchorDate:      t.StartDate,
+        AnchorIndex:     0,
+        NextIndex:       0,
+        NextDate:        nullTime(schedule.Next(0)),
+    })
+    return &rec, err
+}
+
+// checkCategory makes sure an optional category belongs to the ledger.
+func checkCategory(ctx context.Context, queries *database.Queries, ledgerID uuid.UUID, categoryID *uuid.UUID) error {
+    if categoryID == nil {
+        return nil
+    }
+    _, err := queries.GetCategoryByID(ctx, database.GetCategoryByIDParams{ID: *categoryID, LedgerID: ledgerID})
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrCategoryNotFound
+    }
+    return err
+}
+
+func (s *Service) GetLedgerRecurringExpenses(ctx context.Context, ledgerID uuid.UUID) ([]database.RecurringExpense, error) {
+    return s.queries.GetRecurringExpensesByLedger(ctx, ledgerID)
+}
+
+func (s *Service) GetRecurringExpense(ctx context.Context, id, ledgerID uuid.UUID) (*database.RecurringExpense, error) {
+    rec, err := s.queries.GetRecurringExpenseByID(ctx, database.GetRecurringExpenseByIDParams{
+        ID:       id,
+        LedgerID: ledgerID,
+    })
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    return &rec, err
+}
+
+// GetSkips lists skipped occurrences that have not yet been passed.
+func (s *Service) GetSkips(ctx context.Context, rec *database.RecurringExpense) ([]time.Time, error) {
+    if !rec.NextDate.Valid {
+        return []time.Time{}, nil
+    }
+    return s.queries.GetRecurringExpenseSkips(ctx, database.GetRecurringExpenseSkipsParams{
+        RecurringExpenseID: rec.ID,
+        OccurrenceDate:     rec.NextDate.Time,
+    })
+}
+
+// UpdateRecurringExpense changes the template for occurrences that have not
+// been materialized yet. Existing expenses are left untouched. The new
+// schedule starts at t.StartDate, or at the next pending occurrence when no
+// start date is given.
+func (s *Service) UpdateRecurringExpense(ctx context.Context, id, ledgerID uuid.UUID, t Template, keepStart bool) (*database.RecurringExpense, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    qtx := s.queries.WithTx(tx)
+    current, err := qtx.LockRecurringExpense(ctx, id)
+    if err == sql.ErrNoRows || (err == nil && current.LedgerID != ledgerID) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := checkCategory(ctx, qtx, ledgerID, t.CategoryID); err != nil {
+        return nil, err
+    }
+
+    anchor, anchorIndex := t.StartDate, int(current.NextIndex)
+    if keepStart {
+        anchor, anchorIndex = continueAnchor(current, t.Frequency, t.Interval)
+    } else if err := checkStart(t.StartDate); err != nil {
+        return nil, err
+    }
+
+    schedule := Schedule{
+        Frequency:   t.Frequency,
+        Interval:    t.Interval,
+        Anchor:      anchor,
+        AnchorIndex: anchorIndex,
+        Until:       t.EndDate,
+        Count:       t.Count,
+    }
+    if err := schedule.Validate(); err != nil {
+        return nil, err
+    }
+
+    rec, err := qtx.UpdateRecurringExpense(ctx, database.UpdateRecurringExpenseParams{
+        CategoryID:      nullUUID(t.CategoryID),
+        Amount:          t.Amount,
+        Currency:        t.Currency,
+        Description:     t.Description,
+        Frequency:       string(t.Frequency),
+        IntervalCount:   int32(t.Interval),
+        EndDate:         nullTime(t.EndDate),
+        OccurrenceLimit: nullInt32(t.Count),
+        AnchorDate:      anchor,
+        AnchorIndex:     int32(anchorIndex),
+        NextDate:        nullTime(schedule.Next(int(current.NextIndex))),
+        ID:              id,
+        LedgerID:        ledgerID,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+func (s *Service) PauseRecurringExpense(ctx context.Context, id, ledgerID uuid.UUID) (*database.RecurringExpense, error) {
+    rec, err := s.queries.SetRecurringExpensePaused(ctx, database.SetRecurringExpensePausedParams{
+        ID:       id,
+        LedgerID: ledgerID,
+        Paused:   true,
+    })
+    if err == sql.ErrNoRows {
+        return nil, ErrNotFound
+    }
+    return &rec, err
+}
+
+// ResumeRecurringExpense unpauses a template. Occurrences that fell due
+// while it was paused are passed over rather than back-filled.
+func (s *Service) ResumeRecurringExpense(ctx context.Context, id, ledgerID uuid.UUID) (*database.RecurringExpense, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    qtx := s.queries.WithTx(tx)
+    current, err := qtx.LockRecurringExpense(ctx, id)
+    if err == sql.ErrNoRows || (err == nil && current.LedgerID != ledgerID) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if current.Paused {
+        schedule := scheduleOf(current)
+        n := int(current.NextIndex)
+        now := today()
+        for next := schedule.Next(n); next != nil && next.Before(now); next = schedule.Next(n) {
+            n++
+        }
+        err = qtx.AdvanceRecurringExpense(ctx, database.AdvanceRecurringExpenseParams{
+            ID:        id,
+            NextIndex: int32(n),
+            NextDate:  nullTime(schedule.Next(n)),
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    rec, err := qtx.SetRecurringExpensePaused(ctx, database.SetRecurringExpensePausedParams{
+        ID:       id,
+        LedgerID: ledgerID,
+        Paused:   false,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+// SkipOccurrence stops a single upcoming occurrence from being materialized.
+func (s *Service) SkipOccurrence(ctx context.Context, id, ledgerID uuid.UUID, date time.Time) error {
+    rec, err := s.GetRecurringExpense(ctx, id, ledgerID)
+    if err != nil {
+        return err
+    }
+
+    if _, ok := scheduleOf(*rec).IndexOf(date, int(rec.NextIndex)); !ok {
+        return ErrNotAnOccurrence
+    }
+
+    return s.queries.CreateRecurringExpenseSkip(ctx, database.CreateRecurringExpenseSkipParams{
+        RecurringExpenseID: id,
+        OccurrenceDate:     date,
+    })
+}
+
+func (s *Service) DeleteRecurringExpense(ctx context.Context, id, ledgerID uuid.UUID) error {
+    return s.queries.DeleteRecurringExpense(ctx, database.DeleteRecurringExpenseParams{
+        ID:       id,
+        LedgerID: ledgerID,
+    })
+}
+
+// MaterializeDue creates expenses for occurrences due on or before asOf and
+// returns how many were inserted. Each template gets at most
+// maxOccurrencesPerRun per call, so a long backlog is worked off over
+// several runs. It is safe to run concurrently and repeatedly: templates
+// are row-locked while processed and each occurrence is unique in the
+// expenses table.
+func (s *Service) MaterializeDue(ctx context.Context, asOf time.Time) (int, error) {
+    ids, err := s.queries.GetDueRecurringExpenseIDs(ctx, sql.NullTime{Time: asOf, Valid: true})
+    if err != nil {
+        return 0, err
+    }
+
+    created := 0
+    var errs []error
+    for _, id := range ids {
+        n, err := s.materialize(ctx, id, asOf)
+        created += n
+        if err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return created, errors.Join(errs...)
+}
+
+func (s *Service) materialize(ctx context.Context, id uuid.UUID, asOf time.Time) (int, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    qtx := s.queries.WithTx(tx)
+    rec, err := qtx.LockRecurringExpense(ctx, id)
+    if err != nil {
+        return 0, err
+    }
+    // Another worker may have got here first
+    if rec.Paused || !rec.NextDate.Valid || rec.NextDate.Time.After(asOf) {
+        return 0, nil
+    }
+
+    skips, err := qtx.GetRecurringExpenseSkips(ctx, database.GetRecurringExpenseSkipsParams{
+        RecurringExpenseID: rec.ID,
+        OccurrenceDate:     rec.NextDate.Time,
+    })
+    if err != nil {
+        return 0, err
+    }
+    skipped := make(map[string]bool, len(skips))
+    for _, d := range skips {
+        skipped[d.Format("2006-01-02")] = true
+    }
+
+    schedule := scheduleOf(rec)
+    created := 0
+    first := int(rec.NextIndex)
+    n := first
+    for next := schedule.Next(n); next != nil && !next.After(asOf) && n-first < maxOccurrencesPerRun; next = schedule.Next(n) {
+        if !skipped[next.Format("2006-01-02")] {
+            rows, err := qtx.CreateRecurringOccurrence(ctx, database.CreateRecurringOccurrenceParams{
+                LedgerID:           rec.LedgerID,
+                UserID:             rec.UserID,
+                CategoryID:         rec.CategoryID,
+                Amount:             rec.Amount,
+                Currency:           rec.Currency,
+                Description:        rec.Description,
+                Date:               *next,
+                RecurringExpenseID: uuid.NullUUID{UUID: rec.ID, Valid: true},
+            })
+            if err != nil {
+                return 0, err
+            }
+            created += int(rows)
+        }
+        n++
+    }
+
+    err = qtx.AdvanceRecurringExpense(ctx, database.AdvanceRecurringExpenseParams{
+        ID:        rec.ID,
+        NextIndex: int32(n),
+        NextDate:  nullTime(schedule.Next(n)),
+    })
+    if err != nil {
+        return 0, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return 0, err
+    }
+    return created, nil
+}
+
+// checkStart rejects start dates more than maxBackfillDays in the past.
+func checkStart(start time.Time) error {
+    if start.Before(today().AddDate(0, 0, -maxBackfillDays)) {
+        return fmt.Errorf("%w: start_date can be at most %d days in the past", ErrInvalidSchedule, maxBackfillDays)
+    }
+    return nil
+}
+
+// continueAnchor picks the anchor for an edit that keeps the schedule
+// going from its next pending occurrence. With the same frequency and
+// interval the current anchor stays, so no date moves. Otherwise the next
+// pending occurrence becomes the anchor. When that date was clamped to a
+// month end (the 31st landing on Feb 28) the anchor is traced back to an
+// earlier period that has the original day, so later months return to it.
+func continueAnchor(current database.RecurringExpense, frequency Frequency, interval int) (time.Time, int) {
+    if Frequency(current.Frequency) == frequency && int(current.IntervalCount) == interval {
+        return current.AnchorDate, int(current.AnchorIndex)
+    }
+
+    index := int(current.NextIndex)
+    if !current.NextDate.Valid {
+        return today(), index
+    }
+    next := current.NextDate.Time
+
+    months := monthsPerPeriod(frequency, interval)
+    day := current.AnchorDate.Day()
+    clamped := monthsPerPeriod(Frequency(current.Frequency), 1) > 0 &&
+        day > next.Day() && next.AddDate(0, 0, 1).Day() == 1
+    if months == 0 || !clamped {
+        return next, index
+    }
+    for k := 1; k <= 12; k++ {
+        earlier := addMonthsClamped(next, -k*months)
+        year, month, _ := earlier.Date()
+        if day <= time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+            return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), index - k
+        }
+    }
+    return next, index
+}
+
+// monthsPerPeriod is the length of one step in months for monthly and
+// yearly schedules, and 0 for the others.
+func monthsPerPeriod(frequency Frequency, interval int) int {
+    switch frequency {
+    case Monthly:
+        return interval
+    case Yearly:
+        return 12 * interval
+    }
+    return 0
+}
+
+func scheduleOf(rec database.RecurringExpense) Schedule {
+    schedule := Schedule{
+        Frequency:   Frequency(rec.Frequency),
+        Interval:    int(rec.IntervalCount),
+        Anchor:      rec.AnchorDate,
+        AnchorIndex: int(rec.AnchorIndex),
+    }
+    if rec.EndDate.Valid {
+        until := rec.EndDate.Time
+        schedule.Until = &until
+    }
+    if rec.OccurrenceLimit.Valid {
+        count := int(rec.OccurrenceLimit.Int32)
+        schedule.Count = &count
+    }
+    return schedule
+}
+
+// today is the current date at midnight UTC, matching how DATE columns scan.
+func today() time.Time {
+    now := time.Now()
+    return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+    if id == nil {
+        return uuid.NullUUID{}
+    }
+    return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+    if t == nil {
+        return sql.NullTime{}
+    }
+    return sql.NullTime{Time: *t, Valid: true}
+}
+
+func nullInt32(n *int) sql.NullInt32 {
+    if n == nil {
+        return sql.NullInt32{}
+    }
+    return sql.NullInt32{Int32: int32(*n), Valid: true}
+}
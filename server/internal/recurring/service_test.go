@@ -0,0 +1,90 @@
+package recurring
+
+import (
+    "database/sql"
+    "testing"
+    "time"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+)
+
+func TestContinueAnchor(t *testing.T) {
+    tests := []struct {
+        name      string
+        current   database.RecurringExpense
+        frequency Frequency
+        interval  int
+        wantDate  time.Time
+        wantIndex int
+    }{
+        {
+            name:      "unchanged schedule keeps its anchor",
+            current:   recurringAt(Monthly, 1, date(2023, 1, 31), 0, 1, date(2023, 2, 28)),
+            frequency: Monthly, interval: 1,
+            wantDate: date(2023, 1, 31), wantIndex: 0,
+        },
+        {
+            name:      "unclamped next date becomes the anchor",
+            current:   recurringAt(Monthly, 1, date(2023, 1, 15), 0, 1, date(2023, 2, 15)),
+            frequency: Weekly, interval: 1,
+            wantDate: date(2023, 2, 15), wantIndex: 1,
+        },
+        {
+            name:      "clamped month end is traced back",
+            current:   recurringAt(Monthly, 1, date(2023, 1, 31), 0, 1, date(2023, 2, 28)),
+            frequency: Monthly, interval: 2,
+            wantDate: date(2022, 12, 31), wantIndex: 0,
+        },
+        {
+            name:      "clamped leap day moves to a monthly schedule",
+            current:   recurringAt(Yearly, 1, date(2024, 2, 29), 0, 1, date(2025, 2, 28)),
+            frequency: Monthly, interval: 1,
+            wantDate: date(2025, 1, 29), wantIndex: 0,
+        },
+        {
+            name:      "daily schedule is never clamped",
+            current:   recurringAt(Daily, 1, date(2023, 1, 31), 0, 28, date(2023, 2, 28)),
+            frequency: Monthly, interval: 1,
+            wantDate: date(2023, 2, 28), wantIndex: 28,
+        },
+        {
+            name:      "month end that is the real day stays",
+            current:   recurringAt(Monthly, 1, date(2023, 1, 28), 0, 1, date(2023, 2, 28)),
+            frequency: Monthly, interval: 3,
+            wantDate: date(2023, 2, 28), wantIndex: 1,
+        },
+    }
+
+    for _, tt := range tests {
+        gotDate, gotIndex := continueAnchor(tt.current, tt.frequency, tt.interval)
+        if !gotDate.Equal(tt.wantDate) || gotIndex != tt.wantIndex {
+            t.Errorf("%s: continueAnchor = %s, %d, want %s, %d", tt.name,
+                gotDate.Format("2006-01-02"), gotIndex, tt.wantDate.Format("2006-01-02"), tt.wantIndex)
+        }
+    }
+}
+
+func TestContinueAnchorKeepsDaysAfterClamp(t *testing.T) {
+    current := recurringAt(Monthly, 1, date(2023, 1, 31), 0, 1, date(2023, 2, 28))
+    anchor, index := continueAnchor(current, Monthly, 2)
+    schedule := Schedule{Frequency: Monthly, Interval: 2, Anchor: anchor, AnchorIndex: index}
+
+    want := []time.Time{date(2023, 2, 28), date(2023, 4, 30), date(2023, 6, 30), date(2023, 8, 31)}
+    for i, w := range want {
+        if got := schedule.Occurrence(1 + i); !got.Equal(w) {
+            t.Errorf("Occurrence(%d) = %s, want %s", 1+i, got.Format("2006-01-02"), w.Format("2006-01-02"))
+        }
+    }
+}
+
+func recurringAt(frequency Frequency, interval int, anchor time.Time, anchorIndex, nextIndex int, next time.Time) database.RecurringExpense {
+    return database.RecurringExpense{
+        Frequency:     string(frequency),
+        IntervalCount: int32(interval),
+        StartDate:     anchor,
+        AnchorDate:    anchor,
+        AnchorIndex:   int32(anchorIndex),
+        NextIndex:     int32(nextIndex),
+        NextDate:      sql.NullTime{Time: next, Valid: true},
+    }
+}
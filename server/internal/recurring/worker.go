@@ -0,0 +1,30 @@
+package recurring
+
+import (
+    "context"
+    "log"
+    "time"
+)
+
+// RunWorker materializes due occurrences once immediately and then every
+// interval until ctx is cancelled.
+func (s *Service) RunWorker(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        created, err := s.MaterializeDue(ctx, today())
+        if err != nil {
+            log.Printf("Recurring expenses: %v", err)
+        }
+        if created > 0 {
+            log.Printf("Recurring expenses: created %d expenses", created)
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
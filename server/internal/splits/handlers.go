@@ -0,0 +1,424 @@
+package splits
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LuisBAndrade/etracker/internal/auth"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/LuisBAndrade/etracker/internal/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ParticipantRequest struct {
+    UserID string       `json:"user_id" validate:"required"`
+    Share  string       `json:"share"`  // share count or percentage, for those methods
+    Amount money.Amount `json:"amount"` // for exact splits
+}
+
+type SetSplitRequest struct {
+    PaidBy       string               `json:"paid_by"` // defaults to the current user
+    Method       string               `json:"method" validate:"required"`
+    Participants []ParticipantRequest `json:"participants" validate:"required"`
+}
+
+type ParticipantResponse struct {
+    UserID string       `json:"user_id"`
+    Email  string       `json:"email"`
+    Share  *string      `json:"share"`
+    Amount money.Amount `json:"amount"`
+}
+
+type SplitResponse struct {
+    ExpenseID    string                `json:"expense_id"`
+    PaidBy       string                `json:"paid_by"`
+    Method       string                `json:"method"`
+    Amount       money.Amount          `json:"amount"`
+    Currency     string                `json:"currency"`
+    Participants []ParticipantResponse `json:"participants"`
+    UpdatedAt    string                `json:"updated_at"`
+}
+
+type BalanceResponse struct {
+    UserID   string       `json:"user_id"`
+    Email    string       `json:"email"`
+    Currency string       `json:"currency"`
+    Paid     money.Amount `json:"paid"`     // others' shares of expenses they paid
+    Owed     money.Amount `json:"owed"`     // their own shares
+    Sent     money.Amount `json:"sent"`     // settlements paid
+    Received money.Amount `json:"received"` // settlements received
+    Net      money.Amount `json:"net"`      // positive when others owe them
+}
+
+type TransferResponse struct {
+    FromUserID string       `json:"from_user_id"`
+    FromEmail  string       `json:"from_email"`
+    ToUserID   string       `json:"to_user_id"`
+    ToEmail    string       `json:"to_email"`
+    Amount     money.Amount `json:"amount"`
+    Currency   string       `json:"currency"`
+}
+
+type CreateSettlementRequest struct {
+    FromUserID string       `json:"from_user_id"` // defaults to the current user
+    ToUserID   string       `json:"to_user_id" validate:"required"`
+    Amount     money.Amount `json:"amount" validate:"required"`
+    Currency   string       `json:"currency"` // defaults to the user's base currency
+    Date       string       `json:"date"`     // YYYY-MM-DD, defaults to today
+    Note       string       `json:"note"`
+}
+
+type SettlementResponse struct {
+    ID         string       `json:"id"`
+    FromUserID string       `json:"from_user_id"`
+    FromEmail  string       `json:"from_email,omitempty"`
+    ToUserID   string       `json:"to_user_id"`
+    ToEmail    string       `json:"to_email,omitempty"`
+    Amount     money.Amount `json:"amount"`
+    Currency   string       `json:"currency"`
+    Date       string       `json:"date"`
+    Note       string       `json:"note"`
+    CreatedBy  string       `json:"created_by"`
+    CreatedAt  string       `json:"created_at"`
+}
+
+func respondWithSplitError(w http.ResponseWriter, err error, message string) {
+    switch {
+    case errors.Is(err, ErrInvalidSplit), errors.Is(err, ErrSplitMismatch), errors.Is(err, ErrInvalidSettlement):
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+    case errors.Is(err, ErrNotMember):
+        utils.RespondWithError(w, http.StatusBadRequest, "Payer and participants must be members of this ledger")
+    case errors.Is(err, ErrExpenseNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Expense not found")
+    case errors.Is(err, ErrSplitNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Expense is not split")
+    case errors.Is(err, ErrSettlementNotFound):
+        utils.RespondWithError(w, http.StatusNotFound, "Settlement not found")
+    default:
+        utils.RespondWithError(w, http.StatusInternalServerError, message)
+    }
+}
+
+func splitResponse(split *Split) SplitResponse {
+    response := SplitResponse{
+        ExpenseID:    split.ExpenseID.String(),
+        PaidBy:       split.PaidBy.String(),
+        Method:       split.Method,
+        Amount:       split.Amount,
+        Currency:     split.Currency,
+        Participants: make([]ParticipantResponse, len(split.Participants)),
+        UpdatedAt:    split.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+    }
+    for i, p := range split.Participants {
+        response.Participants[i] = ParticipantResponse{
+            UserID: p.UserID.String(),
+            Email:  p.Email,
+            Amount: p.Amount,
+        }
+        if p.Share.Valid {
+            share := p.Share.String
+            response.Participants[i].Share = &share
+        }
+    }
+    return response
+}
+
+func (s *Service) HandleSetSplit(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    expenseID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    
+    var req SetSplitRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    paidBy := user.ID
+    if req.PaidBy != "" {
+        paidBy, err = uuid.Parse(req.PaidBy)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid paid_by user ID")
+            return
+        }
+    }
+    
+    method := strings.ToLower(strings.TrimSpace(req.Method))
+    participants := make([]Participant, len(req.Participants))
+    for i, p := range req.Participants {
+        userID, err := uuid.Parse(p.UserID)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid participant user ID")
+            return
+        }
+        participants[i] = Participant{UserID: userID, Amount: p.Amount}
+        if method == MethodShares || method == MethodPercentage {
+            share, err := ParseShare(strings.TrimSpace(p.Share))
+            if err != nil {
+                utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+                return
+            }
+            participants[i].Share = share
+        }
+    }
+    
+    split, err := s.SetSplit(r.Context(), ledger.ID, expenseID, paidBy, method, participants)
+    if err != nil {
+        respondWithSplitError(w, err, "Failed to split expense")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, splitResponse(split))
+}
+
+func (s *Service) HandleGetSplit(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    expenseID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    
+    split, err := s.GetSplit(r.Context(), ledger.ID, expenseID)
+    if err != nil {
+        respondWithSplitError(w, err, "Failed to get split")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, splitResponse(split))
+}
+
+func (s *Service) HandleDeleteSplit(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    expenseID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid expense ID")
+        return
+    }
+    
+    if err := s.DeleteSplit(r.Context(), ledger.ID, expenseID); err != nil {
+        respondWithSplitError(w, err, "Failed to remove split")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Split removed"})
+}
+
+func (s *Service) HandleGetBalances(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    balances, err := s.GetBalances(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get balances")
+        return
+    }
+    
+    response := make([]BalanceResponse, len(balances))
+    for i, b := range balances {
+        response[i] = BalanceResponse{
+            UserID:   b.UserID.String(),
+            Email:    b.Email,
+            Currency: b.Currency,
+            Paid:     b.Paid,
+            Owed:     b.Owed,
+            Sent:     b.Sent,
+            Received: b.Received,
+            Net:      b.Net,
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleSettleUp(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    transfers, err := s.SuggestSettlements(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to work out settlements")
+        return
+    }
+    
+    response := make([]TransferResponse, len(transfers))
+    for i, t := range transfers {
+        response[i] = TransferResponse{
+            FromUserID: t.From.String(),
+            FromEmail:  t.FromEmail,
+            ToUserID:   t.To.String(),
+            ToEmail:    t.ToEmail,
+            Amount:     t.Amount,
+            Currency:   t.Currency,
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleCreateSettlement(w http.ResponseWriter, r *http.Request) {
+    user, ok := auth.GetUserFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusUnauthorized, "User not found")
+        return
+    }
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    var req CreateSettlementRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON")
+        return
+    }
+    
+    if err := utils.ValidateStruct(req); err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    
+    from := user.ID
+    if req.FromUserID != "" {
+        id, err := uuid.Parse(req.FromUserID)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid from_user_id")
+            return
+        }
+        from = id
+    }
+    to, err := uuid.Parse(req.ToUserID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid to_user_id")
+        return
+    }
+    
+    currency := user.BaseCurrency
+    if req.Currency != "" {
+        currency, err = money.ParseCurrency(req.Currency)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid currency")
+            return
+        }
+    }
+    
+    date := time.Now()
+    if req.Date != "" {
+        date, err = time.Parse("2006-01-02", req.Date)
+        if err != nil {
+            utils.RespondWithError(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
+            return
+        }
+    }
+    
+    settlement, err := s.CreateSettlement(r.Context(), ledger.ID, user.ID, from, to, req.Amount, currency, date, strings.TrimSpace(req.Note))
+    if err != nil {
+        respondWithSplitError(w, err, "Failed to record settlement")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusCreated, SettlementResponse{
+        ID:         settlement.ID.String(),
+        FromUserID: settlement.FromUserID.String(),
+        ToUserID:   settlement.ToUserID.String(),
+        Amount:     settlement.Amount,
+        Currency:   settlement.Currency,
+        Date:       settlement.Date.Format("2006-01-02"),
+        Note:       settlement.Note,
+        CreatedBy:  settlement.CreatedBy.String(),
+        CreatedAt:  settlement.CreatedAt.Format("2006-01-02T15:04:05Z"),
+    })
+}
+
+func (s *Service) HandleGetSettlements(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    settlements, err := s.GetSettlements(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get settlements")
+        return
+    }
+    
+    response := make([]SettlementResponse, len(settlements))
+    for i, st := range settlements {
+        response[i] = SettlementResponse{
+            ID:         st.ID.String(),
+            FromUserID: st.FromUserID.String(),
+            FromEmail:  st.FromEmail,
+            ToUserID:   st.ToUserID.String(),
+            ToEmail:    st.ToEmail,
+            Amount:     st.Amount,
+            Currency:   st.Currency,
+            Date:       st.Date.Format("2006-01-02"),
+            Note:       st.Note,
+            CreatedBy:  st.CreatedBy.String(),
+            CreatedAt:  st.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleDeleteSettlement(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    settlementID, err := uuid.Parse(mux.Vars(r)["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid settlement ID")
+        return
+    }
+    
+    if err := s.DeleteSettlement(r.Context(), settlementID, ledger.ID); err != nil {
+        respondWithSplitError(w, err, "Failed to delete settlement")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Settlement deleted"})
+}
@@ -0,0 +1,371 @@
+package splits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/LuisBAndrade/etracker/internal/database"
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/google/uuid"
+)
+
+var (
+    ErrInvalidSplit       = errors.New("invalid split")
+    ErrSplitMismatch      = errors.New("split amounts don't match the expense")
+    ErrNotMember          = errors.New("not a member of this ledger")
+    ErrExpenseNotFound    = errors.New("expense not found")
+    ErrSplitNotFound      = errors.New("split not found")
+    ErrSettlementNotFound = errors.New("settlement not found")
+    ErrInvalidSettlement  = errors.New("invalid settlement")
+)
+
+type Service struct {
+    db      *sql.DB
+    queries *database.Queries
+}
+
+func NewService(db *sql.DB, queries *database.Queries) *Service {
+    return &Service{db: db, queries: queries}
+}
+
+// Split is an expense's split with each participant's part.
+type Split struct {
+    ExpenseID    uuid.UUID
+    PaidBy       uuid.UUID
+    Method       string
+    Amount       money.Amount
+    Currency     string
+    Participants []database.GetExpenseSplitParticipantsRow
+    UpdatedAt    time.Time
+}
+
+// SetSplit creates or replaces how an expense is shared. The payer and all
+// participants must belong to the ledger.
+func (s *Service) SetSplit(ctx context.Context, ledgerID, expenseID, paidBy uuid.UUID, method string, participants []Participant) (*Split, error) {
+    if !validMethod(method) {
+        return nil, fmt.Errorf("%w: method must be equal, shares, exact or percentage", ErrInvalidSplit)
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+    qtx := s.queries.WithTx(tx)
+    
+    expense, err := qtx.GetExpenseByID(ctx, database.GetExpenseByIDParams{ID: expenseID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrExpenseNotFound
+        }
+        return nil, err
+    }
+    
+    members, err := memberSet(ctx, qtx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    if !members[paidBy] {
+        return nil, fmt.Errorf("%w: the payer", ErrNotMember)
+    }
+    for _, p := range participants {
+        if !members[p.UserID] {
+            return nil, fmt.Errorf("%w: participant %s", ErrNotMember, p.UserID)
+        }
+    }
+    
+    amounts, err := Allocate(expense.Amount, method, participants)
+    if err != nil {
+        return nil, err
+    }
+    
+    split, err := qtx.UpsertExpenseSplit(ctx, database.UpsertExpenseSplitParams{
+        ExpenseID: expenseID,
+        PaidBy:    paidBy,
+        Method:    method,
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := writeParticipants(ctx, qtx, expenseID, method, participants, amounts); err != nil {
+        return nil, err
+    }
+    
+    rows, err := qtx.GetExpenseSplitParticipants(ctx, expenseID)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, err
+    }
+    
+    return &Split{
+        ExpenseID:    split.ExpenseID,
+        PaidBy:       split.PaidBy,
+        Method:       split.Method,
+        Amount:       expense.Amount,
+        Currency:     expense.Currency,
+        Participants: rows,
+        UpdatedAt:    split.UpdatedAt,
+    }, nil
+}
+
+func (s *Service) GetSplit(ctx context.Context, ledgerID, expenseID uuid.UUID) (*Split, error) {
+    expense, err := s.queries.GetExpenseByID(ctx, database.GetExpenseByIDParams{ID: expenseID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrExpenseNotFound
+        }
+        return nil, err
+    }
+    
+    split, err := s.queries.GetExpenseSplit(ctx, database.GetExpenseSplitParams{ExpenseID: expenseID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil, ErrSplitNotFound
+        }
+        return nil, err
+    }
+    
+    rows, err := s.queries.GetExpenseSplitParticipants(ctx, expenseID)
+    if err != nil {
+        return nil, err
+    }
+    
+    return &Split{
+        ExpenseID:    split.ExpenseID,
+        PaidBy:       split.PaidBy,
+        Method:       split.Method,
+        Amount:       expense.Amount,
+        Currency:     expense.Currency,
+        Participants: rows,
+        UpdatedAt:    split.UpdatedAt,
+    }, nil
+}
+
+func (s *Service) DeleteSplit(ctx context.Context, ledgerID, expenseID uuid.UUID) error {
+    deleted, err := s.queries.DeleteExpenseSplit(ctx, database.DeleteExpenseSplitParams{ExpenseID: expenseID, LedgerID: ledgerID})
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrSplitNotFound
+    }
+    return nil
+}
+
+// Rebalance recomputes a split after its expense's amount changed. Shares
+// and percentages scale with the new amount; an exact split that no longer
+// adds up fails with ErrSplitMismatch. Pass queries bound to the
+// transaction that updated the expense.
+func Rebalance(ctx context.Context, queries *database.Queries, ledgerID, expenseID uuid.UUID, amount money.Amount) error {
+    split, err := queries.GetExpenseSplit(ctx, database.GetExpenseSplitParams{ExpenseID: expenseID, LedgerID: ledgerID})
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return nil
+        }
+        return err
+    }
+    
+    rows, err := queries.GetExpenseSplitParticipants(ctx, expenseID)
+    if err != nil {
+        return err
+    }
+    participants := make([]Participant, len(rows))
+    for i, row := range rows {
+        participants[i] = Participant{UserID: row.UserID, Amount: row.Amount}
+        if row.Share.Valid {
+            share, ok := new(big.Rat).SetString(row.Share.String)
+            if !ok {
+                return fmt.Errorf("invalid stored share %q", row.Share.String)
+            }
+            participants[i].Share = share
+        }
+    }
+    
+    amounts, err := Allocate(amount, split.Method, participants)
+    if err != nil {
+        return err
+    }
+    return writeParticipants(ctx, queries, expenseID, split.Method, participants, amounts)
+}
+
+func writeParticipants(ctx context.Context, queries *database.Queries, expenseID uuid.UUID, method string, participants []Participant, amounts []money.Amount) error {
+    if err := queries.ClearExpenseSplitParticipants(ctx, expenseID); err != nil {
+        return err
+    }
+    for i, p := range participants {
+        share := sql.NullString{}
+        if (method == MethodShares || method == MethodPercentage) && p.Share != nil {
+            share = sql.NullString{String: formatShare(p.Share), Valid: true}
+        }
+        if err := queries.AddExpenseSplitParticipant(ctx, database.AddExpenseSplitParticipantParams{
+            ExpenseID: expenseID,
+            UserID:    p.UserID,
+            Share:     share,
+            Amount:    amounts[i],
+        }); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func memberSet(ctx context.Context, queries *database.Queries, ledgerID uuid.UUID) (map[uuid.UUID]bool, error) {
+    ids, err := queries.GetLedgerMemberIDs(ctx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    members := make(map[uuid.UUID]bool, len(ids))
+    for _, id := range ids {
+        members[id] = true
+    }
+    return members, nil
+}
+
+// MemberBalance is one member's running position in one currency. Net is
+// positive when others owe them.
+type MemberBalance struct {
+    UserID   uuid.UUID
+    Email    string
+    Currency string
+    Paid     money.Amount
+    Owed     money.Amount
+    Sent     money.Amount
+    Received money.Amount
+    Net      money.Amount
+}
+
+// GetBalances reports what every member has paid for others, owes for
+// their own shares and has settled, per currency.
+func (s *Service) GetBalances(ctx context.Context, ledgerID uuid.UUID) ([]MemberBalance, error) {
+    rows, err := s.queries.GetLedgerBalances(ctx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    
+    balances := make([]MemberBalance, len(rows))
+    for i, row := range rows {
+        net, err := row.Paid.Sub(row.Owed)
+        if err == nil {
+            net, err = net.Add(row.Sent)
+        }
+        if err == nil {
+            net, err = net.Sub(row.Received)
+        }
+        if err != nil {
+            return nil, err
+        }
+        balances[i] = MemberBalance{
+            UserID:   row.UserID,
+            Email:    row.Email,
+            Currency: row.Currency,
+            Paid:     row.Paid,
+            Owed:     row.Owed,
+            Sent:     row.Sent,
+            Received: row.Received,
+            Net:      net,
+        }
+    }
+    return balances, nil
+}
+
+// SuggestedTransfer is a settle-up payment with both members' emails.
+type SuggestedTransfer struct {
+    Transfer
+    FromEmail string
+    ToEmail   string
+    Currency  string
+}
+
+// SuggestSettlements proposes the fewest transfers that settle every
+// currency. Currencies are settled separately rather than converted.
+func (s *Service) SuggestSettlements(ctx context.Context, ledgerID uuid.UUID) ([]SuggestedTransfer, error) {
+    balances, err := s.GetBalances(ctx, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    
+    emails := make(map[uuid.UUID]string)
+    byCurrency := make(map[string][]Balance)
+    for _, b := range balances {
+        emails[b.UserID] = b.Email
+        byCurrency[b.Currency] = append(byCurrency[b.Currency], Balance{UserID: b.UserID, Net: b.Net})
+    }
+    currencies := make([]string, 0, len(byCurrency))
+    for currency := range byCurrency {
+        currencies = append(currencies, currency)
+    }
+    sort.Strings(currencies)
+    
+    suggestions := []SuggestedTransfer{}
+    for _, currency := range currencies {
+        for _, t := range SettleUp(byCurrency[currency]) {
+            suggestions = append(suggestions, SuggestedTransfer{
+                Transfer:  t,
+                FromEmail: emails[t.From],
+                ToEmail:   emails[t.To],
+                Currency:  currency,
+            })
+        }
+    }
+    return suggestions, nil
+}
+
+// CreateSettlement records money paid from one member to another. It is
+// kept apart from expenses so paying someone back is never counted as
+// spending.
+func (s *Service) CreateSettlement(ctx context.Context, ledgerID, createdBy, from, to uuid.UUID, amount money.Amount, currency string, date time.Time, note string) (*database.Settlement, error) {
+    if from == to {
+        return nil, fmt.Errorf("%w: can't settle with yourself", ErrInvalidSettlement)
+    }
+    if !amount.IsPositive() {
+        return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidSettlement)
+    }
+    if !amount.Storable() {
+        return nil, fmt.Errorf("%w: amount must be at most %s", ErrInvalidSettlement, money.MaxStored)
+    }
+    
+    members, err := memberSet(ctx, s.queries, ledgerID)
+    if err != nil {
+        return nil, err
+    }
+    if !members[from] || !members[to] {
+        return nil, ErrNotMember
+    }
+    
+    settlement, err := s.queries.CreateSettlement(ctx, database.CreateSettlementParams{
+        LedgerID:   ledgerID,
+        FromUserID: from,
+        ToUserID:   to,
+        Amount:     amount,
+        Currency:   currency,
+        Date:       date,
+        Note:       note,
+        CreatedBy:  createdBy,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &settlement, nil
+}
+
+func (s *Service) GetSettlements(ctx context.Context, ledgerID uuid.UUID) ([]database.GetSettlementsByLedgerRow, error) {
+    return s.queries.GetSettlementsByLedger(ctx, ledgerID)
+}
+
+func (s *Service) DeleteSettlement(ctx context.Context, settlementID, ledgerID uuid.UUID) error {
+    deleted, err := s.queries.DeleteSettlement(ctx, database.DeleteSettlementParams{ID: settlementID, LedgerID: ledgerID})
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrSettlementNotFound
+    }
+    return nil
+}
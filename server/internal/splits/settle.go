@@ -0,0 +1,162 @@
+package splits
+
+import (
+	"sort"
+
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/google/uuid"
+)
+
+// maxExactSettle bounds the exhaustive search. Above it settle-up falls
+// back to pairing the largest debtor with the largest creditor, which is
+// at most a few transfers off.
+const maxExactSettle = 16
+
+// Balance is what a member is owed (positive) or owes (negative) in one
+// currency.
+type Balance struct {
+    UserID uuid.UUID
+    Net    money.Amount
+}
+
+// Transfer is one payment that moves balances towards zero.
+type Transfer struct {
+    From   uuid.UUID
+    To     uuid.UUID
+    Amount money.Amount
+}
+
+// SettleUp suggests the fewest transfers that bring every balance to zero.
+// Balances must add up to zero.
+//
+// Settling n people never takes more than n-1 transfers, and each group of
+// people whose balances cancel out on their own saves one more. So the
+// balances are split into as many zero-sum groups as possible, then each
+// group is settled on its own.
+func SettleUp(balances []Balance) []Transfer {
+    var open []Balance
+    for _, b := range balances {
+        if !b.Net.IsZero() {
+            open = append(open, b)
+        }
+    }
+    // Deterministic input order gives deterministic suggestions.
+    sort.Slice(open, func(i, j int) bool {
+        return open[i].UserID.String() < open[j].UserID.String()
+    })
+    
+    if len(open) > maxExactSettle {
+        return settleGreedy(open)
+    }
+    
+    var transfers []Transfer
+    for _, group := range zeroSumGroups(open) {
+        transfers = append(transfers, settleGreedy(group)...)
+    }
+    return transfers
+}
+
+// zeroSumGroups partitions balances into the largest number of groups that
+// each add up to zero.
+//
+// best[mask] is the most zero-sum prefixes over any ordering of the
+// balances in mask: a subset that sums to zero closes one more group than
+// the best subset one element smaller. Walking back through best recovers
+// an ordering, which is cut wherever its running sum returns to zero.
+func zeroSumGroups(balances []Balance) [][]Balance {
+    n := len(balances)
+    full := 1<<n - 1
+    sums := make([]int64, full+1)
+    best := make([]int, full+1)
+    for mask := 1; mask <= full; mask++ {
+        low := mask & -mask
+        i := bitIndex(low)
+        sums[mask] = sums[mask^low] + balances[i].Net.MinorUnits()
+        
+        for j := 0; j < n; j++ {
+            if mask&(1<<j) != 0 && best[mask^(1<<j)] > best[mask] {
+                best[mask] = best[mask^(1<<j)]
+            }
+        }
+        if sums[mask] == 0 {
+            best[mask]++
+        }
+    }
+    
+    order := make([]int, 0, n)
+    for mask := full; mask != 0; {
+        target := best[mask]
+        if sums[mask] == 0 {
+            target--
+        }
+        for j := 0; j < n; j++ {
+            if mask&(1<<j) != 0 && best[mask^(1<<j)] == target {
+                order = append(order, j)
+                mask ^= 1 << j
+                break
+            }
+        }
+    }
+    
+    // order was built from the end, so walk it backwards.
+    var groups [][]Balance
+    var current []Balance
+    running := int64(0)
+    for k := len(order) - 1; k >= 0; k-- {
+        b := balances[order[k]]
+        current = append(current, b)
+        running += b.Net.MinorUnits()
+        if running == 0 {
+            groups = append(groups, current)
+            current = nil
+        }
+    }
+    if len(current) > 0 {
+        groups = append(groups, current)
+    }
+    return groups
+}
+
+func bitIndex(bit int) int {
+    i := 0
+    for bit > 1 {
+        bit >>= 1
+        i++
+    }
+    return i
+}
+
+// settleGreedy repeatedly has the largest debtor pay the largest creditor.
+// Every transfer clears at least one balance.
+func settleGreedy(balances []Balance) []Transfer {
+    var debtors, creditors []Balance
+    for _, b := range balances {
+        if b.Net.IsNegative() {
+            debtors = append(debtors, Balance{UserID: b.UserID, Net: b.Net.Neg()})
+        } else if b.Net.IsPositive() {
+            creditors = append(creditors, b)
+        }
+    }
+    
+    var transfers []Transfer
+    for len(debtors) > 0 && len(creditors) > 0 {
+        sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].Net > debtors[j].Net })
+        sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].Net > creditors[j].Net })
+        
+        d, c := &debtors[0], &creditors[0]
+        amount := d.Net
+        if c.Net < amount {
+            amount = c.Net
+        }
+        transfers = append(transfers, Transfer{From: d.UserID, To: c.UserID, Amount: amount})
+        d.Net = money.FromMinorUnits(d.Net.MinorUnits() - amount.MinorUnits())
+        c.Net = money.FromMinorUnits(c.Net.MinorUnits() - amount.MinorUnits())
+        if d.Net.IsZero() {
+            debtors = debtors[1:]
+        }
+        if c.Net.IsZero() {
+            creditors = creditors[1:]
+        }
+    }
+    return transfers
+}
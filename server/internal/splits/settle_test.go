@@ -0,0 +1,99 @@
+package splits
+
+import (
+    "testing"
+
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/google/uuid"
+)
+
+func balances(nets ...int64) []Balance {
+    out := make([]Balance, len(nets))
+    for i, net := range nets {
+        out[i] = Balance{UserID: user(i + 1), Net: money.FromMinorUnits(net)}
+    }
+    return out
+}
+
+func TestSettleUp(t *testing.T) {
+    tests := []struct {
+        name      string
+        balances  []Balance
+        transfers int
+    }{
+        {"nothing owed", balances(0, 0, 0), 0},
+        {"one debt", balances(-500, 500), 1},
+        {"one creditor", balances(-100, -200, 300), 2},
+        {"chain", balances(-100, 0, 100), 1},
+        {"two independent pairs", balances(-500, -300, 300, 500), 2},
+        {"pairs the greedy pass would miss", balances(-700, -300, 200, 300, 500), 3},
+        {"three groups", balances(-1, 1, -2, 2, -3, 3), 3},
+        {"nothing cancels", balances(-600, -400, 300, 700), 3},
+        {"split cents", balances(-3334, -3333, 6667), 2},
+    }
+
+    for _, tt := range tests {
+        got := SettleUp(tt.balances)
+        if len(got) != tt.transfers {
+            t.Errorf("%s: %d transfers, want %d: %v", tt.name, len(got), tt.transfers, got)
+        }
+        checkSettled(t, tt.name, tt.balances, got)
+    }
+}
+
+func TestSettleUpFallsBackAboveExactLimit(t *testing.T) {
+    nets := make([]int64, 0, maxExactSettle+2)
+    for i := 0; i < maxExactSettle/2+1; i++ {
+        nets = append(nets, int64(-100*(i+1)), int64(100*(i+1)))
+    }
+    b := balances(nets...)
+
+    got := SettleUp(b)
+    if len(got) > len(b)-1 {
+        t.Errorf("%d transfers for %d people, want at most %d", len(got), len(b), len(b)-1)
+    }
+    checkSettled(t, "greedy", b, got)
+}
+
+func TestSettleUpIsDeterministic(t *testing.T) {
+    b := balances(-700, -300, 200, 300, 500)
+    reversed := make([]Balance, len(b))
+    for i := range b {
+        reversed[len(b)-1-i] = b[i]
+    }
+
+    first, second := SettleUp(b), SettleUp(reversed)
+    if len(first) != len(second) {
+        t.Fatalf("got %d and %d transfers", len(first), len(second))
+    }
+    for i := range first {
+        if first[i] != second[i] {
+            t.Errorf("transfer %d: %v, then %v", i, first[i], second[i])
+        }
+    }
+}
+
+// checkSettled applies the transfers and checks every balance ends at zero
+// with only positive payments from debtors to creditors.
+func checkSettled(t *testing.T, name string, start []Balance, transfers []Transfer) {
+    t.Helper()
+    net := make(map[uuid.UUID]int64)
+    for _, b := range start {
+        net[b.UserID] = b.Net.MinorUnits()
+    }
+    for _, tr := range transfers {
+        if !tr.Amount.IsPositive() {
+            t.Errorf("%s: transfer of %s from %s to %s", name, tr.Amount, tr.From, tr.To)
+        }
+        if net[tr.From] >= 0 || net[tr.To] <= 0 {
+            t.Errorf("%s: transfer from %s to %s does not go from a debtor to a creditor", name, tr.From, tr.To)
+        }
+        net[tr.From] += tr.Amount.MinorUnits()
+        net[tr.To] -= tr.Amount.MinorUnits()
+    }
+    for id, n := range net {
+        if n != 0 {
+            t.Errorf("%s: %s is left with %d", name, id, n)
+        }
+    }
+}
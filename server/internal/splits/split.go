@@ -0,0 +1,156 @@
+package splits
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/LuisBAndrade/etracker/internal/money"
+	"github.com/google/uuid"
+)
+
+const (
+    MethodEqual      = "equal"
+    MethodShares     = "shares"
+    MethodExact      = "exact"
+    MethodPercentage = "percentage"
+)
+
+const (
+    MaxParticipants = 50
+    maxShareDigits  = 4
+)
+
+// Participant is one person's part of a split as entered. Share holds the
+// share count or percentage for those methods, Amount the exact amount.
+type Participant struct {
+    UserID uuid.UUID
+    Share  *big.Rat
+    Amount money.Amount
+}
+
+func validMethod(method string) bool {
+    switch method {
+    case MethodEqual, MethodShares, MethodExact, MethodPercentage:
+        return true
+    }
+    return false
+}
+
+// ParseShare reads a share count or percentage with at most four decimals.
+func ParseShare(s string) (*big.Rat, error) {
+    r, ok := new(big.Rat).SetString(s)
+    if !ok || r.Sign() <= 0 {
+        return nil, fmt.Errorf("%w: shares must be positive numbers", ErrInvalidSplit)
+    }
+    scaled := new(big.Rat).Mul(r, big.NewRat(10000, 1))
+    if !scaled.IsInt() {
+        return nil, fmt.Errorf("%w: shares can have at most %d decimals", ErrInvalidSplit, maxShareDigits)
+    }
+    return r, nil
+}
+
+// formatShare renders a share for the share column, trimming trailing zeros.
+func formatShare(r *big.Rat) string {
+    s := r.FloatString(maxShareDigits)
+    for s[len(s)-1] == '0' {
+        s = s[:len(s)-1]
+    }
+    if s[len(s)-1] == '.' {
+        s = s[:len(s)-1]
+    }
+    return s
+}
+
+// Allocate works out how much of total each participant owes. Weighted
+// methods round with the largest remainder method so the parts always add
+// up to the total, to the cent.
+func Allocate(total money.Amount, method string, participants []Participant) ([]money.Amount, error) {
+    if len(participants) == 0 {
+        return nil, fmt.Errorf("%w: at least one participant is required", ErrInvalidSplit)
+    }
+    if len(participants) > MaxParticipants {
+        return nil, fmt.Errorf("%w: at most %d participants", ErrInvalidSplit, MaxParticipants)
+    }
+    seen := make(map[uuid.UUID]bool)
+    for _, p := range participants {
+        if seen[p.UserID] {
+            return nil, fmt.Errorf("%w: participants must be unique", ErrInvalidSplit)
+        }
+        seen[p.UserID] = true
+    }
+    
+    weights := make([]*big.Rat, len(participants))
+    switch method {
+    case MethodEqual:
+        for i := range participants {
+            weights[i] = big.NewRat(1, 1)
+        }
+    case MethodShares, MethodPercentage:
+        sum := new(big.Rat)
+        for i, p := range participants {
+            if p.Share == nil || p.Share.Sign() <= 0 {
+                return nil, fmt.Errorf("%w: every participant needs a positive share", ErrInvalidSplit)
+            }
+            weights[i] = p.Share
+            sum.Add(sum, p.Share)
+        }
+        if method == MethodPercentage && sum.Cmp(big.NewRat(100, 1)) != 0 {
+            return nil, fmt.Errorf("%w: percentages must add up to 100", ErrInvalidSplit)
+        }
+    case MethodExact:
+        amounts := make([]money.Amount, len(participants))
+        for i, p := range participants {
+            if p.Amount.IsNegative() {
+                return nil, fmt.Errorf("%w: amounts cannot be negative", ErrInvalidSplit)
+            }
+            amounts[i] = p.Amount
+        }
+        sum, err := money.Sum(amounts...)
+        if err != nil {
+            return nil, err
+        }
+        if sum != total {
+            return nil, fmt.Errorf("%w: amounts add up to %s, not %s", ErrSplitMismatch, sum, total)
+        }
+        return amounts, nil
+    default:
+        return nil, fmt.Errorf("%w: method must be equal, shares, exact or percentage", ErrInvalidSplit)
+    }
+    return allocateWeighted(total, weights), nil
+}
+
+func allocateWeighted(total money.Amount, weights []*big.Rat) []money.Amount {
+    sum := new(big.Rat)
+    for _, w := range weights {
+        sum.Add(sum, w)
+    }
+    
+    cents := new(big.Int).SetInt64(total.MinorUnits())
+    amounts := make([]money.Amount, len(weights))
+    remainders := make([]*big.Rat, len(weights))
+    allocated := int64(0)
+    for i, w := range weights {
+        exact := new(big.Rat).Mul(new(big.Rat).SetInt(cents), w)
+        exact.Quo(exact, sum)
+        floor := new(big.Int).Quo(exact.Num(), exact.Denom())
+        amounts[i] = money.FromMinorUnits(floor.Int64())
+        remainders[i] = exact.Sub(exact, new(big.Rat).SetInt(floor))
+        allocated += floor.Int64()
+    }
+    
+    // Hand the leftover cents to the largest remainders, earlier
+    // participants first on ties.
+    order := make([]int, len(weights))
+    for i := range order {
+        order[i] = i
+    }
+    sort.SliceStable(order, func(a, b int) bool {
+        return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+    })
+    for i := int64(0); i < total.MinorUnits()-allocated; i++ {
+        idx := order[i]
+        amounts[idx] = money.FromMinorUnits(amounts[idx].MinorUnits() + 1)
+    }
+    return amounts
+}
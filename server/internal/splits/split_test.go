@@ -0,0 +1,157 @@
+package splits
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+    "testing"
+
+    "github.com/LuisBAndrade/etracker/internal/money"
+    "github.com/google/uuid"
+)
+
+func user(n int) uuid.UUID {
+    return uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+}
+
+func shares(values ...string) []Participant {
+    participants := make([]Participant, len(values))
+    for i, v := range values {
+        r, ok := new(big.Rat).SetString(v)
+        if !ok {
+            panic("bad share " + v)
+        }
+        participants[i] = Participant{UserID: user(i + 1), Share: r}
+    }
+    return participants
+}
+
+func exact(cents ...int64) []Participant {
+    participants := make([]Participant, len(cents))
+    for i, c := range cents {
+        participants[i] = Participant{UserID: user(i + 1), Amount: money.FromMinorUnits(c)}
+    }
+    return participants
+}
+
+func TestAllocate(t *testing.T) {
+    tests := []struct {
+        name         string
+        total        int64
+        method       string
+        participants []Participant
+        want         []int64
+        wantErr      error
+    }{
+        {"equal even", 900, MethodEqual, exact(0, 0, 0), []int64{300, 300, 300}, nil},
+        {"equal remainder goes first", 100, MethodEqual, exact(0, 0, 0), []int64{34, 33, 33}, nil},
+        {"equal two leftover cents", 101, MethodEqual, exact(0, 0, 0), []int64{34, 34, 33}, nil},
+        {"equal single cent", 1, MethodEqual, exact(0, 0, 0), []int64{1, 0, 0}, nil},
+        {"equal zero", 0, MethodEqual, exact(0, 0), []int64{0, 0}, nil},
+        {"shares", 100, MethodShares, shares("1", "2"), []int64{33, 67}, nil},
+        {"fractional shares", 1000, MethodShares, shares("0.5", "1.5"), []int64{250, 750}, nil},
+        {"shares largest remainder", 1000, MethodShares, shares("1", "1", "1", "4"), []int64{143, 143, 143, 571}, nil},
+        {"percentage", 1000, MethodPercentage, shares("33.3333", "33.3333", "33.3334"), []int64{333, 333, 334}, nil},
+        {"percentage whole", 2500, MethodPercentage, shares("60", "40"), []int64{1500, 1000}, nil},
+        {"percentage not 100", 1000, MethodPercentage, shares("50", "49"), nil, ErrInvalidSplit},
+        {"shares missing", 1000, MethodShares, []Participant{{UserID: user(1)}}, nil, ErrInvalidSplit},
+        {"exact", 1000, MethodExact, exact(250, 750), []int64{250, 750}, nil},
+        {"exact with zero", 1000, MethodExact, exact(1000, 0), []int64{1000, 0}, nil},
+        {"exact mismatch", 1000, MethodExact, exact(250, 700), nil, ErrSplitMismatch},
+        {"exact negative", 1000, MethodExact, exact(1100, -100), nil, ErrInvalidSplit},
+        {"no participants", 1000, MethodEqual, nil, nil, ErrInvalidSplit},
+        {"duplicate participant", 1000, MethodEqual, []Participant{{UserID: user(1)}, {UserID: user(1)}}, nil, ErrInvalidSplit},
+        {"unknown method", 1000, "thirds", exact(0), nil, ErrInvalidSplit},
+    }
+
+    for _, tt := range tests {
+        got, err := Allocate(money.FromMinorUnits(tt.total), tt.method, tt.participants)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if len(got) != len(tt.want) {
+            t.Errorf("%s: got %d amounts, want %d", tt.name, len(got), len(tt.want))
+            continue
+        }
+        for i := range tt.want {
+            if got[i].MinorUnits() != tt.want[i] {
+                t.Errorf("%s: amount %d = %d, want %d", tt.name, i, got[i].MinorUnits(), tt.want[i])
+            }
+        }
+    }
+}
+
+func TestAllocateTooManyParticipants(t *testing.T) {
+    participants := make([]Participant, MaxParticipants+1)
+    for i := range participants {
+        participants[i] = Participant{UserID: user(i + 1)}
+    }
+    if _, err := Allocate(money.FromMinorUnits(100), MethodEqual, participants); !errors.Is(err, ErrInvalidSplit) {
+        t.Errorf("error = %v, want %v", err, ErrInvalidSplit)
+    }
+}
+
+func TestAllocateAddsUpToTotal(t *testing.T) {
+    weights := [][]string{
+        {"1", "1", "1"},
+        {"1", "2", "3", "4", "5", "6", "7"},
+        {"0.0001", "9999.9999"},
+        {"3", "3", "3", "3", "3", "3"},
+    }
+    totals := []int64{1, 2, 7, 99, 100, 101, 12345, 99999999}
+
+    for _, w := range weights {
+        for _, total := range totals {
+            got, err := Allocate(money.FromMinorUnits(total), MethodShares, shares(w...))
+            if err != nil {
+                t.Fatalf("Allocate(%d, %v): %v", total, w, err)
+            }
+            var sum int64
+            for _, a := range got {
+                if a.IsNegative() {
+                    t.Errorf("Allocate(%d, %v) gave a negative part %s", total, w, a)
+                }
+                sum += a.MinorUnits()
+            }
+            if sum != total {
+                t.Errorf("Allocate(%d, %v) adds up to %d", total, w, sum)
+            }
+        }
+    }
+}
+
+func TestParseShare(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+        wantErr bool
+    }{
+        {"1", "1", false},
+        {"1.5", "1.5", false},
+        {"33.3333", "33.3333", false},
+        {"2.50000", "2.5", false},
+        {"33.33333", "", true},
+        {"0", "", true},
+        {"-1", "", true},
+        {"abc", "", true},
+        {"", "", true},
+    }
+
+    for _, tt := range tests {
+        got, err := ParseShare(tt.in)
+        if tt.wantErr {
+            if !errors.Is(err, ErrInvalidSplit) {
+                t.Errorf("ParseShare(%q) error = %v, want %v", tt.in, err, ErrInvalidSplit)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("ParseShare(%q): %v", tt.in, err)
+            continue
+        }
+        if s := formatShare(got); s != tt.want {
+            t.Errorf("formatShare(ParseShare(%q)) = %q, want %q", tt.in, s, tt.want)
+        }
+    }
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body, used for GET and DELETE.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+type S3Config struct {
+    Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+    Bucket          string
+    Region          string
+    AccessKeyID     string
+    SecretAccessKey string
+    PathStyle       bool // Required by MinIO and most other S3-compatible servers
+}
+
+// S3Store talks to an S3-compatible object store, signing requests with
+// AWS Signature Version 4.
+type S3Store struct {
+    cfg      S3Config
+    endpoint *url.URL
+    client   *http.Client
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+    endpoint, err := url.Parse(cfg.Endpoint)
+    if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+        return nil, fmt.Errorf("storage: invalid S3 endpoint %q", cfg.Endpoint)
+    }
+    if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+        return nil, fmt.Errorf("storage: S3 bucket and credentials are required")
+    }
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+    return &S3Store{
+        cfg:      cfg,
+        endpoint: endpoint,
+        client:   &http.Client{Timeout: 5 * time.Minute},
+    }, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+    hash := sha256.New()
+    if _, err := io.Copy(hash, body); err != nil {
+        return err
+    }
+    if _, err := body.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    
+    req, err := s.newRequest(ctx, http.MethodPut, key, io.NopCloser(body))
+    if err != nil {
+        return err
+    }
+    req.ContentLength = size
+    if contentType != "" {
+        req.Header.Set("Content-Type", contentType)
+    }
+    s.sign(req, hex.EncodeToString(hash.Sum(nil)), time.Now())
+    
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return responseError(resp)
+    }
+    return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+    if err != nil {
+        return nil, err
+    }
+    s.sign(req, emptyPayloadHash, time.Now())
+    
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    switch resp.StatusCode {
+    case http.StatusOK:
+        return resp.Body, nil
+    case http.StatusNotFound:
+        resp.Body.Close()
+        return nil, ErrNotFound
+    default:
+        defer resp.Body.Close()
+        return nil, responseError(resp)
+    }
+}
+
+// Delete succeeds for missing keys, matching S3's own semantics.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+    req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+    if err != nil {
+        return err
+    }
+    s.sign(req, emptyPayloadHash, time.Now())
+    
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+        return responseError(resp)
+    }
+    return nil
+}
+
+func (s *S3Store) newRequest(ctx context.Context, method, key string, body io.ReadCloser) (*http.Request, error) {
+    if !validKey(key) {
+        return nil, ErrInvalidKey
+    }
+    
+    u := *s.endpoint
+    path := "/" + uriEncode(key, false)
+    if s.cfg.PathStyle {
+        path = "/" + uriEncode(s.cfg.Bucket, true) + path
+    } else {
+        u.Host = s.cfg.Bucket + "." + u.Host
+    }
+    u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + path
+    u.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + path
+    
+    req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+    if err != nil {
+        return nil, err
+    }
+    if body != nil {
+        req.Body = body
+    }
+    return req, nil
+}
+
+// sign adds SigV4 headers. Only host, x-amz-content-sha256 and x-amz-date
+// are signed, which is all S3 requires.
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+    now = now.UTC()
+    amzDate := now.Format("20060102T150405Z")
+    day := now.Format("20060102")
+    
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+    
+    signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        req.URL.RawQuery,
+        "host:" + req.URL.Host,
+        "x-amz-content-sha256:" + payloadHash,
+        "x-amz-date:" + amzDate,
+        "",
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+    
+    scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+    requestHash := sha256.Sum256([]byte(canonicalRequest))
+    stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+    
+    key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), day)
+    key = hmacSHA256(key, s.cfg.Region)
+    key = hmacSHA256(key, "s3")
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+    
+    req.Header.Set("Authorization", fmt.Sprintf(
+        "AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.cfg.AccessKeyID, scope, signedHeaders, signature,
+    ))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
+
+// uriEncode applies SigV4's encoding: everything but unreserved characters
+// is percent-encoded, and slashes too unless encodeSlash is false.
+func uriEncode(s string, encodeSlash bool) string {
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+            c == '-', c == '_', c == '.', c == '~':
+            b.WriteByte(c)
+        case c == '/' && !encodeSlash:
+            b.WriteByte(c)
+        default:
+            fmt.Fprintf(&b, "%%%02X", c)
+        }
+    }
+    return b.String()
+}
+
+func responseError(resp *http.Response) error {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    return fmt.Errorf("storage: S3 returned %s: %s", strconv.Itoa(resp.StatusCode), strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,35 @@
+// Package storage keeps attachment blobs outside the database.
+package storage
+
+import (
+    "context"
+    "errors"
+    "io"
+    "strings"
+)
+
+var (
+    ErrNotFound   = errors.New("blob not found")
+    ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store saves and serves opaque blobs by key. Keys are slash-separated
+// relative paths; Put on an existing key replaces it.
+type Store interface {
+    Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape a store's root.
+func validKey(key string) bool {
+    if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+        return false
+    }
+    for _, part := range strings.Split(key, "/") {
+        if part == "" || part == "." || part == ".." {
+            return false
+        }
+    }
+    return true
+}
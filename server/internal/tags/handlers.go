@@ -0,0 +1,67 @@
+package tags
+
+import (
+    "net/http"
+
+    "github.com/LuisBAndrade/etracker/internal/auth"
+    "github.com/LuisBAndrade/etracker/internal/utils"
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+)
+
+type TagResponse struct {
+    ID           string `json:"id"`
+    Name         string `json:"name"`
+    ExpenseCount int64  `json:"expense_count"`
+    CreatedAt    string `json:"created_at"`
+}
+
+func (s *Service) HandleGetTags(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    tags, err := s.GetLedgerTags(r.Context(), ledger.ID)
+    if err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get tags")
+        return
+    }
+    
+    response := make([]TagResponse, len(tags))
+    for i, tag := range tags {
+        response[i] = TagResponse{
+            ID:           tag.ID.String(),
+            Name:         tag.Name,
+            ExpenseCount: tag.ExpenseCount,
+            CreatedAt:    tag.CreatedAt.Format("2006-01-02T15:04:05Z"),
+        }
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
+    ledger, ok := auth.GetLedgerFromContext(r.Context())
+    if !ok {
+        utils.RespondWithError(w, http.StatusForbidden, "No active ledger")
+        return
+    }
+    
+    vars := mux.Vars(r)
+    tagID, err := uuid.Parse(vars["id"])
+    if err != nil {
+        utils.RespondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+        return
+    }
+    
+    if err := s.DeleteTag(r.Context(), tagID, ledger.ID); err != nil {
+        utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete tag")
+        return
+    }
+    
+    utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+        "message": "Tag deleted successfully",
+    })
+}
@@ -0,0 +1,103 @@
+package tags
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+
+    "github.com/LuisBAndrade/etracker/internal/database"
+    "github.com/google/uuid"
+)
+
+const (
+    maxTagLength      = 40
+    MaxTagsPerExpense = 20
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+type Service struct {
+    queries *database.Queries
+}
+
+func NewService(queries *database.Queries) *Service {
+    return &Service{queries: queries}
+}
+
+// Normalize lower-cases and trims tag names, drops duplicates and sorts
+// them. A nil slice stays nil so callers can tell "not given" from "none".
+func Normalize(names []string) ([]string, error) {
+    if names == nil {
+        return nil, nil
+    }
+    
+    seen := make(map[string]bool)
+    result := []string{}
+    for _, name := range names {
+        name = strings.ToLower(strings.TrimSpace(name))
+        if name == "" {
+            return nil, fmt.Errorf("%w: tags cannot be empty", ErrInvalidTag)
+        }
+        if utf8.RuneCountInString(name) > maxTagLength {
+            return nil, fmt.Errorf("%w: tags must be at most %d characters", ErrInvalidTag, maxTagLength)
+        }
+        if strings.ContainsFunc(name, func(r rune) bool { return r == ',' || unicode.IsControl(r) }) {
+            return nil, fmt.Errorf("%w: tags cannot contain commas", ErrInvalidTag)
+        }
+        if !seen[name] {
+            seen[name] = true
+            result = append(result, name)
+        }
+    }
+    if len(result) > MaxTagsPerExpense {
+        return nil, fmt.Errorf("%w: at most %d tags per expense", ErrInvalidTag, MaxTagsPerExpense)
+    }
+    
+    sort.Strings(result)
+    return result, nil
+}
+
+// SetExpenseTags replaces an expense's tags, creating tags that don't
+// exist yet. Pass queries bound to the transaction that wrote the expense.
+func SetExpenseTags(ctx context.Context, queries *database.Queries, ledgerID, userID, expenseID uuid.UUID, names []string) error {
+    if err := queries.ClearExpenseTags(ctx, expenseID); err != nil {
+        return err
+    }
+    if len(names) == 0 {
+        return nil
+    }
+    
+    tags, err := queries.EnsureTags(ctx, database.EnsureTagsParams{
+        LedgerID: ledgerID,
+        UserID:   userID,
+        Names:    names,
+    })
+    if err != nil {
+        return err
+    }
+    
+    tagIDs := make([]uuid.UUID, len(tags))
+    for i, tag := range tags {
+        tagIDs[i] = tag.ID
+    }
+    return queries.AddExpenseTags(ctx, database.AddExpenseTagsParams{
+        ExpenseID: expenseID,
+        TagIds:    tagIDs,
+    })
+}
+
+func (s *Service) GetLedgerTags(ctx context.Context, ledgerID uuid.UUID) ([]database.GetTagsByLedgerRow, error) {
+    return s.queries.GetTagsByLedger(ctx, ledgerID)
+}
+
+// DeleteTag removes the tag from every expense it was on.
+func (s *Service) DeleteTag(ctx context.Context, tagID, ledgerID uuid.UUID) error {
+    return s.queries.DeleteTag(ctx, database.DeleteTagParams{
+        ID:       tagID,
+        LedgerID: ledgerID,
+    })
+}
@@ -39,6 +39,9 @@ func validateRule(field reflect.Value, rule, fieldName string) error {
         if field.Kind() == reflect.String && field.String() == "" {
             return fmt.Errorf("%s is required", fieldName)
         }
+        if field.Kind() == reflect.Int64 && field.Int() == 0 {
+            return fmt.Errorf("%s is required", fieldName)
+        }
     case rule == "email":
         if field.Kind() == reflect.String {
             email := field.String()
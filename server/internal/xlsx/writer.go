@@ -0,0 +1,154 @@
+// Package xlsx writes single-sheet Office Open XML workbooks row by row, so
+// large exports never have to be held in memory.
+package xlsx
+
+import (
+    "archive/zip"
+    "bufio"
+    "bytes"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+)
+
+// Cell is one worksheet value. Numbers are written from their decimal
+// string form so amounts never pass through float64.
+type Cell struct {
+    Value  string
+    Number bool
+}
+
+func String(s string) Cell { return Cell{Value: s} }
+func Number(s string) Cell { return Cell{Value: s, Number: true} }
+
+type Writer struct {
+    zw    *zip.Writer
+    sheet *bufio.Writer
+    row   int
+}
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const workbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const sheetFooter = `</sheetData></worksheet>`
+
+// NewWriter starts a workbook with a single sheet called sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+    zw := zip.NewWriter(w)
+
+    var name bytes.Buffer
+    if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+        return nil, err
+    }
+
+    parts := []struct{ name, body string }{
+        {"[Content_Types].xml", contentTypes},
+        {"_rels/.rels", rootRels},
+        {"xl/_rels/workbook.xml.rels", workbookRels},
+        {"xl/workbook.xml", fmt.Sprintf(workbookTemplate, name.String())},
+    }
+    for _, part := range parts {
+        f, err := zw.Create(part.name)
+        if err != nil {
+            return nil, err
+        }
+        if _, err := io.WriteString(f, part.body); err != nil {
+            return nil, err
+        }
+    }
+
+    f, err := zw.Create("xl/worksheets/sheet1.xml")
+    if err != nil {
+        return nil, err
+    }
+    sheet := bufio.NewWriter(f)
+    if _, err := sheet.WriteString(sheetHeader); err != nil {
+        return nil, err
+    }
+
+    return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends one row to the sheet.
+func (w *Writer) WriteRow(cells []Cell) error {
+    if w.sheet == nil {
+        return errors.New("xlsx: write after close")
+    }
+    w.row++
+
+    w.sheet.WriteString(`<row r="` + strconv.Itoa(w.row) + `">`)
+    for i, c := range cells {
+        ref := columnName(i) + strconv.Itoa(w.row)
+        if c.Number {
+            w.sheet.WriteString(`<c r="` + ref + `"><v>`)
+            xml.EscapeText(w.sheet, []byte(c.Value))
+            w.sheet.WriteString(`</v></c>`)
+            continue
+        }
+        w.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+        if err := xml.EscapeText(w.sheet, []byte(c.Value)); err != nil {
+            return err
+        }
+        w.sheet.WriteString(`</t></is></c>`)
+    }
+    _, err := w.sheet.WriteString(`</row>`)
+    return err
+}
+
+// Flush pushes buffered rows to the underlying writer.
+func (w *Writer) Flush() error {
+    if err := w.sheet.Flush(); err != nil {
+        return err
+    }
+    return w.zw.Flush()
+}
+
+// Close finishes the sheet and the zip archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+    if w.sheet == nil {
+        return nil
+    }
+    if _, err := w.sheet.WriteString(sheetFooter); err != nil {
+        return err
+    }
+    if err := w.sheet.Flush(); err != nil {
+        return err
+    }
+    w.sheet = nil
+    return w.zw.Close()
+}
+
+// columnName converts a zero-based index to A, B, ..., Z, AA, AB, ...
+func columnName(i int) string {
+    name := ""
+    for i++; i > 0; i = (i - 1) / 26 {
+        name = string(rune('A'+(i-1)%26)) + name
+    }
+    return name
+}
@@ -0,0 +1,24 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (user_id, name, prefix, token_hash, scopes, expires_at, created_at)
+VALUES (sqlc.arg(user_id), sqlc.arg(name), sqlc.arg(prefix), sqlc.arg(token_hash), sqlc.arg(scopes)::text[], sqlc.narg(expires_at), NOW())
+RETURNING *;
+
+-- name: GetAPITokensByUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetUserByAPIToken :one
+SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password, u.base_currency, u.email_verified_at, u.active_ledger_id,
+    t.id AS token_id, t.scopes
+FROM api_tokens t
+JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > NOW());
+
+-- name: TouchAPIToken :exec
+-- Records use at most once a minute to keep writes off the hot path.
+UPDATE api_tokens SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,36 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (expense_id, user_id, filename, content_type, size_bytes, sha256)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAttachmentsByExpense :many
+-- Attachments belong to whoever can see the expense, not just the uploader.
+SELECT a.* FROM attachments a
+JOIN expenses e ON e.id = a.expense_id
+WHERE a.expense_id = $1 AND e.ledger_id = $2
+ORDER BY a.created_at;
+
+-- name: GetAttachmentByID :one
+SELECT a.* FROM attachments a
+JOIN expenses e ON e.id = a.expense_id
+WHERE a.id = $1 AND a.expense_id = $2 AND e.ledger_id = $3;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments a
+USING expenses e
+WHERE a.id = $1 AND e.id = a.expense_id AND e.ledger_id = $2;
+
+-- name: GetAttachmentHashesByExpense :many
+SELECT DISTINCT sha256 FROM attachments WHERE expense_id = $1;
+
+-- name: GetAttachmentHashesByLedger :many
+SELECT DISTINCT a.sha256 FROM attachments a
+JOIN expenses e ON e.id = a.expense_id
+WHERE e.ledger_id = $1;
+
+-- name: CountAttachmentsBySHA256 :one
+SELECT COUNT(*) FROM attachments WHERE sha256 = $1;
+
+-- name: LockBlob :exec
+-- Serializes uploads and deletes of the same blob until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtext($1));
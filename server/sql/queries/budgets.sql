@@ -0,0 +1,18 @@
+-- name: UpsertBudget :one
+-- Inserting through categories makes sure the category belongs to the ledger.
+INSERT INTO budgets (ledger_id, user_id, category_id, month, amount, rollover, created_at, updated_at)
+SELECT c.ledger_id, sqlc.arg(user_id), c.id, sqlc.arg(month)::DATE, sqlc.arg(amount)::NUMERIC, sqlc.arg(rollover)::BOOLEAN, NOW(), NOW()
+FROM categories c
+WHERE c.id = sqlc.arg(category_id) AND c.ledger_id = sqlc.arg(ledger_id)
+ON CONFLICT (category_id, month)
+DO UPDATE SET amount = EXCLUDED.amount, rollover = EXCLUDED.rollover, updated_at = NOW()
+RETURNING *;
+
+-- name: GetBudgetsByLedgerAndMonthRange :many
+SELECT * FROM budgets
+WHERE ledger_id = $1 AND month BETWEEN $2 AND $3
+ORDER BY month;
+
+-- name: DeleteBudget :exec
+DELETE FROM budgets
+WHERE id = $1 AND ledger_id = $2;
@@ -1,23 +1,84 @@
 -- name: CreateCategory :one
-INSERT INTO categories (user_id, name, color, created_at)
-VALUES ($1, $2, $3, NOW())
+INSERT INTO categories (ledger_id, user_id, name, color, parent_id, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
 RETURNING *;
 
--- name: GetCategoriesByUser :many
+-- name: GetCategoriesByLedger :many
 SELECT * FROM categories 
-WHERE user_id = $1
+WHERE ledger_id = $1
 ORDER BY name;
 
 -- name: GetCategoryByID :one
 SELECT * FROM categories 
-WHERE id = $1 AND user_id = $2;
+WHERE id = $1 AND ledger_id = $2;
 
 -- name: UpdateCategory :one
 UPDATE categories
-SET name = $2, color = $3
-WHERE id = $1 AND user_id = $4
+SET name = $2, color = $3, parent_id = $5
+WHERE id = $1 AND ledger_id = $4
 RETURNING *;
 
 -- name: DeleteCategory :exec
 DELETE FROM categories 
-WHERE id = $1 AND user_id = $2;
\ No newline at end of file
+WHERE id = $1 AND ledger_id = $2;
+
+-- name: LockCategoriesByLedger :exec
+-- Serializes changes to a ledger's category tree for the transaction.
+SELECT id FROM categories WHERE ledger_id = $1 FOR UPDATE;
+
+-- name: CategoryHasAncestor :one
+-- True when ancestor_id is category_id itself or one of its parents.
+WITH RECURSIVE chain AS (
+    SELECT id, parent_id FROM categories WHERE id = sqlc.arg(category_id)
+    UNION
+    SELECT c.id, c.parent_id FROM categories c JOIN chain ON c.id = chain.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM chain WHERE id = sqlc.arg(ancestor_id));
+
+-- name: ReparentChildCategories :exec
+UPDATE categories
+SET parent_id = sqlc.narg(parent_id)
+WHERE parent_id = sqlc.arg(category_id) AND ledger_id = sqlc.arg(ledger_id);
+
+-- name: SetCategoryParent :exec
+UPDATE categories
+SET parent_id = sqlc.narg(parent_id)
+WHERE id = sqlc.arg(id) AND ledger_id = sqlc.arg(ledger_id);
+
+-- name: MoveExpensesToCategory :execrows
+UPDATE expenses
+SET category_id = sqlc.arg(target_id), updated_at = NOW()
+WHERE category_id = sqlc.arg(source_id) AND ledger_id = sqlc.arg(ledger_id);
+
+-- name: MoveRecurringExpensesToCategory :execrows
+UPDATE recurring_expenses
+SET category_id = sqlc.arg(target_id), updated_at = NOW()
+WHERE category_id = sqlc.arg(source_id) AND ledger_id = sqlc.arg(ledger_id);
+
+-- name: AddOverlappingBudgets :execrows
+-- Where both categories have a budget for the same month the target's
+-- amount absorbs the source's.
+UPDATE budgets t
+SET amount = t.amount + s.amount, updated_at = NOW()
+FROM budgets s
+WHERE t.category_id = sqlc.arg(target_id) AND s.category_id = sqlc.arg(source_id)
+  AND t.month = s.month AND t.ledger_id = sqlc.arg(ledger_id) AND s.ledger_id = sqlc.arg(ledger_id);
+
+-- name: DeleteOverlappingBudgets :exec
+DELETE FROM budgets s
+USING budgets t
+WHERE s.category_id = sqlc.arg(source_id) AND t.category_id = sqlc.arg(target_id)
+  AND s.month = t.month AND s.ledger_id = sqlc.arg(ledger_id);
+
+-- name: MoveBudgetsToCategory :execrows
+UPDATE budgets
+SET category_id = sqlc.arg(target_id), updated_at = NOW()
+WHERE category_id = sqlc.arg(source_id) AND ledger_id = sqlc.arg(ledger_id);
+
+-- name: EnsureCategory :one
+-- Creates the category unless the ledger already has one by that name, in
+-- which case the existing row is returned unchanged.
+INSERT INTO categories (ledger_id, user_id, name, color, parent_id, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (ledger_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
@@ -0,0 +1,21 @@
+-- name: CreateEmailChangeToken :one
+INSERT INTO email_change_tokens (user_id, new_email, token_hash, expires_at, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: InvalidateEmailChangeTokens :exec
+UPDATE email_change_tokens SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: GetValidEmailChangeToken :one
+SELECT * FROM email_change_tokens
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+FOR UPDATE;
+
+-- name: MarkEmailChangeTokenUsed :exec
+UPDATE email_change_tokens SET used_at = NOW()
+WHERE id = $1;
+
+-- name: CleanupExpiredEmailChangeTokens :exec
+DELETE FROM email_change_tokens
+WHERE expires_at <= NOW() OR used_at IS NOT NULL;
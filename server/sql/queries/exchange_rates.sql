@@ -0,0 +1,23 @@
+-- name: UpsertExchangeRate :exec
+INSERT INTO exchange_rates (base_currency, quote_currency, rate_date, rate, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (base_currency, quote_currency, rate_date)
+DO UPDATE SET rate = EXCLUDED.rate;
+
+-- name: GetLatestExchangeRates :many
+-- Latest rate on or before as_of for every pair touching either currency,
+-- which covers direct, inverse and common-base cross conversions.
+SELECT DISTINCT ON (base_currency, quote_currency)
+       base_currency, quote_currency, rate_date, rate, created_at
+FROM exchange_rates
+WHERE rate_date <= sqlc.arg(as_of)
+  AND (base_currency IN (sqlc.arg(from_currency), sqlc.arg(to_currency))
+       OR quote_currency IN (sqlc.arg(from_currency), sqlc.arg(to_currency)))
+ORDER BY base_currency, quote_currency, rate_date DESC;
+
+-- name: GetExchangeRatesByBase :many
+SELECT DISTINCT ON (quote_currency)
+       base_currency, quote_currency, rate_date, rate, created_at
+FROM exchange_rates
+WHERE base_currency = $1 AND rate_date <= $2
+ORDER BY quote_currency, rate_date DESC;
@@ -1,61 +1,140 @@
 -- name: CreateExpense :one
-INSERT INTO expenses (user_id, category_id, amount, description, date, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+INSERT INTO expenses (ledger_id, user_id, category_id, amount, description, date, currency, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 RETURNING *;
 
--- name: GetExpensesByUser :many
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+-- name: GetExpensesByLedger :many
+-- Every filter is optional: NULL dates, an empty category list with
+-- uncategorized unset, has_*_amount false and empty query and tags match
+-- all. An expense must carry every listed tag.
+-- The cursor, when given, returns rows after it in list order.
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.user_id = $1
-ORDER BY e.date DESC, e.created_at DESC
-LIMIT $2 OFFSET $3;
-
--- name: GetExpensesByUserAndDateRange :many
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.ledger_id = sqlc.arg(ledger_id)
+  AND (sqlc.narg(start_date)::date IS NULL OR e.date >= sqlc.narg(start_date))
+  AND (sqlc.narg(end_date)::date IS NULL OR e.date <= sqlc.narg(end_date))
+  AND (
+      (cardinality(sqlc.arg(category_ids)::uuid[]) = 0 AND NOT sqlc.arg(uncategorized)::boolean)
+      OR e.category_id = ANY(sqlc.arg(category_ids)::uuid[])
+      OR (sqlc.arg(uncategorized)::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT sqlc.arg(has_min_amount)::boolean OR e.amount >= sqlc.arg(min_amount))
+  AND (NOT sqlc.arg(has_max_amount)::boolean OR e.amount <= sqlc.arg(max_amount))
+  AND (sqlc.arg(query)::text = '' OR e.description ILIKE '%' || sqlc.arg(query) || '%')
+  AND (
+      cardinality(sqlc.arg(tags)::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY(sqlc.arg(tags)::text[])) = cardinality(sqlc.arg(tags)::text[])
+  )
+  AND (
+      sqlc.narg(cursor_id)::uuid IS NULL
+      OR (e.date, e.created_at, e.id) < (sqlc.narg(cursor_date)::date, sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY e.date DESC, e.created_at DESC, e.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: GetExpensesByLedgerBackward :many
+-- GetExpensesByLedger for the rows before the cursor. They come back
+-- oldest first and the caller reverses them.
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.user_id = $1 AND e.date BETWEEN $2 AND $3
-ORDER BY e.date DESC, e.created_at DESC;
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.ledger_id = sqlc.arg(ledger_id)
+  AND (sqlc.narg(start_date)::date IS NULL OR e.date >= sqlc.narg(start_date))
+  AND (sqlc.narg(end_date)::date IS NULL OR e.date <= sqlc.narg(end_date))
+  AND (
+      (cardinality(sqlc.arg(category_ids)::uuid[]) = 0 AND NOT sqlc.arg(uncategorized)::boolean)
+      OR e.category_id = ANY(sqlc.arg(category_ids)::uuid[])
+      OR (sqlc.arg(uncategorized)::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT sqlc.arg(has_min_amount)::boolean OR e.amount >= sqlc.arg(min_amount))
+  AND (NOT sqlc.arg(has_max_amount)::boolean OR e.amount <= sqlc.arg(max_amount))
+  AND (sqlc.arg(query)::text = '' OR e.description ILIKE '%' || sqlc.arg(query) || '%')
+  AND (
+      cardinality(sqlc.arg(tags)::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY(sqlc.arg(tags)::text[])) = cardinality(sqlc.arg(tags)::text[])
+  )
+  AND (e.date, e.created_at, e.id) > (sqlc.arg(cursor_date)::date, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY e.date ASC, e.created_at ASC, e.id ASC
+LIMIT sqlc.arg(page_limit);
 
 -- name: GetExpenseByID :one
-SELECT e.id, e.user_id, e.category_id, e.amount, e.description, e.date, e.created_at, e.updated_at,
-       c.name as category_name, c.color as category_color
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags
 FROM expenses e
-LEFT JOIN categories c ON e.category_id = c.id
-WHERE e.id = $1 AND e.user_id = $2;
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+WHERE e.id = $1 AND e.ledger_id = $2;
 
 -- name: UpdateExpense :one
 UPDATE expenses
-SET amount = $3, description = $4, category_id = $5, date = $6, updated_at = NOW()
-WHERE id = $1 AND user_id = $2
+SET amount = $3, description = $4, category_id = $5, date = $6, currency = $7, updated_at = NOW()
+WHERE id = $1 AND ledger_id = $2
 RETURNING *;
 
 -- name: DeleteExpense :exec
-DELETE FROM expenses WHERE id = $1 AND user_id = $2;
-
--- name: GetExpenseTotalByUser :one
-SELECT COALESCE(SUM(amount), 0) as total
-FROM expenses
-WHERE user_id = $1;
+DELETE FROM expenses WHERE id = $1 AND ledger_id = $2;
 
--- name: GetExpenseTotalByUserAndDateRange :one
-SELECT COALESCE(SUM(amount), 0) as total
-FROM expenses
-WHERE user_id = $1 AND date BETWEEN $2 AND $3;
+-- name: GetExpenseTotalsByLedger :many
+-- Takes the same filters as GetExpensesByLedger.
+SELECT e.currency, e.date, COALESCE(SUM(e.amount), 0)::NUMERIC(20, 2) as total, COUNT(e.id) as expense_count
+FROM expenses e
+WHERE e.ledger_id = sqlc.arg(ledger_id)
+  AND (sqlc.narg(start_date)::date IS NULL OR e.date >= sqlc.narg(start_date))
+  AND (sqlc.narg(end_date)::date IS NULL OR e.date <= sqlc.narg(end_date))
+  AND (
+      (cardinality(sqlc.arg(category_ids)::uuid[]) = 0 AND NOT sqlc.arg(uncategorized)::boolean)
+      OR e.category_id = ANY(sqlc.arg(category_ids)::uuid[])
+      OR (sqlc.arg(uncategorized)::boolean AND e.category_id IS NULL)
+  )
+  AND (NOT sqlc.arg(has_min_amount)::boolean OR e.amount >= sqlc.arg(min_amount))
+  AND (NOT sqlc.arg(has_max_amount)::boolean OR e.amount <= sqlc.arg(max_amount))
+  AND (sqlc.arg(query)::text = '' OR e.description ILIKE '%' || sqlc.arg(query) || '%')
+  AND (
+      cardinality(sqlc.arg(tags)::text[]) = 0
+      OR (SELECT COUNT(*) FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id AND t.name = ANY(sqlc.arg(tags)::text[])) = cardinality(sqlc.arg(tags)::text[])
+  )
+GROUP BY e.currency, e.date;
 
 -- name: GetExpensesByCategory :many
+-- One row per category, currency and day so totals can be converted at
+-- the rate in effect on the day the money was spent.
 SELECT 
     c.id as category_id,
     c.name as category_name,
     c.color as category_color,
-    COALESCE(SUM(e.amount), 0) as total_amount,
+    c.parent_id,
+    e.currency,
+    e.date,
+    COALESCE(SUM(e.amount), 0)::NUMERIC(20, 2) as total_amount,
     COUNT(e.id) as expense_count
 FROM categories c
-LEFT JOIN expenses e ON c.id = e.category_id AND e.user_id = $1 AND e.date BETWEEN $2 AND $3
-WHERE c.user_id = $1
-GROUP BY c.id, c.name, c.color
-HAVING COUNT(e.id) > 0 OR $4 = true
-ORDER BY total_amount DESC;
\ No newline at end of file
+LEFT JOIN expenses e ON c.id = e.category_id AND e.ledger_id = sqlc.arg(ledger_id) AND e.date BETWEEN sqlc.arg(start_date) AND sqlc.arg(end_date)
+WHERE c.ledger_id = sqlc.arg(ledger_id)
+GROUP BY c.id, c.name, c.color, c.parent_id, e.currency, e.date
+HAVING COUNT(e.id) > 0 OR sqlc.arg(include_empty)::boolean;
+
+-- name: SearchExpenses :many
+-- query is tsquery syntax built by the caller. Highlights in snippet are
+-- wrapped in U+E000/U+E001 so the caller can escape the text first.
+SELECT e.id, e.user_id, e.category_id, e.amount, e.currency, e.description, e.date, e.created_at, e.updated_at,
+       c.name as category_name, c.color as category_color,
+       ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id ORDER BY t.name)::text[] as tags,
+       ts_rank(e.search_vector || COALESCE(c.search_vector, ''::tsvector), q)::real as rank,
+       ts_headline('english', e.description, q,
+           'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxWords=30, MinWords=10, MaxFragments=2') as snippet,
+       COUNT(*) OVER () as total_count
+FROM expenses e
+LEFT JOIN categories c ON e.category_id = c.id AND c.ledger_id = e.ledger_id
+CROSS JOIN to_tsquery('english', sqlc.arg(query)) q
+WHERE e.ledger_id = sqlc.arg(ledger_id)
+  AND (e.search_vector @@ q OR c.search_vector @@ q)
+ORDER BY rank DESC, e.date DESC, e.created_at DESC, e.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
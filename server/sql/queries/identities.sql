@@ -0,0 +1,18 @@
+-- name: GetUserByIdentity :one
+SELECT u.* FROM users u
+JOIN user_identities i ON i.user_id = u.id
+WHERE i.issuer = $1 AND i.subject = $2;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW())
+RETURNING *;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities SET last_login_at = NOW(), email = $3
+WHERE issuer = $1 AND subject = $2;
+
+-- name: GetIdentitiesByUser :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
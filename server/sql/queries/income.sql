@@ -0,0 +1,62 @@
+-- name: CreateIncomeCategory :one
+INSERT INTO income_categories (ledger_id, user_id, name, color, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: GetIncomeCategoriesByLedger :many
+SELECT * FROM income_categories
+WHERE ledger_id = $1
+ORDER BY name;
+
+-- name: GetIncomeCategoryByID :one
+SELECT * FROM income_categories
+WHERE id = $1 AND ledger_id = $2;
+
+-- name: UpdateIncomeCategory :one
+UPDATE income_categories
+SET name = $3, color = $4
+WHERE id = $1 AND ledger_id = $2
+RETURNING *;
+
+-- name: DeleteIncomeCategory :execrows
+DELETE FROM income_categories
+WHERE id = $1 AND ledger_id = $2;
+
+-- name: CreateIncome :one
+INSERT INTO incomes (ledger_id, user_id, category_id, amount, currency, description, date, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+RETURNING *;
+
+-- name: GetIncomesByLedger :many
+-- NULL dates match everything.
+SELECT i.id, i.user_id, i.category_id, i.amount, i.currency, i.description, i.date, i.created_at, i.updated_at,
+       c.name as category_name, c.color as category_color
+FROM incomes i
+LEFT JOIN income_categories c ON i.category_id = c.id
+WHERE i.ledger_id = sqlc.arg(ledger_id)
+  AND (sqlc.narg(start_date)::date IS NULL OR i.date >= sqlc.narg(start_date))
+  AND (sqlc.narg(end_date)::date IS NULL OR i.date <= sqlc.narg(end_date))
+ORDER BY i.date DESC, i.created_at DESC;
+
+-- name: UpdateIncome :one
+UPDATE incomes
+SET category_id = $3, amount = $4, currency = $5, description = $6, date = $7, updated_at = NOW()
+WHERE id = $1 AND ledger_id = $2
+RETURNING *;
+
+-- name: DeleteIncome :execrows
+DELETE FROM incomes WHERE id = $1 AND ledger_id = $2;
+
+-- name: GetIncomeTotalsByDay :many
+-- One row per currency and day so totals can be converted at the rate in
+-- effect on the day the money came in.
+SELECT currency, date, SUM(amount)::NUMERIC(20, 2) as total, COUNT(id) as income_count
+FROM incomes
+WHERE ledger_id = sqlc.arg(ledger_id) AND date BETWEEN sqlc.arg(start_date) AND sqlc.arg(end_date)
+GROUP BY currency, date;
+
+-- name: GetExpenseTotalsByDay :many
+SELECT currency, date, SUM(amount)::NUMERIC(20, 2) as total, COUNT(id) as expense_count
+FROM expenses
+WHERE ledger_id = sqlc.arg(ledger_id) AND date BETWEEN sqlc.arg(start_date) AND sqlc.arg(end_date)
+GROUP BY currency, date;
@@ -0,0 +1,93 @@
+-- name: CreateLedger :one
+INSERT INTO ledgers (name, created_at, updated_at)
+VALUES ($1, NOW(), NOW())
+RETURNING *;
+
+-- name: RenameLedger :one
+UPDATE ledgers SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteLedger :exec
+DELETE FROM ledgers WHERE id = $1;
+
+-- name: LockLedger :exec
+-- Serializes membership changes so a ledger can't lose its last owner.
+SELECT id FROM ledgers WHERE id = $1 FOR UPDATE;
+
+-- name: GetLedgerAccess :one
+-- The user's membership in ledger_id, or with ledger_id NULL their first
+-- own ledger.
+SELECT l.id, l.name, m.role
+FROM ledger_members m
+JOIN ledgers l ON l.id = m.ledger_id
+WHERE m.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(ledger_id)::uuid IS NULL OR m.ledger_id = sqlc.narg(ledger_id))
+ORDER BY (m.role = 'owner') DESC, m.created_at
+LIMIT 1;
+
+-- name: GetLedgersByUser :many
+SELECT l.id, l.name, l.created_at, l.updated_at, m.role,
+    (SELECT COUNT(*) FROM ledger_members o WHERE o.ledger_id = l.id) AS member_count
+FROM ledger_members m
+JOIN ledgers l ON l.id = m.ledger_id
+WHERE m.user_id = $1
+ORDER BY m.created_at, l.name;
+
+-- name: CountLedgersByUser :one
+SELECT COUNT(*) FROM ledger_members WHERE user_id = $1;
+
+-- name: AddLedgerMember :one
+INSERT INTO ledger_members (ledger_id, user_id, role, created_at)
+VALUES ($1, $2, $3, NOW())
+RETURNING *;
+
+-- name: GetLedgerMembers :many
+SELECT m.user_id, u.email, m.role, m.created_at
+FROM ledger_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.ledger_id = $1
+ORDER BY m.created_at;
+
+-- name: UpdateLedgerMemberRole :execrows
+UPDATE ledger_members SET role = $3
+WHERE ledger_id = $1 AND user_id = $2;
+
+-- name: RemoveLedgerMember :execrows
+DELETE FROM ledger_members WHERE ledger_id = $1 AND user_id = $2;
+
+-- name: CountLedgerOwners :one
+SELECT COUNT(*) FROM ledger_members WHERE ledger_id = $1 AND role = 'owner';
+
+-- name: CreateLedgerInvitation :one
+-- Inviting the same address again, in any case, replaces the pending
+-- invitation.
+INSERT INTO ledger_invitations (ledger_id, email, role, invited_by, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (ledger_id, lower(email))
+DO UPDATE SET email = EXCLUDED.email, role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, expires_at = EXCLUDED.expires_at, created_at = NOW()
+RETURNING *;
+
+-- name: GetLedgerInvitations :many
+SELECT * FROM ledger_invitations
+WHERE ledger_id = $1 AND expires_at > NOW()
+ORDER BY created_at;
+
+-- name: GetInvitationsForEmail :many
+SELECT i.id, i.ledger_id, l.name AS ledger_name, i.role, u.email AS invited_by_email, i.expires_at, i.created_at
+FROM ledger_invitations i
+JOIN ledgers l ON l.id = i.ledger_id
+JOIN users u ON u.id = i.invited_by
+WHERE lower(i.email) = lower(sqlc.arg(email)) AND i.expires_at > NOW()
+ORDER BY i.created_at;
+
+-- name: GetInvitationForEmail :one
+SELECT * FROM ledger_invitations
+WHERE id = sqlc.arg(id) AND lower(email) = lower(sqlc.arg(email)) AND expires_at > NOW()
+FOR UPDATE;
+
+-- name: DeleteLedgerInvitation :execrows
+DELETE FROM ledger_invitations WHERE id = $1 AND ledger_id = $2;
+
+-- name: CleanupExpiredLedgerInvitations :exec
+DELETE FROM ledger_invitations WHERE expires_at <= NOW();
@@ -0,0 +1,32 @@
+-- Times are computed in the database so instances with skewed clocks agree.
+
+-- name: GetLoginAttemptBlock :one
+-- Seconds the key is still blocked for, zero or negative when it is not.
+SELECT COALESCE(EXTRACT(EPOCH FROM blocked_until - NOW()), 0)::float8 AS seconds
+FROM login_attempts
+WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+-- Starts counting again when the previous failure is older than the window.
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES (sqlc.arg(key), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8) THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures;
+
+-- name: SetLoginAttemptBlock :exec
+UPDATE login_attempts SET blocked_until = NOW() + make_interval(secs => sqlc.arg(seconds)::float8)
+WHERE key = sqlc.arg(key);
+
+-- name: ResetLoginAttempts :exec
+DELETE FROM login_attempts WHERE key = $1;
+
+-- name: CleanupLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE key LIKE sqlc.arg(prefix) || '%'
+    AND last_failure_at < NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8)
+    AND (blocked_until IS NULL OR blocked_until <= NOW());
@@ -0,0 +1,23 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+VALUES ($1, $2, $3, NOW())
+RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+-- Marks every outstanding token of the user as used so only the newest
+-- reset link works.
+UPDATE password_reset_tokens SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: GetValidPasswordResetToken :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+FOR UPDATE;
+
+-- name: MarkPasswordResetTokenUsed :exec
+UPDATE password_reset_tokens SET used_at = NOW()
+WHERE id = $1;
+
+-- name: CleanupExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at <= NOW() OR used_at IS NOT NULL;
@@ -0,0 +1,78 @@
+-- name: CreateRecurringExpense :one
+INSERT INTO recurring_expenses (
+    ledger_id, user_id, category_id, amount, currency, description, frequency, interval_count,
+    start_date, end_date, occurrence_limit, anchor_date, anchor_index, next_index, next_date,
+    created_at, updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW())
+RETURNING *;
+
+-- name: GetRecurringExpensesByLedger :many
+SELECT * FROM recurring_expenses
+WHERE ledger_id = $1
+ORDER BY next_date NULLS LAST, created_at;
+
+-- name: GetRecurringExpenseByID :one
+SELECT * FROM recurring_expenses
+WHERE id = $1 AND ledger_id = $2;
+
+-- name: LockRecurringExpense :one
+SELECT * FROM recurring_expenses
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateRecurringExpense :one
+UPDATE recurring_expenses
+SET category_id = sqlc.narg(category_id),
+    amount = sqlc.arg(amount),
+    currency = sqlc.arg(currency),
+    description = sqlc.arg(description),
+    frequency = sqlc.arg(frequency),
+    interval_count = sqlc.arg(interval_count),
+    end_date = sqlc.narg(end_date),
+    occurrence_limit = sqlc.narg(occurrence_limit),
+    anchor_date = sqlc.arg(anchor_date),
+    anchor_index = sqlc.arg(anchor_index),
+    next_date = sqlc.narg(next_date),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND ledger_id = sqlc.arg(ledger_id)
+RETURNING *;
+
+-- name: SetRecurringExpensePaused :one
+UPDATE recurring_expenses
+SET paused = $3, updated_at = NOW()
+WHERE id = $1 AND ledger_id = $2
+RETURNING *;
+
+-- name: AdvanceRecurringExpense :exec
+UPDATE recurring_expenses
+SET next_index = $2, next_date = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteRecurringExpense :exec
+DELETE FROM recurring_expenses
+WHERE id = $1 AND ledger_id = $2;
+
+-- name: GetDueRecurringExpenseIDs :many
+SELECT id FROM recurring_expenses
+WHERE NOT paused AND next_date <= $1
+ORDER BY next_date;
+
+-- name: CreateRecurringExpenseSkip :exec
+INSERT INTO recurring_expense_skips (recurring_expense_id, occurrence_date, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetRecurringExpenseSkips :many
+SELECT occurrence_date FROM recurring_expense_skips
+WHERE recurring_expense_id = $1 AND occurrence_date >= $2
+ORDER BY occurrence_date;
+
+-- name: CreateRecurringOccurrence :execrows
+INSERT INTO expenses (
+    ledger_id, user_id, category_id, amount, currency, description, date,
+    recurring_expense_id, occurrence_date, created_at, updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7, NOW(), NOW())
+ON CONFLICT (recurring_expense_id, occurrence_date) WHERE recurring_expense_id IS NOT NULL
+DO NOTHING;
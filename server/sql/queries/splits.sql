@@ -0,0 +1,82 @@
+-- name: UpsertExpenseSplit :one
+INSERT INTO expense_splits (expense_id, paid_by, method, created_at, updated_at)
+VALUES ($1, $2, $3, NOW(), NOW())
+ON CONFLICT (expense_id)
+DO UPDATE SET paid_by = EXCLUDED.paid_by, method = EXCLUDED.method, updated_at = NOW()
+RETURNING *;
+
+-- name: ClearExpenseSplitParticipants :exec
+DELETE FROM expense_split_participants WHERE expense_id = $1;
+
+-- name: AddExpenseSplitParticipant :exec
+INSERT INTO expense_split_participants (expense_id, user_id, share, amount)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetExpenseSplit :one
+SELECT s.* FROM expense_splits s
+JOIN expenses e ON e.id = s.expense_id
+WHERE s.expense_id = $1 AND e.ledger_id = $2;
+
+-- name: GetExpenseSplitParticipants :many
+SELECT p.user_id, u.email, p.share, p.amount
+FROM expense_split_participants p
+JOIN users u ON u.id = p.user_id
+WHERE p.expense_id = $1
+ORDER BY u.email;
+
+-- name: DeleteExpenseSplit :execrows
+DELETE FROM expense_splits s
+USING expenses e
+WHERE s.expense_id = $1 AND e.id = s.expense_id AND e.ledger_id = $2;
+
+-- name: GetLedgerMemberIDs :many
+SELECT user_id FROM ledger_members WHERE ledger_id = $1;
+
+-- name: GetLedgerBalances :many
+-- Per member and currency: the shares of others they paid for, their own
+-- shares, and settlements they sent and received.
+SELECT b.user_id, u.email, b.currency,
+    SUM(b.paid)::NUMERIC(20, 2) AS paid,
+    SUM(b.owed)::NUMERIC(20, 2) AS owed,
+    SUM(b.sent)::NUMERIC(20, 2) AS sent,
+    SUM(b.received)::NUMERIC(20, 2) AS received
+FROM (
+    SELECT s.paid_by AS user_id, e.currency, p.amount AS paid, 0 AS owed, 0 AS sent, 0 AS received
+    FROM expense_splits s
+    JOIN expenses e ON e.id = s.expense_id
+    JOIN expense_split_participants p ON p.expense_id = s.expense_id
+    WHERE e.ledger_id = $1
+    UNION ALL
+    SELECT p.user_id, e.currency, 0, p.amount, 0, 0
+    FROM expense_split_participants p
+    JOIN expenses e ON e.id = p.expense_id
+    WHERE e.ledger_id = $1
+    UNION ALL
+    SELECT st.from_user_id, st.currency, 0, 0, st.amount, 0
+    FROM settlements st
+    WHERE st.ledger_id = $1
+    UNION ALL
+    SELECT st.to_user_id, st.currency, 0, 0, 0, st.amount
+    FROM settlements st
+    WHERE st.ledger_id = $1
+) b
+JOIN users u ON u.id = b.user_id
+GROUP BY b.user_id, u.email, b.currency
+ORDER BY b.currency, u.email;
+
+-- name: CreateSettlement :one
+INSERT INTO settlements (ledger_id, from_user_id, to_user_id, amount, currency, date, note, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+RETURNING *;
+
+-- name: GetSettlementsByLedger :many
+SELECT st.id, st.from_user_id, f.email AS from_email, st.to_user_id, t.email AS to_email,
+    st.amount, st.currency, st.date, st.note, st.created_by, st.created_at
+FROM settlements st
+JOIN users f ON f.id = st.from_user_id
+JOIN users t ON t.id = st.to_user_id
+WHERE st.ledger_id = $1
+ORDER BY st.date DESC, st.created_at DESC;
+
+-- name: DeleteSettlement :execrows
+DELETE FROM settlements WHERE id = $1 AND ledger_id = $2;
@@ -0,0 +1,41 @@
+-- name: EnsureTags :many
+-- Creates any missing tags and returns all of the named ones.
+INSERT INTO tags (ledger_id, user_id, name)
+SELECT sqlc.arg(ledger_id), sqlc.arg(user_id), unnest(sqlc.arg(names)::text[])
+ON CONFLICT (ledger_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: GetTagsByLedger :many
+SELECT t.id, t.user_id, t.name, t.created_at, COUNT(et.expense_id) as expense_count
+FROM tags t
+LEFT JOIN expense_tags et ON et.tag_id = t.id
+WHERE t.ledger_id = $1
+GROUP BY t.id
+ORDER BY t.name;
+
+-- name: DeleteTag :exec
+DELETE FROM tags WHERE id = $1 AND ledger_id = $2;
+
+-- name: ClearExpenseTags :exec
+DELETE FROM expense_tags WHERE expense_id = $1;
+
+-- name: AddExpenseTags :exec
+INSERT INTO expense_tags (expense_id, tag_id)
+SELECT sqlc.arg(expense_id), unnest(sqlc.arg(tag_ids)::uuid[])
+ON CONFLICT DO NOTHING;
+
+-- name: GetExpensesByTag :many
+-- One row per tag, currency and day, like GetExpensesByCategory. An expense
+-- with several tags counts towards each of them.
+SELECT
+    t.id as tag_id,
+    t.name as tag_name,
+    e.currency,
+    e.date,
+    COALESCE(SUM(e.amount), 0)::NUMERIC(20, 2) as total_amount,
+    COUNT(e.id) as expense_count
+FROM tags t
+JOIN expense_tags et ON et.tag_id = t.id
+JOIN expenses e ON e.id = et.expense_id
+WHERE t.ledger_id = sqlc.arg(ledger_id) AND e.date BETWEEN sqlc.arg(start_date) AND sqlc.arg(end_date)
+GROUP BY t.id, t.name, e.currency, e.date;
@@ -0,0 +1,62 @@
+-- name: StartTOTPEnrollment :one
+-- Replaces an unconfirmed secret; returns no row once 2FA is enabled.
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+WHERE user_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPByUser :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: GetTOTPByUserForUpdate :one
+SELECT * FROM user_totp
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: ConfirmTOTP :exec
+UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1;
+
+-- name: SetTOTPLastUsedStep :exec
+UPDATE user_totp SET last_used_step = $2
+WHERE user_id = $1;
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at)
+SELECT sqlc.arg(user_id), unnest(sqlc.arg(code_hashes)::text[]), NOW();
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (token_hash, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, NOW());
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE token_hash = $1 AND expires_at > NOW()
+FOR UPDATE;
+
+-- name: IncrementLoginChallengeAttempts :exec
+UPDATE login_challenges SET attempts = attempts + 1
+WHERE token_hash = $1;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges WHERE token_hash = $1;
+
+-- name: CleanupExpiredLoginChallenges :exec
+DELETE FROM login_challenges WHERE expires_at <= NOW();
@@ -23,15 +23,29 @@ UPDATE users SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateUserBaseCurrency :one
+UPDATE users SET base_currency = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: CreateSession :exec
-INSERT INTO sessions (token, user_id, expires_at, created_at)
-VALUES ($1, $2, $3, NOW());
+INSERT INTO sessions (token, user_id, expires_at, absolute_expires_at, user_agent, ip_address, last_seen_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW());
 
 -- name: GetUserBySessionToken :one
 SELECT u.* FROM users u
 JOIN sessions s ON u.id = s.user_id
 WHERE s.token = $1 AND s.expires_at > NOW();
 
+-- name: HasRecentSession :one
+-- True when token is a live session of user_id that signed in within
+-- max_age_seconds, for actions that need a recent login.
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE token = sqlc.arg(token) AND user_id = sqlc.arg(user_id) AND expires_at > NOW()
+        AND created_at > NOW() - make_interval(secs => sqlc.arg(max_age_seconds)::float8)
+);
+
 -- name: RevokeSession :exec
 DELETE FROM sessions WHERE token = $1;
 
@@ -39,4 +53,41 @@ DELETE FROM sessions WHERE token = $1;
 DELETE FROM sessions WHERE user_id = $1;
 
 -- name: CleanupExpiredSessions :exec
-DELETE FROM sessions WHERE expires_at <= NOW();
\ No newline at end of file
+DELETE FROM sessions WHERE expires_at <= NOW();
+
+-- name: TouchSession :exec
+-- Records activity at most once a minute. With slide set the session is
+-- extended to idle_seconds from now, but never past its absolute expiry.
+UPDATE sessions
+SET last_seen_at = NOW(),
+    ip_address = sqlc.arg(ip_address),
+    expires_at = CASE
+        WHEN sqlc.arg(slide)::boolean
+            THEN LEAST(NOW() + make_interval(secs => sqlc.arg(idle_seconds)::float8), absolute_expires_at)
+        ELSE expires_at
+    END
+WHERE token = sqlc.arg(token) AND last_seen_at < NOW() - INTERVAL '1 minute';
+
+-- name: ListUserSessions :many
+SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at,
+    (token = sqlc.arg(current_token))::boolean AS current
+FROM sessions
+WHERE user_id = sqlc.arg(user_id) AND expires_at > NOW()
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeUserSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2;
+
+-- name: RevokeOtherUserSessions :exec
+DELETE FROM sessions WHERE user_id = $1 AND token <> $2;
+
+-- name: MarkUserEmailVerified :one
+-- Only verifies the address the token was issued for; a later email change
+-- makes older tokens useless.
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: SetActiveLedger :exec
+UPDATE users SET active_ledger_id = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'USD' CHECK (base_currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE expenses
+ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
+
+CREATE TABLE exchange_rates (
+    base_currency TEXT NOT NULL CHECK (base_currency ~ '^[A-Z]{3}$'),
+    quote_currency TEXT NOT NULL CHECK (quote_currency ~ '^[A-Z]{3}$'),
+    rate_date DATE NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base_currency, quote_currency, rate_date)
+);
+
+CREATE INDEX idx_exchange_rates_quote ON exchange_rates(quote_currency, rate_date);
+
+-- +goose Down
+DROP TABLE exchange_rates;
+
+ALTER TABLE expenses
+DROP COLUMN currency;
+
+ALTER TABLE users
+DROP COLUMN base_currency;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE recurring_expenses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$'),
+    description TEXT NOT NULL,
+    frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    occurrence_limit INTEGER CHECK (occurrence_limit > 0),
+    -- Occurrence n falls on anchor_date + (n - anchor_index) steps. Editing
+    -- the schedule moves the anchor so past occurrences keep their dates.
+    anchor_date DATE NOT NULL,
+    anchor_index INTEGER NOT NULL DEFAULT 0,
+    next_index INTEGER NOT NULL DEFAULT 0,
+    next_date DATE,
+    paused BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_recurring_expenses_user_id ON recurring_expenses(user_id);
+CREATE INDEX idx_recurring_expenses_due ON recurring_expenses(next_date) WHERE NOT paused;
+
+CREATE TABLE recurring_expense_skips (
+    recurring_expense_id UUID NOT NULL REFERENCES recurring_expenses(id) ON DELETE CASCADE,
+    occurrence_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (recurring_expense_id, occurrence_date)
+);
+
+ALTER TABLE expenses
+ADD COLUMN recurring_expense_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+ADD COLUMN occurrence_date DATE;
+
+-- Makes materialization idempotent: each occurrence can exist only once.
+CREATE UNIQUE INDEX idx_expenses_recurring_occurrence
+ON expenses(recurring_expense_id, occurrence_date)
+WHERE recurring_expense_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX idx_expenses_recurring_occurrence;
+
+ALTER TABLE expenses
+DROP COLUMN occurrence_date,
+DROP COLUMN recurring_expense_id;
+
+DROP TABLE recurring_expense_skips;
+DROP TABLE recurring_expenses;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    month DATE NOT NULL CHECK (EXTRACT(DAY FROM month) = 1),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    rollover BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(category_id, month)
+);
+
+CREATE INDEX idx_budgets_user_month ON budgets(user_id, month);
+
+-- +goose Down
+DROP TABLE budgets;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Matches the expense list ordering. Forward pages read it in order and
+-- backward pages in reverse, each with its own query so neither has to sort
+CREATE INDEX idx_expenses_user_keyset ON expenses(user_id, date DESC, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX idx_expenses_user_keyset;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Descriptions weigh more than category names when ranking search results
+ALTER TABLE expenses ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (setweight(to_tsvector('english', description), 'A')) STORED;
+ALTER TABLE categories ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (setweight(to_tsvector('english', name), 'B')) STORED;
+
+CREATE INDEX idx_expenses_search ON expenses USING GIN(search_vector);
+CREATE INDEX idx_categories_search ON categories USING GIN(search_vector);
+
+-- +goose Down
+DROP INDEX idx_categories_search;
+DROP INDEX idx_expenses_search;
+ALTER TABLE categories DROP COLUMN search_vector;
+ALTER TABLE expenses DROP COLUMN search_vector;
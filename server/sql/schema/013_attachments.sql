@@ -0,0 +1,19 @@
+-- +goose Up
+-- Blobs are stored once per SHA-256 and shared by every attachment with
+-- the same content; see internal/attachments.
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    sha256 TEXT NOT NULL CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachments_expense_id ON attachments(expense_id);
+CREATE INDEX idx_attachments_sha256 ON attachments(sha256);
+
+-- +goose Down
+DROP TABLE attachments;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE categories ADD COLUMN parent_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+-- +goose Down
+ALTER TABLE categories DROP COLUMN parent_id;
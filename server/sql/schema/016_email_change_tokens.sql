@@ -0,0 +1,18 @@
+-- +goose Up
+-- Pending email changes, applied once the link mailed to the new address
+-- is opened. Like password resets only the token's SHA-256 is stored.
+CREATE TABLE email_change_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL CHECK (token_hash ~ '^[0-9a-f]{64}$'),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_change_tokens_user_id ON email_change_tokens(user_id);
+CREATE INDEX idx_email_change_tokens_expires_at ON email_change_tokens(expires_at);
+
+-- +goose Down
+DROP TABLE email_change_tokens;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Failed login attempts per limiter key, used by ratelimit.PostgresLimiter
+CREATE TABLE login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP
+);
+
+CREATE INDEX idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+
+-- +goose Down
+DROP TABLE login_attempts;
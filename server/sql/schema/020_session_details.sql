@@ -0,0 +1,23 @@
+-- +goose Up
+-- The token stays the primary key but is secret; id is what the sessions
+-- API exposes. absolute_expires_at caps how far sliding expiry may extend.
+ALTER TABLE sessions
+    ADD COLUMN id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ADD COLUMN absolute_expires_at TIMESTAMP;
+
+UPDATE sessions SET absolute_expires_at = expires_at, last_seen_at = created_at;
+
+ALTER TABLE sessions
+    ALTER COLUMN absolute_expires_at SET NOT NULL,
+    ADD CONSTRAINT sessions_id_key UNIQUE (id);
+
+-- +goose Down
+ALTER TABLE sessions
+    DROP COLUMN absolute_expires_at,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN ip_address,
+    DROP COLUMN user_agent,
+    DROP COLUMN id;
@@ -0,0 +1,90 @@
+-- +goose Up
+-- A ledger owns categories, expenses and everything hanging off them, so
+-- several people can share one. user_id on those rows now records who
+-- created them.
+CREATE TABLE ledgers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE ledger_members (
+    ledger_id UUID NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+CREATE INDEX idx_ledger_members_user_id ON ledger_members(user_id);
+
+-- Invitations are addressed to an email and accepted by whoever verified
+-- that address, whether or not they had an account when invited.
+CREATE TABLE ledger_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id UUID NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (ledger_id, email)
+);
+
+CREATE INDEX idx_ledger_invitations_email ON ledger_invitations(lower(email));
+
+-- The ledger used when a request doesn't pick one with X-Ledger-ID
+ALTER TABLE users ADD COLUMN active_ledger_id UUID REFERENCES ledgers(id) ON DELETE SET NULL;
+
+-- Every existing user gets a personal ledger holding their data. Reusing
+-- the user's ID for it keeps the backfill below a plain copy.
+INSERT INTO ledgers (id, name) SELECT id, 'Personal' FROM users;
+INSERT INTO ledger_members (ledger_id, user_id, role) SELECT id, id, 'owner' FROM users;
+UPDATE users SET active_ledger_id = id;
+
+ALTER TABLE categories ADD COLUMN ledger_id UUID REFERENCES ledgers(id) ON DELETE CASCADE;
+UPDATE categories SET ledger_id = user_id;
+ALTER TABLE categories ALTER COLUMN ledger_id SET NOT NULL;
+ALTER TABLE categories DROP CONSTRAINT categories_user_id_name_key;
+ALTER TABLE categories ADD CONSTRAINT categories_ledger_id_name_key UNIQUE (ledger_id, name);
+
+ALTER TABLE expenses ADD COLUMN ledger_id UUID REFERENCES ledgers(id) ON DELETE CASCADE;
+UPDATE expenses SET ledger_id = user_id;
+ALTER TABLE expenses ALTER COLUMN ledger_id SET NOT NULL;
+DROP INDEX idx_expenses_user_keyset;
+CREATE INDEX idx_expenses_ledger_keyset ON expenses(ledger_id, date DESC, created_at DESC, id DESC);
+
+ALTER TABLE tags ADD COLUMN ledger_id UUID REFERENCES ledgers(id) ON DELETE CASCADE;
+UPDATE tags SET ledger_id = user_id;
+ALTER TABLE tags ALTER COLUMN ledger_id SET NOT NULL;
+ALTER TABLE tags DROP CONSTRAINT tags_user_id_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_ledger_id_name_key UNIQUE (ledger_id, name);
+
+ALTER TABLE budgets ADD COLUMN ledger_id UUID REFERENCES ledgers(id) ON DELETE CASCADE;
+UPDATE budgets SET ledger_id = user_id;
+ALTER TABLE budgets ALTER COLUMN ledger_id SET NOT NULL;
+CREATE INDEX idx_budgets_ledger_month ON budgets(ledger_id, month);
+
+ALTER TABLE recurring_expenses ADD COLUMN ledger_id UUID REFERENCES ledgers(id) ON DELETE CASCADE;
+UPDATE recurring_expenses SET ledger_id = user_id;
+ALTER TABLE recurring_expenses ALTER COLUMN ledger_id SET NOT NULL;
+CREATE INDEX idx_recurring_expenses_ledger_id ON recurring_expenses(ledger_id);
+
+-- +goose Down
+-- Rows in shared ledgers stay with whoever created them
+ALTER TABLE recurring_expenses DROP COLUMN ledger_id;
+ALTER TABLE budgets DROP COLUMN ledger_id;
+ALTER TABLE tags DROP CONSTRAINT tags_ledger_id_name_key;
+ALTER TABLE tags DROP COLUMN ledger_id;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+DROP INDEX idx_expenses_ledger_keyset;
+ALTER TABLE expenses DROP COLUMN ledger_id;
+CREATE INDEX idx_expenses_user_keyset ON expenses(user_id, date DESC, created_at DESC, id DESC);
+ALTER TABLE categories DROP CONSTRAINT categories_ledger_id_name_key;
+ALTER TABLE categories DROP COLUMN ledger_id;
+ALTER TABLE categories ADD CONSTRAINT categories_user_id_name_key UNIQUE (user_id, name);
+ALTER TABLE users DROP COLUMN active_ledger_id;
+DROP TABLE ledger_invitations;
+DROP TABLE ledger_members;
+DROP TABLE ledgers;
@@ -0,0 +1,44 @@
+-- +goose Up
+-- An expense paid by one member and shared between several. The method and
+-- each participant's share (a share count or a percentage) are kept so the
+-- amounts can be worked out again when the expense amount changes.
+CREATE TABLE expense_splits (
+    expense_id UUID PRIMARY KEY REFERENCES expenses(id) ON DELETE CASCADE,
+    paid_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method TEXT NOT NULL CHECK (method IN ('equal', 'shares', 'exact', 'percentage')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE expense_split_participants (
+    expense_id UUID NOT NULL REFERENCES expense_splits(expense_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    share DECIMAL(12, 4),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (expense_id, user_id)
+);
+
+CREATE INDEX idx_expense_split_participants_user_id ON expense_split_participants(user_id);
+
+-- Money moved between members to pay back what they owe. Settlements are
+-- not expenses, so they never count towards spending.
+CREATE TABLE settlements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id UUID NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    date DATE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX idx_settlements_ledger_date ON settlements(ledger_id, date DESC);
+
+-- +goose Down
+DROP TABLE settlements;
+DROP TABLE expense_split_participants;
+DROP TABLE expense_splits;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Income is kept apart from expenses so spending totals, budgets and the
+-- amount > 0 check on expenses stay as they are.
+CREATE TABLE income_categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id UUID NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL DEFAULT '#10B981',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(ledger_id, name)
+);
+
+CREATE TABLE incomes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ledger_id UUID NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES income_categories(id) ON DELETE SET NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    description TEXT NOT NULL,
+    date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_incomes_ledger_date ON incomes(ledger_id, date DESC);
+CREATE INDEX idx_incomes_category_id ON incomes(category_id);
+
+-- +goose Down
+DROP TABLE incomes;
+DROP TABLE income_categories;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Invitations are looked up by lower(email), so addresses that differ only
+-- in case must count as the same invitation. Keep the newest of each.
+DELETE FROM ledger_invitations a
+USING ledger_invitations b
+WHERE a.ledger_id = b.ledger_id
+  AND lower(a.email) = lower(b.email)
+  AND (a.created_at, a.id) < (b.created_at, b.id);
+
+ALTER TABLE ledger_invitations DROP CONSTRAINT ledger_invitations_ledger_id_email_key;
+CREATE UNIQUE INDEX idx_ledger_invitations_ledger_email ON ledger_invitations(ledger_id, lower(email));
+
+-- +goose Down
+DROP INDEX idx_ledger_invitations_ledger_email;
+ALTER TABLE ledger_invitations ADD CONSTRAINT ledger_invitations_ledger_id_email_key UNIQUE (ledger_id, email);